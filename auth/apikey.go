@@ -0,0 +1,73 @@
+package auth
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"net/http"
+
+	oapi "github.com/swaggest/openapi-go"
+)
+
+// APIKey authenticates requests with a key in header or query parameter.
+type APIKey struct {
+	// Name of header or query parameter, default "X-API-Key".
+	Name string
+
+	// In is the location of key, oapi.InHeader (default) or oapi.InQuery.
+	In oapi.In
+
+	// Keys maps API keys to principal IDs.
+	Keys map[string]string
+
+	// Lookup finds principal by API key, it is used if key is not found in Keys.
+	Lookup func(ctx context.Context, key string) (Principal, error)
+
+	// Description is exposed in OpenAPI schema.
+	Description string
+}
+
+func (a APIKey) name() string {
+	if a.Name == "" {
+		return "X-API-Key"
+	}
+
+	return a.Name
+}
+
+// Authenticate implements Authenticator.
+func (a APIKey) Authenticate(r *http.Request) (Principal, error) {
+	var key string
+
+	if a.In == oapi.InQuery {
+		key = r.URL.Query().Get(a.name())
+	} else {
+		key = r.Header.Get(a.name())
+	}
+
+	if key == "" {
+		return Principal{}, ErrNoCredentials
+	}
+
+	for k, id := range a.Keys {
+		if subtle.ConstantTimeCompare([]byte(k), []byte(key)) == 1 {
+			return Principal{ID: id}, nil
+		}
+	}
+
+	if a.Lookup != nil {
+		return a.Lookup(r.Context(), key)
+	}
+
+	return Principal{}, errors.New("invalid API key")
+}
+
+// Expose implements Authenticator.
+func (a APIKey) Expose(s oapi.SpecSchema, name string) {
+	in := a.In
+	if in == "" {
+		in = oapi.InHeader
+	}
+
+	s.SetAPIKeySecurity(name, a.name(), in, a.Description)
+}
@@ -0,0 +1,53 @@
+package auth
+
+import (
+	"crypto/subtle"
+	"errors"
+	"net/http"
+
+	oapi "github.com/swaggest/openapi-go"
+)
+
+// Basic authenticates requests with HTTP Basic credentials.
+type Basic struct {
+	// Realm is reported in WWW-Authenticate header, default "Restricted".
+	Realm string
+
+	// Users maps user names to passwords.
+	Users map[string]string
+
+	// Description is exposed in OpenAPI schema.
+	Description string
+}
+
+// Authenticate implements Authenticator.
+func (b Basic) Authenticate(r *http.Request) (Principal, error) {
+	user, pass, ok := r.BasicAuth()
+	if !ok {
+		return Principal{}, ErrNoCredentials
+	}
+
+	expected, found := b.Users[user]
+
+	// Password is compared even for unknown users to avoid timing difference.
+	if subtle.ConstantTimeCompare([]byte(pass), []byte(expected)) != 1 || !found {
+		return Principal{}, errors.New("invalid user or password")
+	}
+
+	return Principal{ID: user}, nil
+}
+
+// Expose implements Authenticator.
+func (b Basic) Expose(s oapi.SpecSchema, name string) {
+	s.SetHTTPBasicSecurity(name, b.Description)
+}
+
+// Challenge implements Challenger.
+func (b Basic) Challenge() string {
+	realm := b.Realm
+	if realm == "" {
+		realm = "Restricted"
+	}
+
+	return `Basic realm="` + realm + `"`
+}
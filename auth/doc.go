@@ -0,0 +1,2 @@
+// Package auth provides authentication middlewares with OpenAPI security schemes.
+package auth
@@ -0,0 +1,277 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/hmac"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	oapi "github.com/swaggest/openapi-go"
+)
+
+// JWTConfig describes JSON Web Token validation.
+type JWTConfig struct {
+	// Secret is a shared key to verify HS256, HS384 and HS512 tokens.
+	Secret string
+
+	// JWKSFile is a path to JSON Web Key Set with RSA public keys to verify RS256, RS384 and RS512 tokens.
+	JWKSFile string `split_words:"true"`
+
+	// Issuer is the expected "iss" claim, if not empty.
+	Issuer string
+
+	// Audience is the expected "aud" claim, if not empty.
+	Audience string
+
+	// Leeway is the allowed clock skew for "exp" and "nbf" claims.
+	Leeway time.Duration `default:"1m"`
+
+	// Description is exposed in OpenAPI schema.
+	Description string
+}
+
+// JWT authenticates requests with bearer JSON Web Token.
+//
+// Please use NewJWT to create an instance.
+type JWT struct {
+	cfg     JWTConfig
+	rsaKeys map[string]*rsa.PublicKey
+	now     func() time.Time
+}
+
+// NewJWT creates JWT authenticator and loads keys.
+func NewJWT(cfg JWTConfig) (*JWT, error) {
+	j := &JWT{
+		cfg: cfg,
+		now: time.Now,
+	}
+
+	if cfg.JWKSFile != "" {
+		keys, err := loadJWKS(cfg.JWKSFile)
+		if err != nil {
+			return nil, fmt.Errorf("load JWKS: %w", err)
+		}
+
+		j.rsaKeys = keys
+	}
+
+	if cfg.Secret == "" && len(j.rsaKeys) == 0 {
+		return nil, errors.New("JWT secret or JWKS file is required")
+	}
+
+	return j, nil
+}
+
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+}
+
+func loadJWKS(fn string) (map[string]*rsa.PublicKey, error) {
+	data, err := os.ReadFile(fn) //nolint:gosec // File name comes from trusted config.
+	if err != nil {
+		return nil, err
+	}
+
+	var set struct {
+		Keys []jwk `json:"keys"`
+	}
+
+	if err := json.Unmarshal(data, &set); err != nil {
+		return nil, err
+	}
+
+	keys := make(map[string]*rsa.PublicKey, len(set.Keys))
+
+	for _, k := range set.Keys {
+		if k.Kty != "RSA" {
+			continue
+		}
+
+		n, err := base64.RawURLEncoding.DecodeString(k.N)
+		if err != nil {
+			return nil, fmt.Errorf("decode modulus of key %q: %w", k.Kid, err)
+		}
+
+		e, err := base64.RawURLEncoding.DecodeString(k.E)
+		if err != nil {
+			return nil, fmt.Errorf("decode exponent of key %q: %w", k.Kid, err)
+		}
+
+		keys[k.Kid] = &rsa.PublicKey{
+			N: new(big.Int).SetBytes(n),
+			E: int(new(big.Int).SetBytes(e).Int64()),
+		}
+	}
+
+	return keys, nil
+}
+
+// Authenticate implements Authenticator.
+func (j *JWT) Authenticate(r *http.Request) (Principal, error) {
+	token, found := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !found || token == "" {
+		return Principal{}, ErrNoCredentials
+	}
+
+	claims, err := j.Verify(token)
+	if err != nil {
+		return Principal{}, err
+	}
+
+	sub, _ := claims["sub"].(string)
+
+	return Principal{ID: sub, Claims: claims}, nil
+}
+
+// Verify checks token signature and claims and returns claims.
+func (j *JWT) Verify(token string) (map[string]interface{}, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, errors.New("malformed token")
+	}
+
+	var header struct {
+		Alg string `json:"alg"`
+		Kid string `json:"kid"`
+	}
+
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return nil, fmt.Errorf("decode header: %w", err)
+	}
+
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, fmt.Errorf("decode signature: %w", err)
+	}
+
+	if err := j.verifySignature(header.Alg, header.Kid, []byte(parts[0]+"."+parts[1]), sig); err != nil {
+		return nil, err
+	}
+
+	claims := map[string]interface{}{}
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		return nil, fmt.Errorf("decode claims: %w", err)
+	}
+
+	if err := j.validateClaims(claims); err != nil {
+		return nil, err
+	}
+
+	return claims, nil
+}
+
+func decodeSegment(s string, v interface{}) error {
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return err
+	}
+
+	return json.Unmarshal(data, v)
+}
+
+func (j *JWT) verifySignature(alg, kid string, signed, sig []byte) error {
+	if len(alg) != 5 {
+		return fmt.Errorf("unsupported algorithm %q", alg)
+	}
+
+	var h crypto.Hash
+
+	switch alg[2:] {
+	case "256":
+		h = crypto.SHA256
+	case "384":
+		h = crypto.SHA384
+	case "512":
+		h = crypto.SHA512
+	default:
+		return fmt.Errorf("unsupported algorithm %q", alg)
+	}
+
+	switch alg[:2] {
+	case "HS":
+		if j.cfg.Secret == "" {
+			return fmt.Errorf("unsupported algorithm %q", alg)
+		}
+
+		mac := hmac.New(h.New, []byte(j.cfg.Secret))
+		mac.Write(signed)
+
+		if !hmac.Equal(sig, mac.Sum(nil)) {
+			return errors.New("invalid signature")
+		}
+
+		return nil
+	case "RS":
+		key, ok := j.rsaKeys[kid]
+		if !ok {
+			return fmt.Errorf("unknown key %q", kid)
+		}
+
+		hh := h.New()
+		hh.Write(signed)
+
+		if err := rsa.VerifyPKCS1v15(key, h, hh.Sum(nil), sig); err != nil {
+			return errors.New("invalid signature")
+		}
+
+		return nil
+	}
+
+	return fmt.Errorf("unsupported algorithm %q", alg)
+}
+
+func (j *JWT) validateClaims(claims map[string]interface{}) error {
+	now := j.now()
+
+	if exp, ok := claims["exp"].(float64); ok && now.After(time.Unix(int64(exp), 0).Add(j.cfg.Leeway)) {
+		return errors.New("token expired")
+	}
+
+	if nbf, ok := claims["nbf"].(float64); ok && now.Add(j.cfg.Leeway).Before(time.Unix(int64(nbf), 0)) {
+		return errors.New("token not valid yet")
+	}
+
+	if j.cfg.Issuer != "" && claims["iss"] != j.cfg.Issuer {
+		return errors.New("unexpected issuer")
+	}
+
+	if j.cfg.Audience == "" {
+		return nil
+	}
+
+	switch aud := claims["aud"].(type) {
+	case string:
+		if aud == j.cfg.Audience {
+			return nil
+		}
+	case []interface{}:
+		for _, a := range aud {
+			if a == j.cfg.Audience {
+				return nil
+			}
+		}
+	}
+
+	return errors.New("unexpected audience")
+}
+
+// Expose implements Authenticator.
+func (j *JWT) Expose(s oapi.SpecSchema, name string) {
+	s.SetHTTPBearerTokenSecurity(name, "JWT", j.cfg.Description)
+}
+
+// Challenge implements Challenger.
+func (j *JWT) Challenge() string {
+	return "Bearer"
+}
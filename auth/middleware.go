@@ -0,0 +1,154 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/bool64/brick/log"
+	"github.com/bool64/ctxd"
+	oapi "github.com/swaggest/openapi-go"
+	"github.com/swaggest/rest"
+	"github.com/swaggest/rest/nethttp"
+	"github.com/swaggest/rest/openapi"
+	"github.com/swaggest/usecase/status"
+)
+
+// ErrNoCredentials indicates that request does not have credentials for authenticator.
+var ErrNoCredentials = errors.New("no credentials")
+
+// Authenticator checks request credentials.
+type Authenticator interface {
+	// Authenticate returns principal of the request or ErrNoCredentials
+	// if request does not have relevant credentials.
+	Authenticate(r *http.Request) (Principal, error)
+
+	// Expose declares security scheme in OpenAPI schema.
+	Expose(s oapi.SpecSchema, name string)
+}
+
+// Challenger provides WWW-Authenticate header value for unauthorized responses.
+type Challenger interface {
+	Challenge() string
+}
+
+// Scheme is a named authenticator.
+type Scheme struct {
+	Name          string
+	Authenticator Authenticator
+}
+
+// Config describes authentication middleware.
+type Config struct {
+	// Schemes are tried in order, the first scheme that finds credentials in request decides.
+	Schemes []Scheme
+
+	// Collector receives security schemes of protected routes, optional.
+	Collector *openapi.Collector
+
+	// Optional allows requests without credentials.
+	Optional bool
+
+	// Logger is used to report authentication failures at DEBUG level.
+	Logger ctxd.Logger
+
+	// PrincipalField is the name of log field with principal ID, default "user.id".
+	PrincipalField string
+}
+
+// Middleware creates authentication middleware.
+//
+// Authenticated principal is available with PrincipalFromContext, and its ID is added to log fields.
+// Middleware is a handler wrapper, routes protected with it declare security schemes
+// in OpenAPI schema.
+//
+//	r.With(auth.Middleware(cfg)).Get("/me", getMe)
+func Middleware(cfg Config) func(http.Handler) http.Handler {
+	if cfg.Logger == nil {
+		cfg.Logger = ctxd.NoOpLogger{}
+	}
+
+	if cfg.PrincipalField == "" {
+		cfg.PrincipalField = "user.id"
+	}
+
+	var annotations []func(http.Handler) http.Handler
+
+	if cfg.Collector != nil {
+		for _, s := range cfg.Schemes {
+			s.Authenticator.Expose(cfg.Collector.SpecSchema(), s.Name)
+
+			annotations = append(annotations, nethttp.AuthMiddleware(cfg.Collector, s.Name))
+		}
+	}
+
+	return func(handler http.Handler) http.Handler {
+		if nethttp.IsWrapperChecker(handler) {
+			return handler
+		}
+
+		for _, a := range annotations {
+			a(handler)
+		}
+
+		return http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+			ctx := r.Context()
+
+			for _, s := range cfg.Schemes {
+				p, err := s.Authenticator.Authenticate(r)
+				if errors.Is(err, ErrNoCredentials) {
+					continue
+				}
+
+				if err != nil {
+					cfg.Logger.Debug(ctx, "authentication failed", "error", err, "scheme", s.Name)
+					unauthorized(ctx, cfg, rw)
+
+					return
+				}
+
+				p.Scheme = s.Name
+				ctx = WithPrincipal(ctx, p)
+				ctx = ctxd.AddFields(ctx, cfg.PrincipalField, p.ID)
+				log.AddRequestFields(ctx, cfg.PrincipalField, p.ID)
+
+				handler.ServeHTTP(rw, r.WithContext(ctx))
+
+				return
+			}
+
+			if !cfg.Optional {
+				unauthorized(ctx, cfg, rw)
+
+				return
+			}
+
+			handler.ServeHTTP(rw, r)
+		})
+	}
+}
+
+func unauthorized(ctx context.Context, cfg Config, rw http.ResponseWriter) {
+	for _, s := range cfg.Schemes {
+		if c, ok := s.Authenticator.(Challenger); ok {
+			rw.Header().Add("WWW-Authenticate", c.Challenge())
+		}
+	}
+
+	code, resp := rest.Err(status.Unauthenticated)
+
+	j, err := json.Marshal(resp)
+	if err != nil {
+		cfg.Logger.Error(ctx, "failed to marshal unauthorized response", "error", err)
+
+		return
+	}
+
+	rw.Header().Set("Content-Type", "application/json; charset=utf-8")
+	rw.WriteHeader(code)
+
+	if _, err := rw.Write(append(j, '\n')); err != nil {
+		cfg.Logger.Error(ctx, "failed to write unauthorized response", "error", err)
+	}
+}
@@ -0,0 +1,136 @@
+package auth_test
+
+import (
+	"context"
+	"crypto"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/bool64/brick/auth"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/swaggest/assertjson"
+	"github.com/swaggest/openapi-go/openapi3"
+	"github.com/swaggest/rest/nethttp"
+	"github.com/swaggest/rest/web"
+	"github.com/swaggest/usecase"
+)
+
+func token(t *testing.T, alg, kid string, claims map[string]interface{}, sign func(signed []byte) []byte) string {
+	t.Helper()
+
+	h, err := json.Marshal(map[string]string{"alg": alg, "kid": kid, "typ": "JWT"})
+	require.NoError(t, err)
+
+	c, err := json.Marshal(claims)
+	require.NoError(t, err)
+
+	signed := base64.RawURLEncoding.EncodeToString(h) + "." + base64.RawURLEncoding.EncodeToString(c)
+
+	return signed + "." + base64.RawURLEncoding.EncodeToString(sign([]byte(signed)))
+}
+
+func TestMiddleware(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	jwks, err := json.Marshal(map[string]interface{}{"keys": []map[string]string{{
+		"kty": "RSA",
+		"kid": "k1",
+		"n":   base64.RawURLEncoding.EncodeToString(rsaKey.N.Bytes()),
+		"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(rsaKey.E)).Bytes()),
+	}}})
+	require.NoError(t, err)
+
+	jwksFile := filepath.Join(t.TempDir(), "jwks.json")
+	require.NoError(t, os.WriteFile(jwksFile, jwks, 0o600))
+
+	jwt, err := auth.NewJWT(auth.JWTConfig{Secret: "secret", JWKSFile: jwksFile, Audience: "app"})
+	require.NoError(t, err)
+
+	r := web.NewService(openapi3.NewReflector())
+
+	r.With(auth.Middleware(auth.Config{
+		Collector: r.OpenAPICollector,
+		Schemes: []auth.Scheme{
+			{Name: "bearerAuth", Authenticator: jwt},
+			{Name: "apiKey", Authenticator: auth.APIKey{Keys: map[string]string{"k3y": "service"}}},
+			{Name: "basicAuth", Authenticator: auth.Basic{Users: map[string]string{"admin": "pass"}}},
+		},
+	})).Method(http.MethodGet, "/me", nethttp.NewHandler(usecase.NewInteractor(
+		func(ctx context.Context, _ struct{}, out *string) error {
+			p, _ := auth.PrincipalFromContext(ctx)
+			*out = p.Scheme + ":" + p.ID
+
+			return nil
+		})))
+
+	do := func(setup func(req *http.Request)) *httptest.ResponseRecorder {
+		rw := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/me", nil)
+		setup(req)
+		r.ServeHTTP(rw, req)
+
+		return rw
+	}
+
+	hs := token(t, "HS256", "", map[string]interface{}{"sub": "john", "aud": "app"}, func(signed []byte) []byte {
+		m := hmac.New(sha256.New, []byte("secret"))
+		m.Write(signed)
+
+		return m.Sum(nil)
+	})
+
+	rw := do(func(req *http.Request) { req.Header.Set("Authorization", "Bearer "+hs) })
+	assert.Equal(t, http.StatusOK, rw.Code)
+	assert.Equal(t, `"bearerAuth:john"`+"\n", rw.Body.String())
+
+	rs := token(t, "RS256", "k1", map[string]interface{}{"sub": "jane", "aud": []string{"app"}}, func(signed []byte) []byte {
+		h := sha256.Sum256(signed)
+		sig, err := rsa.SignPKCS1v15(rand.Reader, rsaKey, crypto.SHA256, h[:])
+		require.NoError(t, err)
+
+		return sig
+	})
+
+	rw = do(func(req *http.Request) { req.Header.Set("Authorization", "Bearer "+rs) })
+	assert.Equal(t, http.StatusOK, rw.Code)
+	assert.Equal(t, `"bearerAuth:jane"`+"\n", rw.Body.String())
+
+	rw = do(func(req *http.Request) { req.Header.Set("Authorization", "Bearer "+hs[:len(hs)-2]) })
+	assert.Equal(t, http.StatusUnauthorized, rw.Code)
+
+	rw = do(func(req *http.Request) { req.Header.Set("X-API-Key", "k3y") })
+	assert.Equal(t, `"apiKey:service"`+"\n", rw.Body.String())
+
+	rw = do(func(req *http.Request) { req.SetBasicAuth("admin", "pass") })
+	assert.Equal(t, `"basicAuth:admin"`+"\n", rw.Body.String())
+
+	rw = do(func(req *http.Request) { req.SetBasicAuth("admin", "wrong") })
+	assert.Equal(t, http.StatusUnauthorized, rw.Code)
+
+	rw = do(func(_ *http.Request) {})
+	assert.Equal(t, http.StatusUnauthorized, rw.Code)
+	assert.Equal(t, []string{"Bearer", `Basic realm="Restricted"`}, rw.Header().Values("WWW-Authenticate"))
+	assert.Equal(t, `{"status":"UNAUTHENTICATED","error":"unauthenticated"}`+"\n", rw.Body.String())
+
+	j, err := json.Marshal(r.OpenAPISchema())
+	require.NoError(t, err)
+
+	assertjson.EqualMarshal(t, []byte(`{
+	  "bearerAuth":{"type":"http","scheme":"bearer","bearerFormat":"JWT","description":""},
+	  "apiKey":{"type":"apiKey","name":"X-API-Key","in":"header","description":""},
+	  "basicAuth":{"type":"http","scheme":"basic","description":""}
+	}`), r.OpenAPICollector.Reflector().SpecEns().Components.SecuritySchemes)
+	assert.Contains(t, string(j), `"security":[{"bearerAuth":[]},{"apiKey":[]},{"basicAuth":[]}]`)
+}
@@ -0,0 +1,41 @@
+package auth
+
+import (
+	"context"
+	"net/http"
+)
+
+// Principal describes an authenticated client.
+type Principal struct {
+	// ID identifies the client, for example JWT subject or user name.
+	ID string
+
+	// Scheme is the name of security scheme that authenticated the client.
+	Scheme string
+
+	// Claims keep additional details, for example JWT claims.
+	Claims map[string]interface{}
+}
+
+type principalCtxKey struct{}
+
+// WithPrincipal adds principal to context.
+func WithPrincipal(ctx context.Context, p Principal) context.Context {
+	return context.WithValue(ctx, principalCtxKey{}, p)
+}
+
+// PrincipalFromContext returns principal from context.
+func PrincipalFromContext(ctx context.Context) (Principal, bool) {
+	p, ok := ctx.Value(principalCtxKey{}).(Principal)
+
+	return p, ok
+}
+
+// PrincipalID returns ID of authenticated principal or empty string.
+//
+// It can be used as ratelimit.KeyFunc.
+func PrincipalID(r *http.Request) string {
+	p, _ := PrincipalFromContext(r.Context())
+
+	return p.ID
+}
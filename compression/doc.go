@@ -0,0 +1,2 @@
+// Package compression provides HTTP response compression middleware.
+package compression
@@ -0,0 +1,111 @@
+package compression
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/klauspost/compress/flate"
+	"github.com/klauspost/compress/gzip"
+	"github.com/klauspost/compress/zstd"
+)
+
+// Supported content encodings.
+const (
+	Zstd    = "zstd"
+	Gzip    = "gzip"
+	Deflate = "deflate"
+)
+
+type encoder interface {
+	io.WriteCloser
+	Flush() error
+	Reset(w io.Writer)
+}
+
+// newEncoderPool creates a pool of encoders for content encoding, level 0 means default level.
+func newEncoderPool(encoding string, level int) (*sync.Pool, error) {
+	var newEncoder func() (encoder, error)
+
+	switch encoding {
+	case Zstd:
+		zl := zstd.SpeedDefault
+		if level != 0 {
+			zl = zstd.EncoderLevelFromZstd(level)
+		}
+
+		newEncoder = func() (encoder, error) {
+			return zstd.NewWriter(nil, zstd.WithEncoderLevel(zl), zstd.WithEncoderConcurrency(1))
+		}
+	case Gzip:
+		if level == 0 {
+			level = gzip.DefaultCompression
+		}
+
+		newEncoder = func() (encoder, error) {
+			return gzip.NewWriterLevel(nil, level)
+		}
+	case Deflate:
+		if level == 0 {
+			level = flate.DefaultCompression
+		}
+
+		newEncoder = func() (encoder, error) {
+			return flate.NewWriter(nil, level)
+		}
+	default:
+		return nil, fmt.Errorf("unsupported content encoding: %q", encoding)
+	}
+
+	// Checking configuration.
+	if _, err := newEncoder(); err != nil {
+		return nil, err
+	}
+
+	return &sync.Pool{New: func() interface{} {
+		e, _ := newEncoder() //nolint:errcheck // Error is checked above.
+
+		return e
+	}}, nil
+}
+
+// negotiate returns the first of server encodings accepted by client.
+func negotiate(acceptEncoding string, encodings []string) string {
+	if acceptEncoding == "" {
+		return ""
+	}
+
+	accepted := make(map[string]bool)
+
+	for _, part := range strings.Split(acceptEncoding, ",") {
+		name, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		name = strings.ToLower(strings.TrimSpace(name))
+		ok := true
+
+		if q, found := strings.CutPrefix(strings.TrimSpace(params), "q="); found {
+			if v, err := strconv.ParseFloat(q, 64); err == nil && v == 0 {
+				ok = false
+			}
+		}
+
+		accepted[name] = ok
+	}
+
+	for _, e := range encodings {
+		if ok, found := accepted[e]; found {
+			if ok {
+				return e
+			}
+
+			continue
+		}
+
+		if accepted["*"] {
+			return e
+		}
+	}
+
+	return ""
+}
@@ -0,0 +1,276 @@
+package compression
+
+import (
+	"bufio"
+	"errors"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/bool64/stats"
+)
+
+// Config describes response compression.
+type Config struct {
+	// Enabled enables compression of responses.
+	Enabled bool
+
+	// Encodings lists supported content encodings in order of preference.
+	Encodings []string `default:"zstd,gzip,deflate"`
+
+	// Level is the compression level of encoder, 0 means default level.
+	Level int
+
+	// MinSize is the minimal size of response body in bytes to compress.
+	MinSize int `split_words:"true" default:"1024"`
+
+	// ContentTypes lists compressible media types, "text/*" matches any text type.
+	ContentTypes []string `split_words:"true" default:"application/json,application/javascript,application/xml,image/svg+xml,text/*"`
+}
+
+// Middleware creates response compression middleware.
+//
+// Compressed and uncompressed sizes of response bodies are counted with
+// "http_response_compressed_bytes" and "http_response_uncompressed_bytes" metrics.
+//
+// Middleware should be the outermost one (for example added first with router Use), so that
+// panic recovery, HTTP logging and idempotency middlewares observe uncompressed response bodies.
+func Middleware(cfg Config, tracker stats.Tracker) (func(http.Handler) http.Handler, error) {
+	if tracker == nil {
+		tracker = stats.NoOp{}
+	}
+
+	pools := make(map[string]*sync.Pool, len(cfg.Encodings))
+
+	for _, e := range cfg.Encodings {
+		p, err := newEncoderPool(e, cfg.Level)
+		if err != nil {
+			return nil, err
+		}
+
+		pools[e] = p
+	}
+
+	return func(next http.Handler) http.Handler {
+		if !cfg.Enabled || len(pools) == 0 {
+			return next
+		}
+
+		return http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+			rw.Header().Add("Vary", "Accept-Encoding")
+
+			encoding := negotiate(r.Header.Get("Accept-Encoding"), cfg.Encodings)
+			if encoding == "" || r.Method == http.MethodHead {
+				next.ServeHTTP(rw, r)
+
+				return
+			}
+
+			cw := &writer{
+				ResponseWriter: rw,
+				cfg:            &cfg,
+				encoding:       encoding,
+				pool:           pools[encoding],
+			}
+
+			defer func() {
+				cw.close()
+
+				if cw.enc != nil {
+					ctx := r.Context()
+					tracker.Add(ctx, "http_response_uncompressed_bytes", float64(cw.uncompressed), "encoding", encoding)
+					tracker.Add(ctx, "http_response_compressed_bytes", float64(cw.compressed.n), "encoding", encoding)
+				}
+			}()
+
+			next.ServeHTTP(cw, r)
+		})
+	}, nil
+}
+
+type countingWriter struct {
+	w http.ResponseWriter
+	n int
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += n
+
+	return n, err
+}
+
+type writer struct {
+	http.ResponseWriter
+
+	cfg      *Config
+	encoding string
+	pool     *sync.Pool
+
+	status        int
+	headerWritten bool
+	decided       bool
+	buf           []byte
+
+	enc          encoder
+	compressed   countingWriter
+	uncompressed int
+}
+
+func (w *writer) WriteHeader(status int) {
+	if w.headerWritten {
+		return
+	}
+
+	if status < http.StatusOK {
+		w.ResponseWriter.WriteHeader(status)
+
+		return
+	}
+
+	w.status = status
+	w.headerWritten = true
+
+	if status == http.StatusNoContent || status == http.StatusNotModified ||
+		w.Header().Get("Content-Encoding") != "" || !w.allowedType() {
+		w.decide(false)
+	}
+}
+
+func (w *writer) Write(p []byte) (int, error) {
+	if !w.headerWritten {
+		w.WriteHeader(http.StatusOK)
+	}
+
+	if w.decided {
+		if w.enc != nil {
+			w.uncompressed += len(p)
+
+			return w.enc.Write(p)
+		}
+
+		return w.ResponseWriter.Write(p)
+	}
+
+	w.buf = append(w.buf, p...)
+
+	if w.Header().Get("Content-Type") == "" {
+		w.Header().Set("Content-Type", http.DetectContentType(w.buf))
+	}
+
+	if len(w.buf) >= w.cfg.MinSize {
+		if err := w.decide(w.allowedType()); err != nil {
+			return 0, err
+		}
+	}
+
+	return len(p), nil
+}
+
+func (w *writer) allowedType() bool {
+	ct := w.Header().Get("Content-Type")
+	if ct == "" {
+		// Content type is not known yet, it will be detected on first write.
+		return true
+	}
+
+	mt, _, _ := strings.Cut(ct, ";")
+	mt = strings.TrimSpace(strings.ToLower(mt))
+
+	for _, t := range w.cfg.ContentTypes {
+		if t == mt {
+			return true
+		}
+
+		if prefix, ok := strings.CutSuffix(t, "*"); ok && strings.HasPrefix(mt, prefix) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// decide writes response header and flushes buffered body.
+func (w *writer) decide(compress bool) error {
+	if w.decided {
+		return nil
+	}
+
+	w.decided = true
+
+	if compress {
+		h := w.Header()
+		h.Del("Content-Length")
+		h.Set("Content-Encoding", w.encoding)
+
+		w.compressed.w = w.ResponseWriter
+		w.enc = w.pool.Get().(encoder) //nolint:errcheck // Pool only holds encoders.
+		w.enc.Reset(&w.compressed)
+	}
+
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+
+	w.ResponseWriter.WriteHeader(w.status)
+
+	if len(w.buf) == 0 {
+		return nil
+	}
+
+	buf := w.buf
+	w.buf = nil
+
+	var err error
+
+	if w.enc != nil {
+		w.uncompressed += len(buf)
+		_, err = w.enc.Write(buf)
+	} else {
+		_, err = w.ResponseWriter.Write(buf)
+	}
+
+	return err
+}
+
+func (w *writer) close() {
+	if !w.headerWritten {
+		return
+	}
+
+	if !w.decided {
+		_ = w.decide(len(w.buf) >= w.cfg.MinSize && w.allowedType()) //nolint:errcheck // Client write errors are not actionable.
+	}
+
+	if w.enc != nil {
+		_ = w.enc.Close() //nolint:errcheck // Client write errors are not actionable.
+
+		w.enc.Reset(nil)
+		w.pool.Put(w.enc)
+	}
+}
+
+// Flush implements http.Flusher.
+func (w *writer) Flush() {
+	if w.headerWritten && !w.decided {
+		_ = w.decide(w.allowedType()) //nolint:errcheck // Client write errors are not actionable.
+	}
+
+	if w.enc != nil {
+		_ = w.enc.Flush() //nolint:errcheck // Client write errors are not actionable.
+	}
+
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Hijack implements http.Hijacker.
+func (w *writer) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	if h, ok := w.ResponseWriter.(http.Hijacker); ok {
+		return h.Hijack()
+	}
+
+	return nil, nil, errors.New("response writer does not implement http.Hijacker")
+}
@@ -0,0 +1,95 @@
+package compression_test
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/bool64/brick/compression"
+	"github.com/bool64/stats"
+	"github.com/klauspost/compress/gzip"
+	"github.com/klauspost/compress/zstd"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMiddleware(t *testing.T) {
+	st := &stats.TrackerMock{}
+
+	mw, err := compression.Middleware(compression.Config{
+		Enabled:      true,
+		Encodings:    []string{compression.Zstd, compression.Gzip},
+		MinSize:      100,
+		ContentTypes: []string{"application/json", "text/*"},
+	}, st)
+	require.NoError(t, err)
+
+	large := `{"foo":"` + strings.Repeat("bar", 100) + `"}`
+
+	h := mw(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/large":
+			rw.Header().Set("Content-Type", "application/json")
+
+			for i := 0; i < len(large); i += 10 {
+				_, err := rw.Write([]byte(large[i:min(i+10, len(large))]))
+				assert.NoError(t, err)
+			}
+		case "/small":
+			_, err := rw.Write([]byte(`hello`))
+			assert.NoError(t, err)
+		case "/image":
+			rw.Header().Set("Content-Type", "image/png")
+			_, err := rw.Write([]byte(large))
+			assert.NoError(t, err)
+		}
+	}))
+
+	do := func(path, acceptEncoding string) *httptest.ResponseRecorder {
+		rw := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.Header.Set("Accept-Encoding", acceptEncoding)
+		h.ServeHTTP(rw, req)
+
+		return rw
+	}
+
+	rw := do("/large", "gzip, deflate, br")
+	assert.Equal(t, "gzip", rw.Header().Get("Content-Encoding"))
+	assert.Equal(t, "Accept-Encoding", rw.Header().Get("Vary"))
+
+	gr, err := gzip.NewReader(rw.Body)
+	require.NoError(t, err)
+
+	body, err := io.ReadAll(gr)
+	require.NoError(t, err)
+	assert.Equal(t, large, string(body))
+
+	rw = do("/large", "gzip;q=0.5, zstd")
+	assert.Equal(t, "zstd", rw.Header().Get("Content-Encoding"))
+
+	zr, err := zstd.NewReader(bytes.NewReader(rw.Body.Bytes()))
+	require.NoError(t, err)
+
+	body, err = io.ReadAll(zr)
+	require.NoError(t, err)
+	assert.Equal(t, large, string(body))
+	assert.Less(t, st.Value("http_response_compressed_bytes", "encoding", "zstd"), float64(len(large)))
+	assert.Equal(t, float64(len(large)), st.Value("http_response_uncompressed_bytes", "encoding", "zstd"))
+
+	rw = do("/large", "zstd;q=0, deflate")
+	assert.Empty(t, rw.Header().Get("Content-Encoding"))
+	assert.Equal(t, large, rw.Body.String())
+
+	rw = do("/small", "gzip")
+	assert.Empty(t, rw.Header().Get("Content-Encoding"))
+	assert.Equal(t, "text/plain; charset=utf-8", rw.Header().Get("Content-Type"))
+	assert.Equal(t, "hello", rw.Body.String())
+
+	rw = do("/image", "gzip")
+	assert.Empty(t, rw.Header().Get("Content-Encoding"))
+	assert.Equal(t, large, rw.Body.String())
+}
@@ -3,7 +3,14 @@ package brick
 import (
 	"time"
 
+	"github.com/bool64/brick/compression"
 	"github.com/bool64/brick/debug"
+	"github.com/bool64/brick/headers"
+	"github.com/bool64/brick/log"
+	"github.com/bool64/brick/logship"
+	"github.com/bool64/brick/metrics"
+	"github.com/bool64/brick/report"
+	"github.com/bool64/brick/tracing"
 	"github.com/bool64/zapctxd"
 )
 
@@ -14,6 +21,30 @@ type BaseConfig struct {
 
 	Log zapctxd.Config `split_words:"true"`
 
+	// LogSampling controls suppression of repeated log messages.
+	LogSampling log.Sampling `split_words:"true"`
+
+	// LogShipping controls sending of logs to remote collectors in addition to Log.Output.
+	LogShipping logship.Config `split_words:"true"`
+
+	// AccessLog controls logging of completed HTTP requests.
+	AccessLog log.AccessLog `split_words:"true"`
+
+	// HTTPMetrics controls Prometheus metrics of HTTP requests by route.
+	HTTPMetrics metrics.Config `split_words:"true"`
+
+	// SlowRequests controls detection and diagnostics of slow HTTP requests.
+	SlowRequests log.SlowRequests `split_words:"true"`
+
+	// BodyLog controls logging of HTTP request and response bodies.
+	BodyLog log.BodyLog `split_words:"true"`
+
+	// DebugRequests controls per-request elevation of logging to DEBUG level.
+	DebugRequests log.DebugRequests `split_words:"true"`
+
+	// DebugBuffer enables logging of buffered DEBUG messages of failed, panicked or slow requests.
+	DebugBuffer log.DebugBuffer `split_words:"true"`
+
 	// Environment is the name of environment where application runs.
 	Environment string `default:"dev"`
 
@@ -26,11 +57,35 @@ type BaseConfig struct {
 	// ShutdownTimeout limits time for graceful shutdown of an application.
 	ShutdownTimeout time.Duration `split_words:"true" default:"10s"`
 
+	// UseCaseDurationBuckets are upper bounds of use case duration histogram in seconds.
+	UseCaseDurationBuckets []float64 `split_words:"true" default:"0.005,0.01,0.025,0.05,0.1,0.25,0.5,1,2.5,5,10"`
+
+	// UsecaseErrorLevels overrides log levels of use case errors by status, for example "NOT_FOUND:off,ABORTED:error".
+	UsecaseErrorLevels map[string]string `split_words:"true"`
+
+	// Tracing selects tracing backend.
+	Tracing tracing.Config
+
+	// TraceSampling defines sampling rules, default probability is Debug.TraceSamplingProbability.
+	TraceSampling tracing.Sampling `split_words:"true"`
+
+	// ErrorReporting controls reporting of panics and internal errors.
+	ErrorReporting report.Config `split_words:"true"`
+
 	// Debug controls dev tools.
 	Debug debug.Config `split_words:"true"`
 
 	// CacheTransferURL is URL to fetch cache from on application start.
 	CacheTransferURL string `split_words:"true"`
+
+	// CORS controls Cross-Origin Resource Sharing.
+	CORS headers.CORS
+
+	// SecurityHeaders controls security headers of HTTP responses.
+	SecurityHeaders headers.Security `split_words:"true"`
+
+	// Compression controls compression of HTTP responses.
+	Compression compression.Config
 }
 
 // WithBaseConfig is an embedded config accessor.
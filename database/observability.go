@@ -8,19 +8,30 @@ import (
 	"time"
 
 	"contrib.go.opencensus.io/integrations/ocsql"
+	"github.com/bool64/brick/tracing"
 	"github.com/bool64/ctxd"
 	"github.com/bool64/dbwrap"
 	"github.com/bool64/stats"
-	"go.opencensus.io/trace"
 )
 
 // withTracing instruments database connector with OpenCensus tracing.
+//
+// Driver level tracing is skipped if default tracer is not OpenCensus, query spans are still
+// created with default tracer by query logging middleware.
 func withTracing(dbConnector driver.Connector) driver.Connector {
+	if _, ok := tracing.Default().(tracing.OpenCensus); !ok {
+		return dbConnector
+	}
+
 	return ocsql.WrapConnector(dbConnector, tracingOptions()...)
 }
 
 // driverNameWithTracing registers database driver name with OpenCensus tracing.
 func driverNameWithTracing(driverName string) (string, error) {
+	if _, ok := tracing.Default().(tracing.OpenCensus); !ok {
+		return driverName, nil
+	}
+
 	return ocsql.Register(driverName, tracingOptions()...)
 }
 
@@ -93,10 +104,9 @@ func observe(logger ctxd.Logger, statsTracker stats.Tracker, skipPackages []stri
 			return nil, nil
 		}
 
-		ctx, span := trace.StartSpan(ctx, caller+":"+string(operation))
-		span.AddAttributes(
-			trace.StringAttribute("stmt", statement),
-			trace.StringAttribute("args", fmt.Sprintf("%v", args)),
+		ctx, span := tracing.Start(ctx, caller+":"+string(operation),
+			tracing.String("stmt", statement),
+			tracing.String("args", fmt.Sprintf("%v", args)),
 		)
 
 		statsTracker.Add(ctx, "sql_storage_queries_total", 1, "method", caller)
@@ -114,7 +124,7 @@ func observe(logger ctxd.Logger, statsTracker stats.Tracker, skipPackages []stri
 			res := " complete"
 
 			if err != nil {
-				span.SetStatus(trace.Status{Message: err.Error()})
+				span.SetError(err)
 
 				res = " failed"
 			}
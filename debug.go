@@ -2,10 +2,8 @@ package brick
 
 import (
 	"net/http"
-	"strings"
 
 	"github.com/bool64/brick/debug"
-	"github.com/bool64/brick/debug/zpages"
 	"github.com/bool64/dev/version"
 	"github.com/bool64/logz/ctxz"
 	"github.com/bool64/logz/logzpage"
@@ -48,19 +46,14 @@ func (l *BaseLocator) SetupDebugRouter() {
 	dr.AddLink("version", "Version")
 	dr.Get("/version", version.Handler)
 
-	dr.AddLink("zpages/tracez", "Trace Spans")
-
-	if cfg.Debug.TraceURL != "" {
-		dr.Mount("/zpages", zpages.Mux(prefix+"/zpages", func(traceID string) string {
-			return strings.ReplaceAll(cfg.Debug.TraceURL, "{trace_id}", traceID)
-		}))
-	} else {
-		dr.Mount("/zpages", zpages.Mux(prefix+"/zpages", nil))
+	if l.SpanStore != nil {
+		dr.AddLink("traces", "Traces")
+		dr.Mount("/traces", l.SpanStore.Handler(prefix+"/traces"))
 	}
 
 	if pt, ok := l.StatsTracker().(*prom.Tracker); ok {
 		dr.AddLink("metrics", "Metrics")
-		dr.Method(http.MethodGet, "/metrics", promhttp.HandlerFor(pt.PrometheusRegistry(), promhttp.HandlerOpts{}))
+		dr.Method(http.MethodGet, "/metrics", promhttp.HandlerFor(pt.PrometheusRegistry(), promhttp.HandlerOpts{EnableOpenMetrics: true}))
 	}
 
 	if lz, ok := l.CtxdLogger().(ctxz.Observer); ok {
@@ -68,6 +61,18 @@ func (l *BaseLocator) SetupDebugRouter() {
 		dr.Mount("/logz", logzpage.Handler(lz.LevelObservers()...))
 	}
 
+	if l.LogLevel != nil {
+		dr.AddLink("loglevel", "Log Level")
+		dr.Method(http.MethodGet, "/loglevel", l.LogLevel)
+		dr.Method(http.MethodPost, "/loglevel", l.LogLevel)
+	}
+
+	if l.TraceSampler != nil {
+		dr.AddLink("sampling", "Trace Sampling")
+		dr.Method(http.MethodGet, "/sampling", l.TraceSampler)
+		dr.Method(http.MethodPost, "/sampling", l.TraceSampler)
+	}
+
 	if l.cacheTransfer != nil && l.cacheTransfer.CachesCount() > 0 {
 		dr.AddLink("export-cache", "Export Cache As JSONL")
 		dr.AddLink("transfer-cache", "Transfer Cache")
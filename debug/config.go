@@ -8,12 +8,18 @@ import (
 
 // Config keeps debug settings.
 type Config struct {
-	// TraceSamplingProbability is probability of exporting of OpenCensus trace.
+	// TraceSamplingProbability is probability of exporting of trace.
+	// Zero value disables sampling of traces that are not matched by sampling rules,
+	// unlike previous releases that used default sampler of OpenCensus (1 in 10000 traces) for zero value.
 	TraceSamplingProbability float64 `split_words:"true" default:"0.1"`
 
 	// TraceURL allows providing URL to {trace_id}, example http://jaeger.myservice.com/trace/{trace_id}.
 	TraceURL string `split_words:"true"`
 
+	// TraceViewSpans is the number of recent sampled spans kept in memory for trace viewer of dev tools,
+	// zero value disables trace viewer.
+	TraceViewSpans int `split_words:"true" default:"10000"`
+
 	// DevTools enables developer tools for documentation and debug.
 	DevTools bool `split_words:"true" default:"true"`
 
@@ -0,0 +1,278 @@
+package traceview
+
+import (
+	"encoding/json"
+	"html/template"
+	"math"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+)
+
+const style = `<style>
+body{font-family:sans-serif;font-size:14px}
+table{border-collapse:collapse}
+td,th{padding:2px 8px;text-align:left;border-bottom:1px solid #ddd}
+.err{color:#c1272d}
+.row{position:relative;height:18px;background:#f5f5f5;width:600px}
+.bar{position:absolute;height:18px;background:#4a90d9}
+.bar.err{background:#c1272d}
+</style>`
+
+var (
+	funcs = template.FuncMap{
+		"dur": func(d time.Duration) string {
+			return d.Round(time.Microsecond).String()
+		},
+	}
+
+	indexPage = template.Must(template.New("index").Funcs(funcs).Parse(`<!DOCTYPE html>
+<html><head><title>Traces</title>` + style + `</head><body>
+<h2>Traces</h2>
+<form method="get">
+<label>Span name <input name="name" value="{{.Query.Name}}" size="40" placeholder="route or use case"/></label>
+<label>Status <select name="status">
+<option value="">any</option>
+<option value="error"{{if eq .Query.Status "error"}} selected{{end}}>error</option>
+<option value="ok"{{if eq .Query.Status "ok"}} selected{{end}}>ok</option>
+</select></label>
+<label>Min duration <input name="min" value="{{.Min}}" size="6" placeholder="100ms"/></label>
+<button>Search</button> <a href="{{.Prefix}}/jaeger.json?{{.RawQuery}}">Export JSON</a>
+</form>
+<table><tr><th>Trace</th><th>Root span</th><th>Start</th><th>Duration</th><th>Spans</th></tr>
+{{range .Traces}}<tr{{if .Error}} class="err"{{end}}><td><a href="{{$.Prefix}}/trace/{{.TraceID}}">{{.TraceID}}</a></td>
+<td>{{.Root}}</td><td>{{.Start.Format "15:04:05.000"}}</td><td>{{dur .Duration}}</td><td>{{.Spans}}</td></tr>
+{{end}}</table>
+<h3>Span names</h3>
+<table><tr><th>Name</th><th>Count</th><th>Errors</th>{{range .Bounds}}<th>&lt;{{.}}</th>{{end}}<th>&ge;{{.Last}}</th></tr>
+{{range .Stats}}<tr><td><a href="{{$.Prefix}}/?name={{.Name}}">{{.Name}}</a></td><td>{{.Count}}</td>
+<td>{{if .Errors}}<a class="err" href="{{$.Prefix}}/errors?name={{.Name}}">{{.Errors}}</a>{{else}}0{{end}}</td>
+{{range .Latency}}<td>{{.}}</td>{{end}}</tr>
+{{end}}</table>
+</body></html>`))
+
+	errorsPage = template.Must(template.New("errors").Funcs(funcs).Parse(`<!DOCTYPE html>
+<html><head><title>Failed spans</title>` + style + `</head><body>
+<h2>Latest failed spans of {{.Name}}</h2>
+<table><tr><th>Trace</th><th>Start</th><th>Duration</th><th>Status</th></tr>
+{{range .Spans}}<tr><td><a href="{{$.Prefix}}/trace/{{.TraceID}}">{{.TraceID}}</a></td>
+<td>{{.Start.Format "15:04:05.000"}}</td><td>{{dur .Duration}}</td><td class="err">{{.Status}}</td></tr>
+{{end}}</table>
+</body></html>`))
+
+	tracePage = template.Must(template.New("trace").Funcs(funcs).Parse(`<!DOCTYPE html>
+<html><head><title>Trace {{.TraceID}}</title>` + style + `</head><body>
+<h2>Trace {{.TraceID}}</h2>
+<p>Duration {{dur .Duration}}, <a href="{{.Prefix}}/trace/{{.TraceID}}/jaeger.json">export JSON</a>
+{{with .URL}}, <a href="{{.}}">external viewer</a>{{end}}</p>
+<table><tr><th>Span</th><th>Duration</th><th></th></tr>
+{{range .Rows}}<tr{{if .Error}} class="err"{{end}}>
+<td style="padding-left:{{.Indent}}px" title="{{.Attributes}}">{{.Name}}{{with .Kind}} <small>{{.}}</small>{{end}}
+{{with .Status}}<br/><small>{{.}}</small>{{end}}
+{{range .Links}}<br/><small>follows <a href="{{$.Prefix}}/trace/{{.TraceID}}">{{.TraceID}}</a></small>{{end}}</td>
+<td>{{dur .Duration}}</td>
+<td><div class="row"><div class="bar{{if .Error}} err{{end}}" style="left:{{.Offset}}%;width:{{.Width}}%"></div></div></td></tr>
+{{end}}</table>
+{{with .Linked}}<h3>Linked traces</h3>
+<ul>{{range .}}<li><a href="{{$.Prefix}}/trace/{{.}}">{{.}}</a></li>{{end}}</ul>{{end}}
+</body></html>`))
+)
+
+type waterfallRow struct {
+	Span
+	Indent int
+	Offset float64
+	Width  float64
+}
+
+// Handler serves trace viewer at prefixed path.
+func (s *Store) Handler(prefix string) http.Handler {
+	r := chi.NewRouter()
+
+	r.Get("/", func(w http.ResponseWriter, r *http.Request) {
+		q := parseQuery(r)
+
+		bounds := make([]string, 0, len(LatencyBounds))
+		for _, b := range LatencyBounds {
+			bounds = append(bounds, b.String())
+		}
+
+		minDuration := ""
+		if q.MinDuration > 0 {
+			minDuration = q.MinDuration.String()
+		}
+
+		render(w, indexPage, map[string]interface{}{
+			"Prefix":   prefix,
+			"Query":    q,
+			"Min":      minDuration,
+			"RawQuery": template.URL(r.URL.RawQuery), //nolint:gosec // Query is escaped by browser.
+			"Traces":   s.Search(q),
+			"Stats":    s.Stats(),
+			"Bounds":   bounds,
+			"Last":     LatencyBounds[len(LatencyBounds)-1].String(),
+		})
+	})
+
+	r.Get("/errors", func(w http.ResponseWriter, r *http.Request) {
+		name := r.URL.Query().Get("name")
+
+		render(w, errorsPage, map[string]interface{}{
+			"Prefix": prefix,
+			"Name":   name,
+			"Spans":  s.ErrorSamples(name),
+		})
+	})
+
+	r.Get("/jaeger.json", func(w http.ResponseWriter, r *http.Request) {
+		var res JaegerExport
+
+		for _, t := range s.Search(parseQuery(r)) {
+			res.Data = append(res.Data, s.Jaeger(t.TraceID, s.Trace(t.TraceID)))
+		}
+
+		writeJSON(w, res)
+	})
+
+	r.Get("/trace/{id}/jaeger.json", func(w http.ResponseWriter, r *http.Request) {
+		id := chi.URLParam(r, "id")
+
+		spans := s.Trace(id)
+		if len(spans) == 0 {
+			http.NotFound(w, r)
+
+			return
+		}
+
+		writeJSON(w, JaegerExport{Data: []JaegerTrace{s.Jaeger(id, spans)}})
+	})
+
+	r.Get("/trace/{id}", func(w http.ResponseWriter, r *http.Request) {
+		id := chi.URLParam(r, "id")
+
+		spans := s.Trace(id)
+		if len(spans) == 0 {
+			http.NotFound(w, r)
+
+			return
+		}
+
+		rows, total := waterfall(spans)
+
+		url := ""
+		if s.TraceURL != nil {
+			url = s.TraceURL(id)
+		}
+
+		render(w, tracePage, map[string]interface{}{
+			"Prefix":   prefix,
+			"TraceID":  id,
+			"Duration": total,
+			"URL":      url,
+			"Rows":     rows,
+			"Linked":   s.LinkedTraces(id),
+		})
+	})
+
+	return r
+}
+
+func parseQuery(r *http.Request) Query {
+	v := r.URL.Query()
+	q := Query{
+		Name:   v.Get("name"),
+		Status: v.Get("status"),
+	}
+
+	if d, err := time.ParseDuration(v.Get("min")); err == nil {
+		q.MinDuration = d
+	}
+
+	return q
+}
+
+// waterfall orders spans depth-first with children after parents and positions them on timeline.
+func waterfall(spans []Span) ([]waterfallRow, time.Duration) {
+	var (
+		start, end = spans[0].Start, spans[0].End
+		children   = make(map[string][]Span)
+		ids        = make(map[string]bool, len(spans))
+		roots      []Span
+	)
+
+	for _, sp := range spans {
+		ids[sp.SpanID] = true
+
+		if sp.Start.Before(start) {
+			start = sp.Start
+		}
+
+		if sp.End.After(end) {
+			end = sp.End
+		}
+	}
+
+	for _, sp := range spans {
+		if ids[sp.ParentSpanID] {
+			children[sp.ParentSpanID] = append(children[sp.ParentSpanID], sp)
+		} else {
+			roots = append(roots, sp)
+		}
+	}
+
+	total := end.Sub(start)
+	rows := make([]waterfallRow, 0, len(spans))
+
+	var walk func(sp Span, depth int)
+
+	walk = func(sp Span, depth int) {
+		row := waterfallRow{Span: sp, Indent: 8 + 16*depth, Width: 100}
+
+		if total > 0 {
+			row.Offset = math.Round(1000*float64(sp.Start.Sub(start))/float64(total)) / 10
+			row.Width = math.Max(0.5, math.Round(1000*float64(sp.Duration())/float64(total))/10)
+		}
+
+		rows = append(rows, row)
+
+		for _, c := range children[sp.SpanID] {
+			walk(c, depth+1)
+		}
+	}
+
+	for _, sp := range roots {
+		walk(sp, 0)
+	}
+
+	return rows, total
+}
+
+func render(w http.ResponseWriter, t *template.Template, data interface{}) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+
+	if err := t.Execute(w, data); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+
+	for k := range m {
+		keys = append(keys, k)
+	}
+
+	sort.Strings(keys)
+
+	return keys
+}
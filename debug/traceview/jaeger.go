@@ -0,0 +1,138 @@
+package traceview
+
+import (
+	"fmt"
+)
+
+// JaegerTrace is a trace in JSON format of Jaeger query API, it can be loaded in Jaeger UI.
+type JaegerTrace struct {
+	TraceID   string                   `json:"traceID"`
+	Spans     []JaegerSpan             `json:"spans"`
+	Processes map[string]JaegerProcess `json:"processes"`
+}
+
+// JaegerSpan is a span of JaegerTrace.
+type JaegerSpan struct {
+	TraceID       string            `json:"traceID"`
+	SpanID        string            `json:"spanID"`
+	OperationName string            `json:"operationName"`
+	References    []JaegerReference `json:"references"`
+	StartTime     int64             `json:"startTime"`
+	Duration      int64             `json:"duration"`
+	Tags          []JaegerKeyValue  `json:"tags"`
+	Logs          []JaegerLog       `json:"logs"`
+	ProcessID     string            `json:"processID"`
+}
+
+// JaegerReference links span to parent or to a span of another trace.
+type JaegerReference struct {
+	RefType string `json:"refType"`
+	TraceID string `json:"traceID"`
+	SpanID  string `json:"spanID"`
+}
+
+// JaegerKeyValue is a tag of span or process.
+type JaegerKeyValue struct {
+	Key   string      `json:"key"`
+	Type  string      `json:"type"`
+	Value interface{} `json:"value"`
+}
+
+// JaegerLog is a span event.
+type JaegerLog struct {
+	Timestamp int64            `json:"timestamp"`
+	Fields    []JaegerKeyValue `json:"fields"`
+}
+
+// JaegerProcess describes service.
+type JaegerProcess struct {
+	ServiceName string           `json:"serviceName"`
+	Tags        []JaegerKeyValue `json:"tags"`
+}
+
+// JaegerExport is a payload that can be uploaded to Jaeger UI with "JSON File" search.
+type JaegerExport struct {
+	Data []JaegerTrace `json:"data"`
+}
+
+// Jaeger converts spans of a trace to Jaeger format.
+func (s *Store) Jaeger(traceID string, spans []Span) JaegerTrace {
+	service := s.ServiceName
+	if service == "" {
+		service = "unknown"
+	}
+
+	t := JaegerTrace{
+		TraceID:   traceID,
+		Spans:     make([]JaegerSpan, 0, len(spans)),
+		Processes: map[string]JaegerProcess{"p1": {ServiceName: service, Tags: []JaegerKeyValue{}}},
+	}
+
+	for _, sp := range spans {
+		js := JaegerSpan{
+			TraceID:       sp.TraceID,
+			SpanID:        sp.SpanID,
+			OperationName: sp.Name,
+			References:    []JaegerReference{},
+			StartTime:     sp.Start.UnixMicro(),
+			Duration:      sp.Duration().Microseconds(),
+			Tags:          make([]JaegerKeyValue, 0, len(sp.Attributes)+2),
+			Logs:          make([]JaegerLog, 0, len(sp.Events)),
+			ProcessID:     "p1",
+		}
+
+		if sp.ParentSpanID != "" {
+			js.References = append(js.References, JaegerReference{
+				RefType: "CHILD_OF",
+				TraceID: sp.TraceID,
+				SpanID:  sp.ParentSpanID,
+			})
+		}
+
+		for _, l := range sp.Links {
+			js.References = append(js.References, JaegerReference{
+				RefType: "FOLLOWS_FROM",
+				TraceID: l.TraceID,
+				SpanID:  l.SpanID,
+			})
+		}
+
+		for _, k := range sortedKeys(sp.Attributes) {
+			js.Tags = append(js.Tags, jaegerKeyValue(k, sp.Attributes[k]))
+		}
+
+		if sp.Kind != "" {
+			js.Tags = append(js.Tags, jaegerKeyValue("span.kind", sp.Kind))
+		}
+
+		if sp.Error {
+			js.Tags = append(js.Tags, jaegerKeyValue("error", true))
+		}
+
+		for _, e := range sp.Events {
+			js.Logs = append(js.Logs, JaegerLog{
+				Timestamp: e.Time.UnixMicro(),
+				Fields:    []JaegerKeyValue{jaegerKeyValue("event", e.Name)},
+			})
+		}
+
+		t.Spans = append(t.Spans, js)
+	}
+
+	return t
+}
+
+func jaegerKeyValue(key string, value interface{}) JaegerKeyValue {
+	switch v := value.(type) {
+	case string:
+		return JaegerKeyValue{Key: key, Type: "string", Value: v}
+	case bool:
+		return JaegerKeyValue{Key: key, Type: "bool", Value: v}
+	case int, int32, int64:
+		return JaegerKeyValue{Key: key, Type: "int64", Value: v}
+	case float32, float64:
+		return JaegerKeyValue{Key: key, Type: "float64", Value: v}
+	default:
+		return JaegerKeyValue{Key: key, Type: "string", Value: fmt.Sprintf("%v", v)}
+	}
+}
@@ -0,0 +1,443 @@
+// Package traceview provides in-memory span store and trace viewer for dev tools.
+package traceview
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	octrace "go.opencensus.io/trace"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const maxNames = 1000
+
+// OtherName is the name of summary of spans with new names after limit of distinct names is reached.
+const OtherName = "other"
+
+// LatencyBounds are upper bounds of latency buckets of span names.
+var LatencyBounds = []time.Duration{
+	10 * time.Microsecond,
+	100 * time.Microsecond,
+	time.Millisecond,
+	10 * time.Millisecond,
+	100 * time.Millisecond,
+	time.Second,
+	10 * time.Second,
+	100 * time.Second,
+}
+
+// Event is a timed annotation of a span.
+type Event struct {
+	Time time.Time `json:"time"`
+	Name string    `json:"name"`
+}
+
+// Link refers to a span of another trace, for example to a request that enqueued async work.
+type Link struct {
+	TraceID string `json:"traceId"`
+	SpanID  string `json:"spanId"`
+}
+
+// Span is a finished span of any tracing backend.
+type Span struct {
+	TraceID      string                 `json:"traceId"`
+	SpanID       string                 `json:"spanId"`
+	ParentSpanID string                 `json:"parentSpanId,omitempty"`
+	Name         string                 `json:"name"`
+	Kind         string                 `json:"kind,omitempty"`
+	Start        time.Time              `json:"start"`
+	End          time.Time              `json:"end"`
+	Attributes   map[string]interface{} `json:"attributes,omitempty"`
+	Error        bool                   `json:"error,omitempty"`
+	Status       string                 `json:"status,omitempty"`
+	Events       []Event                `json:"events,omitempty"`
+	Links        []Link                 `json:"links,omitempty"`
+}
+
+// Duration returns span latency.
+func (s Span) Duration() time.Duration {
+	return s.End.Sub(s.Start)
+}
+
+// NameStats summarizes spans with the same name.
+type NameStats struct {
+	Name   string
+	Count  int
+	Errors int
+
+	// Latency counts spans by LatencyBounds, last bucket counts spans slower than all bounds.
+	Latency []int
+
+	errorSamples []Span
+}
+
+// TraceSummary describes a trace found in store.
+type TraceSummary struct {
+	TraceID  string
+	Root     string
+	Start    time.Time
+	Duration time.Duration
+	Spans    int
+	Error    bool
+	names    map[string]bool
+}
+
+// Query filters traces.
+type Query struct {
+	// Name is matched as a substring of any span name of a trace, for example route or use case name.
+	Name string
+
+	// Status is "error" for traces with failed spans, "ok" for traces without failed spans, or empty for any.
+	Status string
+
+	// MinDuration is the minimal duration of local root span.
+	MinDuration time.Duration
+
+	// Limit is the max number of results, 100 is used by default.
+	Limit int
+}
+
+// Store keeps recent spans in memory.
+//
+// It implements go.opencensus.io/trace.Exporter and go.opentelemetry.io/otel/sdk/trace.SpanProcessor,
+// only sampled spans are stored.
+//
+// Please use NewStore to create an instance.
+type Store struct {
+	// ServiceName is used as process name in Jaeger export.
+	ServiceName string
+
+	// TraceURL creates URL of a trace in external viewer, for example Jaeger, optional.
+	TraceURL func(traceID string) string
+
+	mu           sync.Mutex
+	spans        []Span
+	next         int
+	names        map[string]*NameStats
+	errorSamples int
+}
+
+var (
+	_ octrace.Exporter       = &Store{}
+	_ sdktrace.SpanProcessor = &Store{}
+)
+
+// NewStore creates store with a ring buffer of spans and a number of latest failed spans to keep per span name.
+func NewStore(capacity, errorSamples int) *Store {
+	if capacity <= 0 {
+		capacity = 10000
+	}
+
+	if errorSamples <= 0 {
+		errorSamples = 10
+	}
+
+	return &Store{
+		spans:        make([]Span, 0, capacity),
+		names:        make(map[string]*NameStats),
+		errorSamples: errorSamples,
+	}
+}
+
+// Add stores a span.
+func (s *Store) Add(span Span) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if len(s.spans) < cap(s.spans) {
+		s.spans = append(s.spans, span)
+	} else {
+		s.spans[s.next] = span
+		s.next = (s.next + 1) % len(s.spans)
+	}
+
+	ns, ok := s.names[span.Name]
+	if !ok {
+		name := span.Name
+
+		if len(s.names) >= maxNames {
+			name = OtherName
+			ns, ok = s.names[name]
+		}
+
+		if !ok {
+			ns = &NameStats{Name: name, Latency: make([]int, len(LatencyBounds)+1)}
+			s.names[name] = ns
+		}
+	}
+
+	ns.Count++
+
+	i := sort.Search(len(LatencyBounds), func(i int) bool { return span.Duration() < LatencyBounds[i] })
+	ns.Latency[i]++
+
+	if span.Error {
+		ns.Errors++
+
+		if len(ns.errorSamples) >= s.errorSamples {
+			ns.errorSamples = ns.errorSamples[1:]
+		}
+
+		ns.errorSamples = append(ns.errorSamples, span)
+	}
+}
+
+// Stats returns summaries of span names ordered by name.
+func (s *Store) Stats() []NameStats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	res := make([]NameStats, 0, len(s.names))
+
+	for _, ns := range s.names {
+		st := *ns
+		st.Latency = append([]int(nil), ns.Latency...)
+		res = append(res, st)
+	}
+
+	sort.Slice(res, func(i, j int) bool { return res[i].Name < res[j].Name })
+
+	return res
+}
+
+// ErrorSamples returns latest failed spans of a span name.
+func (s *Store) ErrorSamples(name string) []Span {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if ns, ok := s.names[name]; ok {
+		return append([]Span(nil), ns.errorSamples...)
+	}
+
+	return nil
+}
+
+// Trace returns spans of a trace ordered by start time.
+//
+// Failed spans are kept as error samples, so they may outlive other spans of their trace.
+func (s *Store) Trace(traceID string) []Span {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var (
+		res  []Span
+		seen = make(map[string]bool)
+	)
+
+	add := func(sp Span) {
+		if sp.TraceID == traceID && !seen[sp.SpanID] {
+			seen[sp.SpanID] = true
+
+			res = append(res, sp)
+		}
+	}
+
+	for _, sp := range s.spans {
+		add(sp)
+	}
+
+	for _, ns := range s.names {
+		for _, sp := range ns.errorSamples {
+			add(sp)
+		}
+	}
+
+	sort.SliceStable(res, func(i, j int) bool { return res[i].Start.Before(res[j].Start) })
+
+	return res
+}
+
+// LinkedTraces returns IDs of traces with spans linked to the trace, ordered by start time.
+func (s *Store) LinkedTraces(traceID string) []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var (
+		res  []string
+		seen = make(map[string]bool)
+	)
+
+	for _, sp := range s.spans {
+		for _, l := range sp.Links {
+			if l.TraceID == traceID && sp.TraceID != traceID && !seen[sp.TraceID] {
+				seen[sp.TraceID] = true
+
+				res = append(res, sp.TraceID)
+			}
+		}
+	}
+
+	return res
+}
+
+// Search returns latest traces that match query.
+func (s *Store) Search(q Query) []TraceSummary {
+	if q.Limit <= 0 {
+		q.Limit = 100
+	}
+
+	s.mu.Lock()
+
+	traces := make(map[string]*TraceSummary)
+	spanIDs := make(map[string]bool, len(s.spans))
+
+	for _, sp := range s.spans {
+		spanIDs[sp.SpanID] = true
+	}
+
+	for _, sp := range s.spans {
+		t, ok := traces[sp.TraceID]
+		if !ok {
+			t = &TraceSummary{TraceID: sp.TraceID, names: make(map[string]bool)}
+			traces[sp.TraceID] = t
+		}
+
+		t.Spans++
+		t.Error = t.Error || sp.Error
+		t.names[sp.Name] = true
+
+		// Local root has no parent in store, the earliest one wins if trace is partially evicted.
+		if !spanIDs[sp.ParentSpanID] && (t.Root == "" || sp.Start.Before(t.Start)) {
+			t.Root = sp.Name
+			t.Start = sp.Start
+			t.Duration = sp.Duration()
+		}
+	}
+
+	s.mu.Unlock()
+
+	res := make([]TraceSummary, 0, len(traces))
+
+	for _, t := range traces {
+		if q.match(t) {
+			res = append(res, *t)
+		}
+	}
+
+	sort.Slice(res, func(i, j int) bool { return res[i].Start.After(res[j].Start) })
+
+	if len(res) > q.Limit {
+		res = res[:q.Limit]
+	}
+
+	return res
+}
+
+func (q Query) match(t *TraceSummary) bool {
+	if (q.Status == "error" && !t.Error) || (q.Status == "ok" && t.Error) || t.Duration < q.MinDuration {
+		return false
+	}
+
+	if q.Name == "" {
+		return true
+	}
+
+	for n := range t.names {
+		if strings.Contains(n, q.Name) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// ExportSpan implements go.opencensus.io/trace.Exporter.
+func (s *Store) ExportSpan(sd *octrace.SpanData) {
+	if !sd.IsSampled() {
+		return
+	}
+
+	sp := Span{
+		TraceID:    sd.TraceID.String(),
+		SpanID:     sd.SpanID.String(),
+		Name:       sd.Name,
+		Start:      sd.StartTime,
+		End:        sd.EndTime,
+		Attributes: sd.Attributes,
+		Error:      sd.Code != 0,
+		Status:     sd.Message,
+	}
+
+	if sd.ParentSpanID != (octrace.SpanID{}) {
+		sp.ParentSpanID = sd.ParentSpanID.String()
+	}
+
+	switch sd.SpanKind {
+	case octrace.SpanKindServer:
+		sp.Kind = "server"
+	case octrace.SpanKindClient:
+		sp.Kind = "client"
+	}
+
+	for _, a := range sd.Annotations {
+		sp.Events = append(sp.Events, Event{Time: a.Time, Name: a.Message})
+	}
+
+	for _, l := range sd.Links {
+		sp.Links = append(sp.Links, Link{TraceID: l.TraceID.String(), SpanID: l.SpanID.String()})
+	}
+
+	s.Add(sp)
+}
+
+// OnStart implements go.opentelemetry.io/otel/sdk/trace.SpanProcessor.
+func (s *Store) OnStart(_ context.Context, _ sdktrace.ReadWriteSpan) {}
+
+// OnEnd implements go.opentelemetry.io/otel/sdk/trace.SpanProcessor.
+func (s *Store) OnEnd(ro sdktrace.ReadOnlySpan) {
+	sc := ro.SpanContext()
+	if !sc.IsSampled() {
+		return
+	}
+
+	sp := Span{
+		TraceID: sc.TraceID().String(),
+		SpanID:  sc.SpanID().String(),
+		Name:    ro.Name(),
+		Start:   ro.StartTime(),
+		End:     ro.EndTime(),
+		Error:   ro.Status().Code == codes.Error,
+		Status:  ro.Status().Description,
+	}
+
+	if p := ro.Parent(); p.IsValid() {
+		sp.ParentSpanID = p.SpanID().String()
+	}
+
+	if k := ro.SpanKind(); k != trace.SpanKindInternal && k != trace.SpanKindUnspecified {
+		sp.Kind = k.String()
+	}
+
+	if attrs := ro.Attributes(); len(attrs) > 0 {
+		sp.Attributes = make(map[string]interface{}, len(attrs))
+
+		for _, a := range attrs {
+			sp.Attributes[string(a.Key)] = a.Value.AsInterface()
+		}
+	}
+
+	for _, e := range ro.Events() {
+		sp.Events = append(sp.Events, Event{Time: e.Time, Name: e.Name})
+	}
+
+	for _, l := range ro.Links() {
+		sp.Links = append(sp.Links, Link{TraceID: l.SpanContext.TraceID().String(), SpanID: l.SpanContext.SpanID().String()})
+	}
+
+	s.Add(sp)
+}
+
+// Shutdown implements go.opentelemetry.io/otel/sdk/trace.SpanProcessor.
+func (s *Store) Shutdown(_ context.Context) error {
+	return nil
+}
+
+// ForceFlush implements go.opentelemetry.io/otel/sdk/trace.SpanProcessor.
+func (s *Store) ForceFlush(_ context.Context) error {
+	return nil
+}
@@ -0,0 +1,144 @@
+package traceview_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/bool64/brick/debug/traceview"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestStore(t *testing.T) {
+	s := traceview.NewStore(10, 2)
+	s.ServiceName = "my-service"
+	s.TraceURL = func(traceID string) string {
+		return "http://jaeger/trace/" + traceID
+	}
+
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSampler(sdktrace.AlwaysSample()), sdktrace.WithSpanProcessor(s))
+	tr := tp.Tracer("test")
+
+	ctx, root := tr.Start(context.Background(), "GET /items", trace.WithSpanKind(trace.SpanKindServer))
+	_, child := tr.Start(ctx, "findItems")
+	child.AddEvent("cache miss")
+	child.RecordError(errors.New("failed"))
+	child.SetStatus(codes.Error, "failed")
+	child.End()
+	root.End(trace.WithTimestamp(time.Now().Add(200 * time.Millisecond)))
+
+	_, other := tr.Start(context.Background(), "GET /health")
+	other.End()
+
+	failedID := root.SpanContext().TraceID().String()
+
+	stats := s.Stats()
+	require.Len(t, stats, 3)
+	assert.Equal(t, "findItems", stats[2].Name)
+	assert.Equal(t, 1, stats[2].Errors)
+	assert.Len(t, s.ErrorSamples("findItems"), 1)
+
+	spans := s.Trace(failedID)
+	require.Len(t, spans, 2)
+	assert.Equal(t, "GET /items", spans[0].Name)
+	assert.Equal(t, "server", spans[0].Kind)
+	assert.Equal(t, spans[0].SpanID, spans[1].ParentSpanID)
+	assert.True(t, spans[1].Error)
+
+	assert.Len(t, s.Search(traceview.Query{}), 2)
+
+	found := s.Search(traceview.Query{Status: "error"})
+	require.Len(t, found, 1)
+	assert.Equal(t, failedID, found[0].TraceID)
+	assert.Equal(t, "GET /items", found[0].Root)
+	assert.Equal(t, 2, found[0].Spans)
+
+	assert.Len(t, s.Search(traceview.Query{Name: "find"}), 1)
+	assert.Len(t, s.Search(traceview.Query{MinDuration: 100 * time.Millisecond}), 1)
+	assert.Empty(t, s.Search(traceview.Query{Name: "health", Status: "error"}))
+
+	h := s.Handler("/debug/traces")
+
+	rw := httptest.NewRecorder()
+	h.ServeHTTP(rw, httptest.NewRequest(http.MethodGet, "/?status=error", nil))
+	assert.Equal(t, http.StatusOK, rw.Code)
+	assert.Contains(t, rw.Body.String(), `href="/debug/traces/trace/`+failedID+`"`)
+	assert.NotContains(t, rw.Body.String(), `<td>GET /health</td>`)
+
+	rw = httptest.NewRecorder()
+	h.ServeHTTP(rw, httptest.NewRequest(http.MethodGet, "/trace/"+failedID, nil))
+	assert.Equal(t, http.StatusOK, rw.Code)
+	assert.Contains(t, rw.Body.String(), "findItems")
+	assert.Contains(t, rw.Body.String(), `href="http://jaeger/trace/`+failedID+`"`)
+
+	rw = httptest.NewRecorder()
+	h.ServeHTTP(rw, httptest.NewRequest(http.MethodGet, "/trace/0123", nil))
+	assert.Equal(t, http.StatusNotFound, rw.Code)
+
+	rw = httptest.NewRecorder()
+	h.ServeHTTP(rw, httptest.NewRequest(http.MethodGet, "/trace/"+failedID+"/jaeger.json", nil))
+	assert.Equal(t, http.StatusOK, rw.Code)
+
+	var exp traceview.JaegerExport
+
+	require.NoError(t, json.Unmarshal(rw.Body.Bytes(), &exp))
+	require.Len(t, exp.Data, 1)
+	assert.Equal(t, "my-service", exp.Data[0].Processes["p1"].ServiceName)
+	require.Len(t, exp.Data[0].Spans, 2)
+	assert.Equal(t, "CHILD_OF", exp.Data[0].Spans[1].References[0].RefType)
+	assert.Len(t, exp.Data[0].Spans[1].Logs, 2)
+
+	_, async := tr.Start(context.Background(), "processJob", trace.WithLinks(trace.Link{SpanContext: root.SpanContext()}))
+	async.End()
+
+	asyncID := async.SpanContext().TraceID().String()
+
+	assert.Equal(t, []string{asyncID}, s.LinkedTraces(failedID))
+	assert.Equal(t, "FOLLOWS_FROM", s.Jaeger(asyncID, s.Trace(asyncID)).Spans[0].References[0].RefType)
+
+	rw = httptest.NewRecorder()
+	h.ServeHTTP(rw, httptest.NewRequest(http.MethodGet, "/trace/"+asyncID, nil))
+	assert.Contains(t, rw.Body.String(), `follows <a href="/debug/traces/trace/`+failedID+`">`)
+
+	rw = httptest.NewRecorder()
+	h.ServeHTTP(rw, httptest.NewRequest(http.MethodGet, "/trace/"+failedID, nil))
+	assert.Contains(t, rw.Body.String(), `<li><a href="/debug/traces/trace/`+asyncID+`">`)
+
+	// Failed spans outlive ring buffer.
+	for i := 0; i < 10; i++ {
+		_, sp := tr.Start(context.Background(), "GET /health")
+		sp.End()
+	}
+
+	assert.Len(t, s.Trace(failedID), 1)
+}
+
+func TestStore_Add_otherNames(t *testing.T) {
+	s := traceview.NewStore(10, 2)
+
+	for i := 0; i < 1000; i++ {
+		s.Add(traceview.Span{Name: "span" + strconv.Itoa(i)})
+	}
+
+	s.Add(traceview.Span{Name: "late", Error: true})
+	s.Add(traceview.Span{Name: "later"})
+
+	stats := s.Stats()
+	require.Len(t, stats, 1001)
+	assert.Equal(t, traceview.OtherName, stats[0].Name)
+	assert.Equal(t, 2, stats[0].Count)
+	assert.Equal(t, 1, stats[0].Errors)
+
+	samples := s.ErrorSamples(traceview.OtherName)
+	require.Len(t, samples, 1)
+	assert.Equal(t, "late", samples[0].Name)
+}
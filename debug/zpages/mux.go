@@ -1,4 +1,7 @@
 // Package zpages provides OpenCensus zpages handlers.
+//
+// Deprecated: use github.com/bool64/brick/debug/traceview, it shows spans of any tracing backend.
+// Package will be removed in a future release.
 package zpages
 
 import (
@@ -66,6 +69,8 @@ td.head{text-align:center;color:#FFF;line-height:3.0;}`
 // Mux creates zpages mux to serve at prefixed path.
 // If traceToURL is not nil, sampled traces are converted to URLs (URL could
 // lead to Jaeger instance for example).
+//
+// Deprecated: use traceview.Store and its Handler.
 func Mux(prefix string, traceToURL func(traceID string) string) http.Handler {
 	mux := http.NewServeMux()
 	zpages.Handle(mux, prefix+"/")
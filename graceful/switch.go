@@ -1,8 +1,10 @@
 package graceful
 
 import (
+	"fmt"
 	"os"
 	"os/signal"
+	"runtime/debug"
 	"sort"
 	"strings"
 	"sync"
@@ -22,6 +24,10 @@ func (e ErrTimeout) Error() string {
 //
 // Please use NewSwitch to create an instance.
 type Switch struct {
+	// PanicHandler receives panics recovered from shutdown tasks,
+	// panic is printed to stderr if handler is not set.
+	PanicHandler func(task string, rcv interface{}, stack []byte)
+
 	sig chan os.Signal
 
 	done <-chan error
@@ -29,6 +35,12 @@ type Switch struct {
 	mu     sync.Mutex
 	closed bool
 	tasks  map[string]func()
+	after  []namedTask
+}
+
+type namedTask struct {
+	name string
+	fn   func()
 }
 
 // NewSwitch creates shutdown handler that triggers on any of provided OS signals
@@ -81,6 +93,8 @@ func (s *Switch) waitForSignal(done chan error, timeout time.Duration) {
 				<-sem
 			}()
 
+			defer s.recoverTask(name)
+
 			fn()
 		}()
 	}
@@ -88,28 +102,61 @@ func (s *Switch) waitForSignal(done chan error, timeout time.Duration) {
 
 	deadline := time.After(timeout)
 
+	var timedOut ErrTimeout
+
+wait:
 	for i := 0; i < cap(sem); i++ {
 		select {
 		case sem <- struct{}{}:
 		case <-deadline:
-			var err ErrTimeout
-
 			s.mu.Lock()
 
 			for k := range active {
-				err = append(err, k)
-				sort.Strings(err)
+				timedOut = append(timedOut, k)
 			}
 
 			s.mu.Unlock()
 
-			done <- err
+			sort.Strings(timedOut)
+
+			break wait
 		}
 	}
 
+	s.mu.Lock()
+	after := s.after
+	s.mu.Unlock()
+
+	for _, t := range after {
+		func() {
+			defer s.recoverTask(t.name)
+
+			t.fn()
+		}()
+	}
+
+	if timedOut != nil {
+		done <- timedOut
+	}
+
 	close(done)
 }
 
+func (s *Switch) recoverTask(name string) {
+	rcv := recover()
+	if rcv == nil {
+		return
+	}
+
+	if s.PanicHandler != nil {
+		s.PanicHandler(name, rcv, debug.Stack())
+
+		return
+	}
+
+	_, _ = fmt.Fprintf(os.Stderr, "graceful: shutdown task %s panicked: %v\n%s\n", name, rcv, debug.Stack())
+}
+
 // Wait returns a channel that blocks until switch is triggered.
 //
 // Resulting channel may return a non-empty error if tasks fail to finish within a timeout.
@@ -129,6 +176,21 @@ func (s *Switch) OnShutdown(name string, fn func()) {
 	s.tasks[name] = fn
 }
 
+// AfterShutdown adds a named task to run after shutdown tasks are finished or timed out.
+//
+// Such tasks are invoked sequentially in order of registration before Wait unblocks,
+// for example to deliver logs of shutdown tasks.
+func (s *Switch) AfterShutdown(name string, fn func()) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.tasks == nil {
+		panic("graceful: Switch is not initialized, did you call NewSwitch?")
+	}
+
+	s.after = append(s.after, namedTask{name: name, fn: fn})
+}
+
 // Shutdown triggers the switch and stops listening to OS signals.
 func (s *Switch) Shutdown() {
 	s.mu.Lock()
@@ -2,6 +2,7 @@ package graceful_test
 
 import (
 	"os"
+	"sync"
 	"syscall"
 	"testing"
 	"time"
@@ -92,3 +93,81 @@ func TestNewShutdown_timeout(t *testing.T) {
 		assert.Fail(t, "failed to shutdown in reasonable time")
 	}
 }
+
+func TestNewShutdown_panic(t *testing.T) {
+	var (
+		ok       bool
+		panicked string
+	)
+
+	done := graceful.NewSwitch(time.Minute, syscall.SIGTERM)
+	done.PanicHandler = func(task string, rcv interface{}, _ []byte) {
+		panicked = task + ": " + rcv.(string)
+	}
+
+	done.OnShutdown("test1", func() { panic("oops") })
+	done.OnShutdown("test2", func() { ok = true })
+	done.Shutdown()
+
+	select {
+	case err := <-done.Wait():
+		assert.NoError(t, err)
+	case <-time.After(time.Second):
+		assert.Fail(t, "failed to shutdown in reasonable time")
+	}
+
+	assert.True(t, ok)
+	assert.Equal(t, "test1: oops", panicked)
+}
+
+func TestSwitch_AfterShutdown(t *testing.T) {
+	var (
+		mu    sync.Mutex
+		order []string
+	)
+
+	add := func(name string) func() {
+		return func() {
+			mu.Lock()
+			defer mu.Unlock()
+
+			order = append(order, name)
+		}
+	}
+
+	done := graceful.NewSwitch(time.Minute, syscall.SIGTERM)
+	done.AfterShutdown("flush", add("flush"))
+	done.AfterShutdown("close", add("close"))
+	done.OnShutdown("test1", func() {
+		time.Sleep(10 * time.Millisecond)
+		add("test1")()
+	})
+	done.Shutdown()
+
+	select {
+	case err := <-done.Wait():
+		assert.NoError(t, err)
+	case <-time.After(time.Second):
+		assert.Fail(t, "failed to shutdown in reasonable time")
+	}
+
+	assert.Equal(t, []string{"test1", "flush", "close"}, order)
+}
+
+func TestSwitch_AfterShutdown_timeout(t *testing.T) {
+	var ok bool
+
+	done := graceful.NewSwitch(time.Millisecond, syscall.SIGTERM)
+	done.OnShutdown("test1", func() { time.Sleep(time.Minute) })
+	done.AfterShutdown("flush", func() { ok = true })
+	done.Shutdown()
+
+	select {
+	case err := <-done.Wait():
+		assert.EqualError(t, err, "shutdown timeout, tasks left: test1")
+	case <-time.After(time.Second):
+		assert.Fail(t, "failed to shutdown in reasonable time")
+	}
+
+	assert.True(t, ok)
+}
@@ -0,0 +1,188 @@
+package headers
+
+import (
+	"errors"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// CORS describes Cross-Origin Resource Sharing.
+type CORS struct {
+	// AllowedOrigins is a list of allowed origins, empty list disables CORS.
+	// Origin can contain wildcards, for example "https://*.example.com", "*" allows any origin.
+	AllowedOrigins []string `split_words:"true"`
+
+	// AllowedMethods limits methods for preflight requests, by default methods of matched route are allowed.
+	AllowedMethods []string `split_words:"true"`
+
+	// AllowedHeaders lists request headers allowed for preflight requests.
+	AllowedHeaders []string `split_words:"true" default:"Accept,Authorization,Content-Type,X-Request-Id"`
+
+	// ExposedHeaders lists response headers exposed to the client.
+	ExposedHeaders []string `split_words:"true"`
+
+	// AllowCredentials allows requests with cookies and authorization headers.
+	AllowCredentials bool `split_words:"true"`
+
+	// MaxAge is a duration for browser to cache preflight response.
+	MaxAge time.Duration `split_words:"true" default:"10m"`
+}
+
+var errCredentialsAnyOrigin = errors.New("CORS credentials can not be allowed for any origin \"*\"")
+
+// Validate checks configuration.
+//
+// Credentials are not allowed with "*" origin, that would let any site make credentialed requests.
+func (c CORS) Validate() error {
+	if !c.AllowCredentials {
+		return nil
+	}
+
+	for _, o := range c.AllowedOrigins {
+		if o == "*" {
+			return errCredentialsAnyOrigin
+		}
+	}
+
+	return nil
+}
+
+var preflightMethods = []string{
+	http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut,
+	http.MethodPatch, http.MethodDelete,
+}
+
+func (c CORS) allowOrigin(origin string) bool {
+	for _, o := range c.AllowedOrigins {
+		if o == "*" || o == origin {
+			return true
+		}
+
+		if ok, err := path.Match(o, origin); err == nil && ok {
+			return true
+		}
+	}
+
+	return false
+}
+
+// CORSMiddleware creates router middleware to handle CORS requests.
+//
+// Preflight requests are served for routes that exist in chi router for the requested method,
+// other OPTIONS requests are passed to the router.
+//
+// Configuration should be checked with Validate, credentials are not allowed for "*" origin.
+func CORSMiddleware(cfg CORS) func(http.Handler) http.Handler {
+	anyOrigin := false
+
+	for _, o := range cfg.AllowedOrigins {
+		if o == "*" {
+			anyOrigin = true
+		}
+	}
+
+	allowHeaders := strings.Join(cfg.AllowedHeaders, ", ")
+	exposeHeaders := strings.Join(cfg.ExposedHeaders, ", ")
+	maxAge := strconv.Itoa(int(cfg.MaxAge.Seconds()))
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+			origin := r.Header.Get("Origin")
+			h := rw.Header()
+
+			h.Add("Vary", "Origin")
+
+			if origin == "" || !cfg.allowOrigin(origin) {
+				next.ServeHTTP(rw, r)
+
+				return
+			}
+
+			if anyOrigin {
+				h.Set("Access-Control-Allow-Origin", "*")
+			} else {
+				h.Set("Access-Control-Allow-Origin", origin)
+			}
+
+			if cfg.AllowCredentials && !anyOrigin {
+				h.Set("Access-Control-Allow-Credentials", "true")
+			}
+
+			reqMethod := r.Header.Get("Access-Control-Request-Method")
+
+			if r.Method != http.MethodOptions || reqMethod == "" {
+				if exposeHeaders != "" {
+					h.Set("Access-Control-Expose-Headers", exposeHeaders)
+				}
+
+				next.ServeHTTP(rw, r)
+
+				return
+			}
+
+			methods := cfg.routeMethods(r)
+			if !contains(methods, reqMethod) {
+				next.ServeHTTP(rw, r)
+
+				return
+			}
+
+			h.Add("Vary", "Access-Control-Request-Method")
+			h.Add("Vary", "Access-Control-Request-Headers")
+			h.Set("Access-Control-Allow-Methods", strings.Join(methods, ", "))
+
+			if allowHeaders != "" {
+				h.Set("Access-Control-Allow-Headers", allowHeaders)
+			}
+
+			if cfg.MaxAge > 0 {
+				h.Set("Access-Control-Max-Age", maxAge)
+			}
+
+			rw.WriteHeader(http.StatusNoContent)
+		})
+	}
+}
+
+// routeMethods returns allowed methods that have a route for request path.
+func (c CORS) routeMethods(r *http.Request) []string {
+	candidates := c.AllowedMethods
+	if len(candidates) == 0 {
+		candidates = preflightMethods
+	}
+
+	rctx := chi.RouteContext(r.Context())
+	if rctx == nil || rctx.Routes == nil {
+		return candidates
+	}
+
+	p := r.URL.RawPath
+	if p == "" {
+		p = r.URL.Path
+	}
+
+	var methods []string
+
+	for _, m := range candidates {
+		if rctx.Routes.Match(chi.NewRouteContext(), m, p) {
+			methods = append(methods, m)
+		}
+	}
+
+	return methods
+}
+
+func contains(items []string, item string) bool {
+	for _, i := range items {
+		if i == item {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,101 @@
+package headers_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/bool64/brick/headers"
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCORSMiddleware(t *testing.T) {
+	r := chi.NewRouter()
+	r.Use(headers.CORSMiddleware(headers.CORS{
+		AllowedOrigins:   []string{"https://*.example.com"},
+		AllowedHeaders:   []string{"Content-Type"},
+		ExposedHeaders:   []string{"X-Total"},
+		AllowCredentials: true,
+		MaxAge:           time.Hour,
+	}))
+
+	r.Route("/api", func(r chi.Router) {
+		r.Get("/items/{id}", func(rw http.ResponseWriter, _ *http.Request) {
+			rw.WriteHeader(http.StatusOK)
+		})
+		r.Delete("/items/{id}", func(rw http.ResponseWriter, _ *http.Request) {
+			rw.WriteHeader(http.StatusOK)
+		})
+	})
+
+	do := func(method, path, origin, reqMethod string) *httptest.ResponseRecorder {
+		rw := httptest.NewRecorder()
+		req := httptest.NewRequest(method, path, nil)
+		req.Header.Set("Origin", origin)
+
+		if reqMethod != "" {
+			req.Header.Set("Access-Control-Request-Method", reqMethod)
+		}
+
+		r.ServeHTTP(rw, req)
+
+		return rw
+	}
+
+	rw := do(http.MethodOptions, "/api/items/1", "https://app.example.com", http.MethodDelete)
+	assert.Equal(t, http.StatusNoContent, rw.Code)
+	assert.Equal(t, "https://app.example.com", rw.Header().Get("Access-Control-Allow-Origin"))
+	assert.Equal(t, "true", rw.Header().Get("Access-Control-Allow-Credentials"))
+	assert.Equal(t, "GET, DELETE", rw.Header().Get("Access-Control-Allow-Methods"))
+	assert.Equal(t, "Content-Type", rw.Header().Get("Access-Control-Allow-Headers"))
+	assert.Equal(t, "3600", rw.Header().Get("Access-Control-Max-Age"))
+
+	rw = do(http.MethodOptions, "/api/items/1", "https://app.example.com", http.MethodPost)
+	assert.Equal(t, http.StatusMethodNotAllowed, rw.Code)
+	assert.Empty(t, rw.Header().Get("Access-Control-Allow-Methods"))
+
+	rw = do(http.MethodOptions, "/api/unknown", "https://app.example.com", http.MethodGet)
+	assert.Equal(t, http.StatusNotFound, rw.Code)
+
+	rw = do(http.MethodGet, "/api/items/1", "https://app.example.com", "")
+	assert.Equal(t, http.StatusOK, rw.Code)
+	assert.Equal(t, "https://app.example.com", rw.Header().Get("Access-Control-Allow-Origin"))
+	assert.Equal(t, "X-Total", rw.Header().Get("Access-Control-Expose-Headers"))
+
+	rw = do(http.MethodGet, "/api/items/1", "https://evil.com", "")
+	assert.Equal(t, http.StatusOK, rw.Code)
+	assert.Empty(t, rw.Header().Get("Access-Control-Allow-Origin"))
+}
+
+func TestSecurityMiddleware(t *testing.T) {
+	h := headers.SecurityMiddleware(headers.Security{
+		Preset:     headers.PresetDefault,
+		HSTSMaxAge: 24 * time.Hour,
+		Headers:    map[string]string{"x-frame-options": "", "Permissions-Policy": "camera=()"},
+	})(http.HandlerFunc(func(_ http.ResponseWriter, _ *http.Request) {}))
+
+	rw := httptest.NewRecorder()
+	h.ServeHTTP(rw, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	assert.Equal(t, http.Header{
+		"X-Content-Type-Options":    []string{"nosniff"},
+		"Referrer-Policy":           []string{"strict-origin-when-cross-origin"},
+		"Strict-Transport-Security": []string{"max-age=86400"},
+		"Permissions-Policy":        []string{"camera=()"},
+	}, rw.Header())
+}
+
+func TestCORS_Validate(t *testing.T) {
+	assert.NoError(t, headers.CORS{AllowedOrigins: []string{"*"}}.Validate())
+	assert.NoError(t, headers.CORS{AllowedOrigins: []string{"https://*.example.com"}, AllowCredentials: true}.Validate())
+	assert.Error(t, headers.CORS{AllowedOrigins: []string{"https://example.com", "*"}, AllowCredentials: true}.Validate())
+}
+
+func TestSecurity_Validate(t *testing.T) {
+	assert.NoError(t, headers.Security{}.Validate())
+	assert.NoError(t, headers.Security{Preset: headers.PresetStrict}.Validate())
+	assert.EqualError(t, headers.Security{Preset: "strcit"}.Validate(),
+		"unknown security headers preset, expected none, default or strict: strcit")
+}
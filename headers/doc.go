@@ -0,0 +1,2 @@
+// Package headers provides CORS and security headers middlewares.
+package headers
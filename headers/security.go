@@ -0,0 +1,120 @@
+package headers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Security header presets.
+const (
+	PresetNone    = "none"
+	PresetDefault = "default"
+	PresetStrict  = "strict"
+)
+
+// Security describes security response headers.
+type Security struct {
+	// Preset is a base set of headers: "none", "default" or "strict".
+	//
+	// Default preset disables content type sniffing and framing by other origins,
+	// strict preset also forbids loading of any content, it is only suitable for pure API services.
+	Preset string `default:"default" enum:"none,default,strict"`
+
+	// HSTSMaxAge enables Strict-Transport-Security header with max-age directive.
+	HSTSMaxAge time.Duration `split_words:"true"`
+
+	// HSTSIncludeSubdomains adds includeSubDomains directive to Strict-Transport-Security header.
+	HSTSIncludeSubdomains bool `split_words:"true"`
+
+	// ContentSecurityPolicy overrides Content-Security-Policy header of preset.
+	ContentSecurityPolicy string `split_words:"true"`
+
+	// Headers are added to (or override) preset headers, empty value removes header.
+	Headers map[string]string
+}
+
+var presets = map[string]map[string]string{
+	PresetDefault: {
+		"X-Content-Type-Options": "nosniff",
+		"X-Frame-Options":        "SAMEORIGIN",
+		"Referrer-Policy":        "strict-origin-when-cross-origin",
+	},
+	PresetStrict: {
+		"X-Content-Type-Options":       "nosniff",
+		"X-Frame-Options":              "DENY",
+		"Referrer-Policy":              "no-referrer",
+		"Content-Security-Policy":      "default-src 'none'; frame-ancestors 'none'",
+		"Cross-Origin-Resource-Policy": "same-origin",
+	},
+}
+
+var errUnknownPreset = errors.New("unknown security headers preset, expected none, default or strict")
+
+// Validate checks configuration.
+func (s Security) Validate() error {
+	switch s.Preset {
+	case "", PresetNone, PresetDefault, PresetStrict:
+		return nil
+	}
+
+	return fmt.Errorf("%w: %s", errUnknownPreset, s.Preset)
+}
+
+// Values returns resulting headers.
+func (s Security) Values() map[string]string {
+	values := make(map[string]string)
+
+	for k, v := range presets[s.Preset] {
+		values[k] = v
+	}
+
+	if s.HSTSMaxAge > 0 {
+		v := "max-age=" + strconv.Itoa(int(s.HSTSMaxAge.Seconds()))
+
+		if s.HSTSIncludeSubdomains {
+			v += "; includeSubDomains"
+		}
+
+		values["Strict-Transport-Security"] = v
+	}
+
+	if s.ContentSecurityPolicy != "" {
+		values["Content-Security-Policy"] = s.ContentSecurityPolicy
+	}
+
+	for k, v := range s.Headers {
+		k = http.CanonicalHeaderKey(k)
+
+		if v == "" {
+			delete(values, k)
+		} else {
+			values[k] = v
+		}
+	}
+
+	return values
+}
+
+// SecurityMiddleware creates middleware that adds security headers to responses.
+func SecurityMiddleware(cfg Security) func(http.Handler) http.Handler {
+	values := cfg.Values()
+
+	return func(next http.Handler) http.Handler {
+		if len(values) == 0 {
+			return next
+		}
+
+		return http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+			h := rw.Header()
+
+			for k, v := range values {
+				h.Set(k, v)
+			}
+
+			next.ServeHTTP(rw, r)
+		})
+	}
+}
@@ -8,6 +8,7 @@ import (
 	"net/http"
 	"time"
 
+	"github.com/bool64/brick/headers"
 	"github.com/bool64/prom-stats"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"github.com/swaggest/openapi-go/openapi3"
@@ -22,10 +23,25 @@ func NewBaseWebService(l *BaseLocator) *web.Service {
 	r := web.NewService(openapi3.NewReflector(), l.HTTPServiceOptions...)
 
 	// Setup middlewares.
+	// Compression is outermost to keep response bodies uncompressed for logging and panic recovery.
+	if l.HTTPCompressionMiddleware != nil {
+		r.Use(l.HTTPCompressionMiddleware)
+	}
+
+	if l.HTTPMetricsMiddleware != nil {
+		r.Use(l.HTTPMetricsMiddleware)
+	}
+
+	r.Use(headers.SecurityMiddleware(l.BaseConfig.SecurityHeaders))
+
+	if len(l.BaseConfig.CORS.AllowedOrigins) > 0 {
+		r.Use(headers.CORSMiddleware(l.BaseConfig.CORS))
+	}
+
 	r.Wrap(l.HTTPServerMiddlewares...)
 
 	if pt, ok := l.StatsTracker().(*prom.Tracker); ok {
-		r.Method(http.MethodGet, "/metrics", promhttp.HandlerFor(pt.PrometheusRegistry(), promhttp.HandlerOpts{}))
+		r.Method(http.MethodGet, "/metrics", promhttp.HandlerFor(pt.PrometheusRegistry(), promhttp.HandlerOpts{EnableOpenMetrics: true}))
 	}
 
 	if l.BaseConfig.Debug.DevTools {
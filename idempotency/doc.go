@@ -0,0 +1,2 @@
+// Package idempotency provides Idempotency-Key support for unsafe HTTP requests.
+package idempotency
@@ -0,0 +1,34 @@
+package idempotency
+
+import "sync"
+
+// locks tracks keys of requests in progress.
+type locks struct {
+	mu   sync.Mutex
+	keys map[string]struct{}
+}
+
+func newLocks() *locks {
+	return &locks{keys: make(map[string]struct{})}
+}
+
+// lock returns false if key is already locked.
+func (l *locks) lock(key string) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if _, ok := l.keys[key]; ok {
+		return false
+	}
+
+	l.keys[key] = struct{}{}
+
+	return true
+}
+
+func (l *locks) unlock(key string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	delete(l.keys, key)
+}
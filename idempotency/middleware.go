@@ -0,0 +1,350 @@
+package idempotency
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"slices"
+
+	"github.com/bool64/brick/auth"
+	"github.com/bool64/cache"
+	"github.com/bool64/ctxd"
+	oapi "github.com/swaggest/openapi-go"
+	"github.com/swaggest/rest"
+	"github.com/swaggest/rest/nethttp"
+	"github.com/swaggest/rest/openapi"
+	"github.com/swaggest/usecase/status"
+)
+
+// Header is the name of request header with idempotency key.
+const Header = "Idempotency-Key"
+
+// ReplayedHeader is set to "true" in replayed responses.
+const ReplayedHeader = "Idempotent-Replayed"
+
+// Response is a stored response of idempotent request.
+type Response struct {
+	Status      int         `json:"status"`
+	Header      http.Header `json:"header"`
+	Body        []byte      `json:"body"`
+	RequestHash string      `json:"requestHash"`
+
+	// TooLarge is set if response body exceeds Config.MaxBodySize, such response is not replayed.
+	TooLarge bool `json:"tooLarge,omitempty"`
+}
+
+// CacheConfig configures failover cache to store responses, it is intended for brick.MakeCacheOf.
+//
+//	responses := brick.MakeCacheOf[idempotency.Response](l, "idempotency", 24*time.Hour, idempotency.CacheConfig)
+//
+// Responses are built synchronously and failures are not cached, so that a failed request can be retried.
+func CacheConfig(cfg *cache.FailoverConfigOf[Response]) {
+	cfg.SyncUpdate = true
+	cfg.FailedUpdateTTL = -1
+	cfg.FailHard = true
+}
+
+// Config describes idempotency middleware.
+type Config struct {
+	// Cache stores responses, it should be configured with CacheConfig.
+	Cache *cache.FailoverOf[Response]
+
+	// Collector receives documentation of idempotency header, optional.
+	Collector *openapi.Collector
+
+	// Principal identifies the client, default is auth.PrincipalID.
+	// Keys of different clients do not collide, requests with empty principal are served
+	// without idempotency unless AllowAnonymous is set.
+	Principal func(r *http.Request) string
+
+	// AllowAnonymous enables idempotency for requests with empty principal,
+	// such clients share keys and can replay responses of each other.
+	AllowAnonymous bool
+
+	// MaxBodySize limits the size of response body to store, default 1 MiB.
+	// Retries of requests with larger responses are rejected with 409 Conflict.
+	MaxBodySize int
+
+	// MaxRequestSize limits the size of request body with idempotency key, default 1 MiB.
+	// Larger requests are rejected with 413 Request Entity Too Large.
+	MaxRequestSize int64
+
+	// Logger reports failures.
+	Logger ctxd.Logger
+}
+
+type idempotencyHeader struct {
+	Key string `header:"Idempotency-Key" description:"Unique key to safely retry the request, response of completed request is replayed for the same key."`
+}
+
+var (
+	errNotStored = errors.New("response is not stored")
+	errInFlight  = errors.New("request with the same idempotency key is in progress")
+	errTooLarge  = errors.New("response of request with the same idempotency key is too large to replay")
+)
+
+// Middleware creates idempotency middleware for unsafe HTTP methods.
+//
+// For a request with Idempotency-Key header the response is stored and replayed for retries
+// with the same key and principal. Concurrent request with the same key is rejected with 409 Conflict,
+// and reuse of key with a different request body is rejected with 422 Unprocessable Entity.
+// Responses with 5xx status are not stored.
+// Requests of anonymous clients are not idempotent unless Config.AllowAnonymous is set.
+//
+// Only headers set by the handler and its inner middlewares are stored and replayed.
+//
+// Requests in progress are tracked in memory of a single instance, concurrent requests with
+// the same key that are served by different instances of a service may both be executed.
+//
+// Middleware is a handler wrapper, it should be applied after authentication middleware
+// to have access to principal.
+func Middleware(cfg Config) func(http.Handler) http.Handler {
+	if cfg.Principal == nil {
+		cfg.Principal = auth.PrincipalID
+	}
+
+	if cfg.MaxBodySize == 0 {
+		cfg.MaxBodySize = 1 << 20
+	}
+
+	if cfg.MaxRequestSize == 0 {
+		cfg.MaxRequestSize = 1 << 20
+	}
+
+	if cfg.Logger == nil {
+		cfg.Logger = ctxd.NoOpLogger{}
+	}
+
+	inFlight := newLocks()
+
+	return func(handler http.Handler) http.Handler {
+		if nethttp.IsWrapperChecker(handler) {
+			return handler
+		}
+
+		var withRoute rest.HandlerWithRoute
+
+		if nethttp.HandlerAs(handler, &withRoute) {
+			if isSafe(withRoute.RouteMethod()) {
+				return handler
+			}
+
+			if cfg.Collector != nil {
+				cfg.Collector.AnnotateOperation(withRoute.RouteMethod(), withRoute.RoutePattern(), documentHeader)
+			}
+		}
+
+		return http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+			key := r.Header.Get(Header)
+			if key == "" || isSafe(r.Method) {
+				handler.ServeHTTP(rw, r)
+
+				return
+			}
+
+			principal := cfg.Principal(r)
+			if principal == "" && !cfg.AllowAnonymous {
+				handler.ServeHTTP(rw, r)
+
+				return
+			}
+
+			ctx := r.Context()
+
+			body, err := io.ReadAll(http.MaxBytesReader(rw, r.Body, cfg.MaxRequestSize))
+			if err != nil {
+				var mbe *http.MaxBytesError
+				if errors.As(err, &mbe) {
+					err = rest.HTTPCodeAsError(http.StatusRequestEntityTooLarge)
+				} else {
+					err = status.Wrap(err, status.InvalidArgument)
+				}
+
+				writeError(ctx, cfg.Logger, rw, err)
+
+				return
+			}
+
+			r.Body = io.NopCloser(bytes.NewReader(body))
+			h := sha256.Sum256(body)
+			requestHash := hex.EncodeToString(h[:])
+
+			cacheKey := principal + "\x00" + r.Method + " " + r.URL.Path + "\x00" + key
+
+			if !inFlight.lock(cacheKey) {
+				writeError(ctx, cfg.Logger, rw, status.Wrap(errInFlight, status.Aborted))
+
+				return
+			}
+			defer inFlight.unlock(cacheKey)
+
+			executed := false
+
+			resp, err := cfg.Cache.Get(ctx, []byte(cacheKey), func(_ context.Context) (Response, error) {
+				executed = true
+
+				return serve(handler, rw, r, cfg.MaxBodySize, requestHash)
+			})
+
+			if executed {
+				if err != nil && !errors.Is(err, errNotStored) {
+					cfg.Logger.Error(ctx, "failed to store idempotent response", "error", err)
+				}
+
+				return
+			}
+
+			if err != nil {
+				cfg.Logger.Error(ctx, "failed to get idempotent response", "error", err)
+				writeError(ctx, cfg.Logger, rw, err)
+
+				return
+			}
+
+			if resp.RequestHash != requestHash {
+				writeError(ctx, cfg.Logger, rw, rest.HTTPCodeAsError(http.StatusUnprocessableEntity))
+
+				return
+			}
+
+			if resp.TooLarge {
+				writeError(ctx, cfg.Logger, rw, status.Wrap(errTooLarge, status.Aborted))
+
+				return
+			}
+
+			replay(ctx, cfg.Logger, rw, resp)
+		})
+	}
+}
+
+func isSafe(method string) bool {
+	return method == http.MethodGet || method == http.MethodHead || method == http.MethodOptions || method == http.MethodTrace
+}
+
+func documentHeader(oc oapi.OperationContext) error {
+	oc.AddReqStructure(idempotencyHeader{})
+	oc.AddRespStructure(rest.ErrResponse{}, func(cu *oapi.ContentUnit) {
+		cu.HTTPStatus = http.StatusConflict
+		cu.Description = "Request with the same idempotency key is in progress or its response is too large to replay."
+	})
+
+	return nil
+}
+
+// serve invokes handler and captures response.
+func serve(handler http.Handler, rw http.ResponseWriter, r *http.Request, maxBodySize int, requestHash string) (Response, error) {
+	cw := &captureWriter{ResponseWriter: rw, maxBodySize: maxBodySize, outer: rw.Header().Clone()}
+
+	handler.ServeHTTP(cw, r)
+
+	if cw.status == 0 {
+		cw.status = http.StatusOK
+		cw.header = handlerHeader(rw.Header(), cw.outer)
+	}
+
+	if cw.status >= http.StatusInternalServerError {
+		return Response{}, errNotStored
+	}
+
+	if cw.overflow {
+		return Response{Status: cw.status, RequestHash: requestHash, TooLarge: true}, nil
+	}
+
+	return Response{
+		Status:      cw.status,
+		Header:      cw.header,
+		Body:        cw.body,
+		RequestHash: requestHash,
+	}, nil
+}
+
+func replay(ctx context.Context, logger ctxd.Logger, rw http.ResponseWriter, resp Response) {
+	h := rw.Header()
+
+	for k, v := range resp.Header {
+		h[k] = v
+	}
+
+	h.Set(ReplayedHeader, "true")
+	rw.WriteHeader(resp.Status)
+
+	if _, err := rw.Write(resp.Body); err != nil {
+		logger.Error(ctx, "failed to write replayed response", "error", err)
+	}
+}
+
+func writeError(ctx context.Context, logger ctxd.Logger, rw http.ResponseWriter, err error) {
+	code, resp := rest.Err(err)
+
+	j, err := json.Marshal(resp)
+	if err != nil {
+		logger.Error(ctx, "failed to marshal error response", "error", err)
+
+		return
+	}
+
+	rw.Header().Set("Content-Type", "application/json; charset=utf-8")
+	rw.WriteHeader(code)
+
+	if _, err := rw.Write(append(j, '\n')); err != nil {
+		logger.Error(ctx, "failed to write error response", "error", err)
+	}
+}
+
+type captureWriter struct {
+	http.ResponseWriter
+
+	maxBodySize int
+	outer       http.Header
+	status      int
+	header      http.Header
+	body        []byte
+	overflow    bool
+}
+
+func (w *captureWriter) WriteHeader(status int) {
+	if w.status == 0 {
+		w.status = status
+		w.header = handlerHeader(w.Header(), w.outer)
+	}
+
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *captureWriter) Write(p []byte) (int, error) {
+	if w.status == 0 {
+		w.WriteHeader(http.StatusOK)
+	}
+
+	if !w.overflow {
+		if len(w.body)+len(p) > w.maxBodySize {
+			w.overflow = true
+			w.body = nil
+		} else {
+			w.body = append(w.body, p...)
+		}
+	}
+
+	return w.ResponseWriter.Write(p)
+}
+
+// handlerHeader returns headers that are added or changed after outer middlewares.
+func handlerHeader(h, outer http.Header) http.Header {
+	res := make(http.Header, len(h))
+
+	for k, v := range h {
+		if ov, ok := outer[k]; ok && slices.Equal(ov, v) {
+			continue
+		}
+
+		res[k] = v
+	}
+
+	return res
+}
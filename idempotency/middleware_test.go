@@ -0,0 +1,221 @@
+package idempotency_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/bool64/brick"
+	"github.com/bool64/brick/idempotency"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/swaggest/openapi-go/openapi3"
+	"github.com/swaggest/rest/web"
+	"github.com/swaggest/usecase"
+	"github.com/swaggest/usecase/status"
+)
+
+func TestMiddleware(t *testing.T) {
+	l := brick.NoOpLocator()
+	r := web.NewService(openapi3.NewReflector())
+
+	r.Wrap(idempotency.Middleware(idempotency.Config{
+		Cache:     brick.MakeCacheOf[idempotency.Response](l, "idempotency", time.Hour, idempotency.CacheConfig),
+		Collector: r.OpenAPICollector,
+		Principal: func(r *http.Request) string { return r.Header.Get("X-Client") },
+	}))
+
+	type order struct {
+		Item string `json:"item"`
+	}
+
+	var (
+		mu      sync.Mutex
+		calls   int
+		started = make(chan struct{})
+		release = make(chan struct{})
+	)
+
+	r.Post("/orders", usecase.NewInteractor(func(_ context.Context, in order, out *order) error {
+		mu.Lock()
+		calls++
+		mu.Unlock()
+
+		switch in.Item {
+		case "slow":
+			close(started)
+			<-release
+		case "fail":
+			return status.Internal
+		}
+
+		*out = in
+
+		return nil
+	}))
+
+	do := func(key, body string) *httptest.ResponseRecorder {
+		rw := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, "/orders", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("X-Client", "alice")
+		req.Header.Set(idempotency.Header, key)
+		r.ServeHTTP(rw, req)
+
+		return rw
+	}
+
+	rw := do("k1", `{"item":"book"}`)
+	assert.Equal(t, http.StatusOK, rw.Code)
+	assert.Empty(t, rw.Header().Get(idempotency.ReplayedHeader))
+
+	rw = do("k1", `{"item":"book"}`)
+	assert.Equal(t, http.StatusOK, rw.Code)
+	assert.Equal(t, `{"item":"book"}`+"\n", rw.Body.String())
+	assert.Equal(t, "true", rw.Header().Get(idempotency.ReplayedHeader))
+	assert.Equal(t, 1, calls)
+
+	rw = do("k1", `{"item":"pen"}`)
+	assert.Equal(t, http.StatusUnprocessableEntity, rw.Code)
+
+	rw = do("k2", `{"item":"fail"}`)
+	assert.Equal(t, http.StatusInternalServerError, rw.Code)
+	rw = do("k2", `{"item":"fail"}`)
+	assert.Equal(t, http.StatusInternalServerError, rw.Code)
+	assert.Equal(t, 3, calls)
+
+	done := make(chan struct{})
+
+	go func() {
+		defer close(done)
+
+		assert.Equal(t, http.StatusOK, do("k3", `{"item":"slow"}`).Code)
+	}()
+
+	<-started
+
+	rw = do("k3", `{"item":"slow"}`)
+	assert.Equal(t, http.StatusConflict, rw.Code)
+
+	close(release)
+	<-done
+
+	j, err := json.Marshal(r.OpenAPISchema())
+	require.NoError(t, err)
+	assert.Contains(t, string(j), `"name":"Idempotency-Key","in":"header"`)
+	assert.Contains(t, string(j), `"409":{"description":"Request with the same idempotency key is in progress or its response is too large to replay."`)
+}
+
+func TestMiddleware_anonymous(t *testing.T) {
+	for _, allow := range []bool{false, true} {
+		l := brick.NoOpLocator()
+		calls := 0
+
+		h := idempotency.Middleware(idempotency.Config{
+			Cache:          brick.MakeCacheOf[idempotency.Response](l, "idempotency", time.Hour, idempotency.CacheConfig),
+			AllowAnonymous: allow,
+			MaxRequestSize: 10,
+		})(http.HandlerFunc(func(_ http.ResponseWriter, _ *http.Request) {
+			calls++
+		}))
+
+		do := func(body string) *httptest.ResponseRecorder {
+			rw := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodPost, "/orders", strings.NewReader(body))
+			req.Header.Set(idempotency.Header, "k1")
+			h.ServeHTTP(rw, req)
+
+			return rw
+		}
+
+		assert.Equal(t, http.StatusOK, do("book").Code)
+		assert.Equal(t, http.StatusOK, do("book").Code)
+
+		if !allow {
+			assert.Equal(t, 2, calls)
+			assert.Equal(t, http.StatusOK, do("very long request body").Code)
+
+			continue
+		}
+
+		assert.Equal(t, 1, calls)
+		assert.Equal(t, http.StatusRequestEntityTooLarge, do("very long request body").Code)
+	}
+}
+
+func TestMiddleware_tooLarge(t *testing.T) {
+	l := brick.NoOpLocator()
+	calls := 0
+
+	h := idempotency.Middleware(idempotency.Config{
+		Cache:          brick.MakeCacheOf[idempotency.Response](l, "idempotency", time.Hour, idempotency.CacheConfig),
+		AllowAnonymous: true,
+		MaxBodySize:    10,
+	})(http.HandlerFunc(func(rw http.ResponseWriter, _ *http.Request) {
+		calls++
+
+		_, err := rw.Write([]byte("very long response body"))
+		assert.NoError(t, err)
+	}))
+
+	do := func() *httptest.ResponseRecorder {
+		rw := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, "/orders", strings.NewReader("book"))
+		req.Header.Set(idempotency.Header, "k1")
+		h.ServeHTTP(rw, req)
+
+		return rw
+	}
+
+	rw := do()
+	assert.Equal(t, http.StatusOK, rw.Code)
+	assert.Equal(t, "very long response body", rw.Body.String())
+
+	rw = do()
+	assert.Equal(t, http.StatusConflict, rw.Code)
+	assert.Equal(t, `{"status":"ABORTED","error":"aborted: response of request with the same idempotency key is too large to replay"}`+"\n", rw.Body.String())
+	assert.Equal(t, 1, calls)
+}
+
+func TestMiddleware_handlerHeader(t *testing.T) {
+	l := brick.NoOpLocator()
+	calls := 0
+
+	h := idempotency.Middleware(idempotency.Config{
+		Cache:          brick.MakeCacheOf[idempotency.Response](l, "idempotency", time.Hour, idempotency.CacheConfig),
+		AllowAnonymous: true,
+	})(http.HandlerFunc(func(rw http.ResponseWriter, _ *http.Request) {
+		calls++
+
+		rw.Header().Set("X-Handler", "foo")
+		rw.WriteHeader(http.StatusCreated)
+	}))
+
+	do := func() *httptest.ResponseRecorder {
+		rw := httptest.NewRecorder()
+		rw.Header().Set("X-Outer", strconv.Itoa(calls))
+
+		req := httptest.NewRequest(http.MethodPost, "/orders", strings.NewReader("book"))
+		req.Header.Set(idempotency.Header, "k1")
+		h.ServeHTTP(rw, req)
+
+		return rw
+	}
+
+	rw := do()
+	assert.Equal(t, http.StatusCreated, rw.Code)
+	assert.Equal(t, "0", rw.Header().Get("X-Outer"))
+
+	rw = do()
+	assert.Equal(t, http.StatusCreated, rw.Code)
+	assert.Equal(t, "foo", rw.Header().Get("X-Handler"))
+	assert.Equal(t, "1", rw.Header().Get("X-Outer"))
+	assert.Equal(t, "true", rw.Header().Get(idempotency.ReplayedHeader))
+	assert.Equal(t, 1, calls)
+}
@@ -1,13 +1,25 @@
 package brick
 
 import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"os"
+	"strings"
 	"time"
 
 	ocprom "contrib.go.opencensus.io/exporter/prometheus"
 	"contrib.go.opencensus.io/integrations/ocsql"
+	"github.com/bool64/brick/compression"
+	"github.com/bool64/brick/debug/traceview"
 	"github.com/bool64/brick/graceful"
 	"github.com/bool64/brick/log"
+	"github.com/bool64/brick/logship"
+	"github.com/bool64/brick/metrics"
 	"github.com/bool64/brick/opencensus"
+	"github.com/bool64/brick/report"
+	"github.com/bool64/brick/tracing"
 	ucase "github.com/bool64/brick/usecase"
 	"github.com/bool64/cache"
 	"github.com/bool64/ctxd"
@@ -26,6 +38,11 @@ import (
 	"github.com/swaggest/usecase"
 	"go.opencensus.io/stats/view"
 	"go.opencensus.io/trace"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.uber.org/zap"
 )
 
 // NoOpLocator creates a dummy service locator, suitable to docs rendering.
@@ -53,6 +70,8 @@ func NoOpLocator() *BaseLocator {
 
 	bl.LoggerProvider = ctxd.NoOpLogger{}
 	bl.TrackerProvider = stats.NoOp{}
+	bl.Tracer = tracing.OpenCensus{}
+	bl.HTTPClient = &http.Client{Transport: &tracing.Transport{}}
 	bl.cacheInvalidationIndex = cache.NewInvalidationIndex()
 
 	return bl
@@ -68,23 +87,102 @@ func NewBaseLocator(cfg BaseConfig) (*BaseLocator, error) {
 		return l, nil
 	}
 
+	if err := cfg.CORS.Validate(); err != nil {
+		return l, err
+	}
+
+	if err := cfg.SecurityHeaders.Validate(); err != nil {
+		return l, err
+	}
+
 	l.Switch = graceful.NewSwitch(cfg.ShutdownTimeout)
 
-	l.LoggerProvider = ctxz.NewObserver(zapctxd.New(cfg.Log).SkipCaller(), logz.Config{
+	if cfg.LogShipping.Enabled() {
+		s, err := logship.New(cfg.LogShipping)
+		if err != nil {
+			return l, err
+		}
+
+		out := cfg.Log.Output
+		if out == nil {
+			out = os.Stdout
+		}
+
+		cfg.Log.Output = io.MultiWriter(out, s)
+		l.LogShippers = s
+	}
+
+	// Caller skip accounts for sampling, level control and debug buffer wrappers.
+	callerSkip := 2
+	if cfg.DebugBuffer.Enabled {
+		callerSkip++
+	}
+
+	zl := zapctxd.New(cfg.Log, zap.AddCallerSkip(callerSkip))
+	l.LogLevel = log.NewLevelControl(cfg.Log.Level)
+	zl.SetLevelEnabler(l.LogLevel)
+
+	var next ctxd.Logger = zl.SkipCaller()
+	if cfg.DebugBuffer.Enabled {
+		next = log.NewBufferedLogger(cfg.DebugBuffer, l.LogLevel, next)
+	}
+
+	// Level control is applied before sampling to elevate messages of packages with overridden level.
+	sl := log.NewSampledLogger(cfg.LogSampling, l.LogLevel, next)
+
+	// Observer receives all messages before sampling to keep accurate counts.
+	l.LoggerProvider = ctxz.NewObserver(l.LogLevel.Wrap(sl), logz.Config{
 		MaxCardinality: 100,
 		MaxSamples:     50,
 	})
+	l.LogLevel.Logger = l.CtxdLogger()
+
+	if err := setupPrometheus(l); err != nil {
+		return l, err
+	}
+
+	if err := setupTracing(l); err != nil {
+		return l, err
+	}
 
 	l.UseCaseMiddlewares = []usecase.Middleware{
-		opencensus.UseCaseMiddleware{},
-		ucase.StatsMiddleware(l.StatsTracker()),
-		log.UsecaseErrors(l.CtxdLogger()),
+		tracing.HTTPErrorMiddleware{}, // Tracing of request decoding errors.
+		tracing.UseCaseMiddleware{Tracer: l.Tracer},
+		ucase.StatsMiddleware(l.StatsTracker(), func(o *ucase.StatsOptions) {
+			o.Buckets = cfg.UseCaseDurationBuckets
+		}),
+		log.UsecaseErrors(l.CtxdLogger(), cfg.UsecaseErrorLevels),
 	}
 
-	if cfg.Debug.TraceSamplingProbability > 0 {
-		trace.ApplyConfig(trace.Config{
-			DefaultSampler: trace.ProbabilitySampler(cfg.Debug.TraceSamplingProbability),
-		})
+	head := l.TraceSampler.OpenCensus()
+
+	switch {
+	case cfg.TraceSampling.NeedsTail():
+		l.OnShutdown("disable_tail_sampling", opencensus.EnablePolicySampling(l.TraceSampler, head, cfg.TraceSampling.TailOptions))
+	case cfg.SlowRequests.ForceSample && cfg.SlowRequests.Enabled():
+		l.OnShutdown("disable_tail_sampling", opencensus.EnableTailSampling(head, cfg.TraceSampling.TailOptions))
+	default:
+		trace.ApplyConfig(trace.Config{DefaultSampler: head})
+	}
+
+	onPanic := cfg.Debug.OnPanic
+
+	if cfg.ErrorReporting.Enabled() {
+		if err := setupErrorReporting(l); err != nil {
+			return l, err
+		}
+
+		onPanic = append(onPanic, l.ErrorReporter.OnPanic)
+		l.UseCaseMiddlewares = append(l.UseCaseMiddlewares, l.ErrorReporter.UseCaseMiddleware())
+	}
+
+	l.Recoverer = log.Recoverer{
+		Logger:  l.CtxdLogger(),
+		Stats:   l.StatsTracker(),
+		OnPanic: onPanic,
+	}
+	l.Switch.PanicHandler = func(task string, rcv interface{}, stack []byte) {
+		l.Recoverer.Handle(context.Background(), "shutdown task", task, rcv, stack)
 	}
 
 	l.HTTPRecoveryMiddleware = log.HTTPRecover{ // Panic recovery and request logging.
@@ -92,6 +190,12 @@ func NewBaseLocator(cfg BaseConfig) (*BaseLocator, error) {
 		FieldNames:  l.BaseConfig.Log.FieldNames,
 		PrintPanic:  cfg.Log.DevMode,
 		ExposePanic: cfg.Debug.ExposePanic,
+		OnPanic:     onPanic,
+		AccessLog:   cfg.AccessLog,
+
+		SlowRequests: cfg.SlowRequests,
+		Stats:        l.StatsTracker(),
+		BodyLog:      cfg.BodyLog,
 	}.Middleware()
 
 	l.HTTPServiceOptions = append(l.HTTPServiceOptions, func(s *web.Service) {
@@ -99,7 +203,8 @@ func NewBaseLocator(cfg BaseConfig) (*BaseLocator, error) {
 	})
 
 	l.HTTPServerMiddlewares = append(l.HTTPServerMiddlewares,
-		opencensus.Middleware, // Tracing.
+		l.Tracer.HTTPMiddleware,                               // Tracing.
+		cfg.DebugRequests.Middleware(l.CtxdLogger()),          // Per-request debug logging.
 		log.HTTPTraceTransaction(l.BaseConfig.Log.FieldNames), // Trace transaction.
 		nethttp.UseCaseMiddlewares(l.UseCaseMiddlewares...),   // Use case middlewares.
 	)
@@ -110,13 +215,150 @@ func NewBaseLocator(cfg BaseConfig) (*BaseLocator, error) {
 
 	l.cacheInvalidationIndex = cache.NewInvalidationIndex()
 
-	if err := setupPrometheus(l); err != nil {
-		return l, err
+	if cfg.Compression.Enabled {
+		mw, err := compression.Middleware(cfg.Compression, l.StatsTracker())
+		if err != nil {
+			return l, err
+		}
+
+		l.HTTPCompressionMiddleware = mw
 	}
 
+	// Logs are delivered after shutdown tasks and error reporting to include their messages.
+	l.AfterShutdown("close_logs", func() {
+		sl.Flush(context.Background())
+		ctx, cancel := context.WithTimeout(context.Background(), cfg.ShutdownTimeout)
+		defer cancel()
+
+		_ = l.LogShippers.Close(ctx) //nolint:errcheck // Logs can not be delivered on shutdown.
+	})
+
 	return l, nil
 }
 
+var (
+	errSlowForceSample = errors.New("force sampling of slow requests is only supported with opencensus tracing backend")
+	errTailSampling    = errors.New("use case, error and slow trace sampling rules are only supported with opencensus tracing backend")
+)
+
+func setupTracing(l *BaseLocator) error {
+	cfg := l.BaseConfig
+
+	if err := cfg.Tracing.Validate(); err != nil {
+		return err
+	}
+
+	p, err := tracing.NewPropagator(cfg.Tracing.Propagation)
+	if err != nil {
+		return err
+	}
+
+	l.TracePropagator = p
+
+	cfg.TraceSampling.Probability = cfg.Debug.TraceSamplingProbability
+
+	s, err := tracing.NewSampler(cfg.TraceSampling)
+	if err != nil {
+		return err
+	}
+
+	s.Logger = l.CtxdLogger()
+	l.TraceSampler = s
+	l.Tracer = tracing.OpenCensus{Propagation: p.OpenCensus()}
+
+	if cfg.Tracing.Backend == tracing.OpenTelemetryBackend {
+		if cfg.SlowRequests.ForceSample && cfg.SlowRequests.Enabled() {
+			return errSlowForceSample
+		}
+
+		if cfg.TraceSampling.NeedsTail() {
+			return errTailSampling
+		}
+
+		tp := sdktrace.NewTracerProvider(
+			sdktrace.WithSampler(s.OpenTelemetry()),
+			sdktrace.WithResource(resource.NewSchemaless(
+				attribute.String("service.name", cfg.ServiceName),
+				attribute.String("service.version", version.Info().Version),
+				attribute.String("deployment.environment", cfg.Environment),
+			)),
+		)
+
+		otel.SetTracerProvider(tp)
+		otel.SetTextMapPropagator(p)
+
+		tr := tracing.NewOpenTelemetry(tp)
+		tr.Propagator = p
+
+		l.OTelTracerProvider = tp
+		l.Tracer = tr
+
+		if cfg.HTTPMetrics.OpenCensusViews {
+			// Views are otherwise recorded by HTTP middleware of OpenCensus tracer.
+			l.HTTPServerMiddlewares = append(l.HTTPServerMiddlewares, opencensus.ViewsMiddleware)
+		}
+
+		l.OnShutdown("shutdown_otel_tracing", func() {
+			_ = tp.Shutdown(context.Background()) //nolint:errcheck // Spans can not be delivered on shutdown.
+		})
+	}
+
+	tracing.SetDefault(l.Tracer)
+
+	if cfg.Debug.DevTools && cfg.Debug.TraceViewSpans > 0 {
+		setupTraceView(l)
+	}
+
+	l.HTTPClient = &http.Client{Transport: &tracing.Transport{Tracer: l.Tracer, Propagator: p}}
+
+	return nil
+}
+
+func setupTraceView(l *BaseLocator) {
+	cfg := l.BaseConfig
+
+	l.SpanStore = traceview.NewStore(cfg.Debug.TraceViewSpans, 10)
+	l.SpanStore.ServiceName = cfg.ServiceName
+
+	if cfg.Debug.TraceURL != "" {
+		l.SpanStore.TraceURL = func(traceID string) string {
+			return strings.ReplaceAll(cfg.Debug.TraceURL, "{trace_id}", traceID)
+		}
+	}
+
+	if l.OTelTracerProvider != nil {
+		l.OTelTracerProvider.RegisterSpanProcessor(l.SpanStore)
+
+		return
+	}
+
+	opencensus.RegisterExporter(l.SpanStore)
+	l.OnShutdown("unregister_trace_view", func() {
+		opencensus.UnregisterExporter(l.SpanStore)
+	})
+}
+
+func setupErrorReporting(l *BaseLocator) error {
+	r, err := report.NewReporter(l.BaseConfig.ErrorReporting)
+	if err != nil {
+		return err
+	}
+
+	r.Logger = l.CtxdLogger()
+	r.FieldNames = l.BaseConfig.Log.FieldNames
+	r.Environment = l.BaseConfig.Environment
+	r.TraceID = log.RequestTraceID
+
+	// Reports are delivered after shutdown tasks to include their errors.
+	l.AfterShutdown("close_error_reporting", func() {
+		_ = r.Close() //nolint:errcheck // Events can not be reported on shutdown.
+	})
+
+	l.ErrorReporter = r
+
+	return nil
+}
+
 func setupPrometheus(l *BaseLocator) error {
 	promReg := prometheus.NewRegistry()
 
@@ -128,11 +370,12 @@ func setupPrometheus(l *BaseLocator) error {
 		return err
 	}
 
-	if err := view.Register(opencensus.Views()...); err != nil {
-		return err
+	views := ocsql.DefaultViews
+	if l.BaseConfig.HTTPMetrics.OpenCensusViews {
+		views = append(opencensus.Views(), views...)
 	}
 
-	if err := view.Register(ocsql.DefaultViews...); err != nil {
+	if err := view.Register(views...); err != nil {
 		return err
 	}
 
@@ -147,8 +390,7 @@ func setupPrometheus(l *BaseLocator) error {
 	view.RegisterExporter(promExporter)
 
 	l.OnShutdown("unregister_oc_prom", func() {
-		view.Unregister(opencensus.Views()...)
-		view.Unregister(ocsql.DefaultViews...)
+		view.Unregister(views...)
 		view.UnregisterExporter(promExporter)
 	})
 
@@ -161,5 +403,14 @@ func setupPrometheus(l *BaseLocator) error {
 
 	l.TrackerProvider = pt
 
+	if l.BaseConfig.HTTPMetrics.Enabled {
+		mw, err := metrics.Middleware(l.BaseConfig.HTTPMetrics, promReg)
+		if err != nil {
+			return err
+		}
+
+		l.HTTPMetricsMiddleware = mw
+	}
+
 	return nil
 }
@@ -2,21 +2,26 @@ package brick_test
 
 import (
 	"bytes"
+	"compress/gzip"
 	"context"
+	"io"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/bool64/brick"
 	"github.com/bool64/brick/config"
+	"github.com/bool64/brick/tracing"
 	"github.com/go-chi/chi/v5"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"github.com/swaggest/assertjson"
 	"github.com/swaggest/rest/nethttp"
 	"github.com/swaggest/usecase"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
 	"go.uber.org/zap"
 )
 
@@ -35,6 +40,8 @@ func TestNewBaseLocator(t *testing.T) {
 
 	cfg := brick.BaseConfig{}
 	require.NoError(t, config.Load("TEST", &cfg))
+	assert.True(t, cfg.HTTPMetrics.OpenCensusViews)
+	assert.False(t, cfg.Compression.Enabled)
 
 	log := bytes.NewBuffer(nil)
 
@@ -71,6 +78,7 @@ func TestNewBaseLocator(t *testing.T) {
 
 	assert.Equal(t, http.StatusInternalServerError, rw.Code)
 	assert.Equal(t, "application/json; charset=utf-8", rw.Header().Get("Content-Type"))
+	assert.Equal(t, "nosniff", rw.Header().Get("X-Content-Type-Options"))
 	assert.Equal(t, `{"error":"request panicked"}`+"\n", rw.Body.String())
 
 	logs := "[" + strings.ReplaceAll(log.String(), "\n", ",\n") + "{}]"
@@ -81,3 +89,125 @@ func TestNewBaseLocator(t *testing.T) {
 
 	assert.NoError(t, <-l.Wait())
 }
+
+func TestNewBaseLocator_forceSampleOpenTelemetry(t *testing.T) {
+	cfg := brick.BaseConfig{}
+	require.NoError(t, config.Load("TEST", &cfg))
+
+	cfg.ServiceName = "test"
+	cfg.Tracing.Backend = tracing.OpenTelemetryBackend
+	cfg.SlowRequests.Threshold = time.Second
+	cfg.SlowRequests.ForceSample = true
+
+	_, err := brick.NewBaseLocator(cfg)
+	require.EqualError(t, err, "force sampling of slow requests is only supported with opencensus tracing backend")
+}
+
+func TestNewBaseLocator_tailSamplingOpenTelemetry(t *testing.T) {
+	cfg := brick.BaseConfig{}
+	require.NoError(t, config.Load("TEST", &cfg))
+
+	cfg.ServiceName = "test"
+	cfg.Tracing.Backend = tracing.OpenTelemetryBackend
+	cfg.TraceSampling.Errors = true
+
+	_, err := brick.NewBaseLocator(cfg)
+	require.EqualError(t, err, "use case, error and slow trace sampling rules are only supported with opencensus tracing backend")
+}
+
+func TestNewBaseWebService_validationTrace(t *testing.T) {
+	cfg := brick.BaseConfig{}
+	require.NoError(t, config.Load("TEST", &cfg))
+
+	cfg.ServiceName = "test"
+	cfg.Log.Output = io.Discard
+	cfg.Tracing.Backend = tracing.OpenTelemetryBackend
+	cfg.Debug.TraceSamplingProbability = 1.0
+
+	l, err := brick.NewBaseLocator(cfg)
+	require.NoError(t, err)
+
+	defer tracing.SetDefault(tracing.OpenCensus{})
+
+	sr := tracetest.NewSpanRecorder()
+	l.OTelTracerProvider.RegisterSpanProcessor(sr)
+
+	type createItem struct {
+		Count int `json:"count" minimum:"1"`
+	}
+
+	u := usecase.NewInteractor(func(_ context.Context, _ createItem, _ *struct{}) error {
+		return nil
+	})
+	u.SetName("createItem")
+
+	r := brick.NewBaseWebService(l)
+	r.Post("/items", u)
+
+	req := httptest.NewRequest(http.MethodPost, "/items", strings.NewReader(`{"count":0}`))
+	req.Header.Set("Content-Type", "application/json")
+
+	rw := httptest.NewRecorder()
+	r.ServeHTTP(rw, req)
+	assert.Equal(t, http.StatusBadRequest, rw.Code)
+
+	var events []string
+
+	for _, s := range sr.Ended() {
+		for _, e := range s.Events() {
+			if e.Name == "validation failed" {
+				events = append(events, s.Name()+": "+e.Attributes[0].Value.AsString())
+			}
+		}
+	}
+
+	assert.Equal(t, []string{"createItem: #/count: must be >= 1/1 but found 0"}, events)
+}
+
+func TestNewBaseWebService_compression(t *testing.T) {
+	cfg := brick.BaseConfig{}
+	require.NoError(t, config.Load("TEST", &cfg))
+
+	log := bytes.NewBuffer(nil)
+
+	cfg.ServiceName = "test"
+	cfg.Log.Level = zap.InfoLevel
+	cfg.Log.Output = log
+	cfg.Compression.Enabled = true
+	cfg.Compression.MinSize = 1
+	cfg.BodyLog.Routes = []string{"*"}
+
+	l, err := brick.NewBaseLocator(cfg)
+	require.NoError(t, err)
+
+	type item struct {
+		Name string `json:"name"`
+	}
+
+	u := usecase.NewInteractor(func(_ context.Context, _ struct{}, out *item) error {
+		out.Name = "foo"
+
+		return nil
+	})
+	u.SetName("getItem")
+
+	r := brick.NewBaseWebService(l)
+	r.Get("/item", u)
+
+	req := httptest.NewRequest(http.MethodGet, "/item", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+
+	rw := httptest.NewRecorder()
+	r.ServeHTTP(rw, req)
+	assert.Equal(t, http.StatusOK, rw.Code)
+	assert.Equal(t, "gzip", rw.Header().Get("Content-Encoding"))
+
+	zr, err := gzip.NewReader(rw.Body)
+	require.NoError(t, err)
+
+	body, err := io.ReadAll(zr)
+	require.NoError(t, err)
+	assert.Equal(t, `{"name":"foo"}`+"\n", string(body))
+
+	assert.Contains(t, log.String(), `"http.response.body":{"name":"foo"}`)
+}
@@ -6,8 +6,8 @@ import (
 	"fmt"
 
 	"contrib.go.opencensus.io/exporter/jaeger"
+	"github.com/bool64/brick/opencensus"
 	"github.com/bool64/ctxd"
-	"go.opencensus.io/trace"
 )
 
 type deps interface {
@@ -85,9 +85,9 @@ func Setup(cfg Config, l deps) error {
 		return err
 	}
 
-	trace.RegisterExporter(jaegerExporter)
+	opencensus.RegisterExporter(jaegerExporter)
 	l.OnShutdown("unregister_oc_jaeger", func() {
-		trace.UnregisterExporter(jaegerExporter)
+		opencensus.UnregisterExporter(jaegerExporter)
 	})
 
 	return nil
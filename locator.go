@@ -1,10 +1,18 @@
 package brick
 
 import (
+	"context"
+	"fmt"
 	"net/http"
+	rdebug "runtime/debug"
 
 	"github.com/bool64/brick/debug"
+	"github.com/bool64/brick/debug/traceview"
 	"github.com/bool64/brick/graceful"
+	"github.com/bool64/brick/log"
+	"github.com/bool64/brick/logship"
+	"github.com/bool64/brick/report"
+	"github.com/bool64/brick/tracing"
 	"github.com/bool64/cache"
 	"github.com/bool64/ctxd"
 	"github.com/bool64/sqluct"
@@ -13,6 +21,7 @@ import (
 	"github.com/swaggest/rest/web"
 	"github.com/swaggest/swgui"
 	"github.com/swaggest/usecase"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
 )
 
 // BaseLocator is a basic application agnostic service locator that manages common infrastructure.
@@ -24,6 +33,36 @@ type BaseLocator struct {
 	*graceful.Switch
 	DebugRouter *debug.Mux
 
+	// LogLevel controls log level at runtime, it is nil in NoOpLocator.
+	LogLevel *log.LevelControl
+
+	// LogShippers send logs to configured collectors, it is empty if shipping is disabled.
+	LogShippers logship.Shippers
+
+	// Tracer creates spans with configured backend, it is also set as tracing.Default.
+	Tracer tracing.Tracer
+
+	// OTelTracerProvider manages OpenTelemetry spans, it is nil if backend is not OpenTelemetry.
+	OTelTracerProvider *sdktrace.TracerProvider
+
+	// TraceSampler makes sampling decisions with rules that can be changed at runtime.
+	TraceSampler *tracing.Sampler
+
+	// SpanStore keeps recent sampled spans for trace viewer of dev tools, it is nil if viewer is disabled.
+	SpanStore *traceview.Store
+
+	// TracePropagator extracts and injects span context in HTTP headers with configured formats.
+	TracePropagator *tracing.Propagator
+
+	// HTTPClient sends outgoing requests with client spans and propagated span context.
+	HTTPClient *http.Client
+
+	// ErrorReporter sends panics and internal errors to configured sinks, it is nil if reporting is disabled.
+	ErrorReporter *report.Reporter
+
+	// Recoverer handles panics of use cases, background goroutines and shutdown tasks.
+	Recoverer log.Recoverer
+
 	UseCaseMiddlewares []usecase.Middleware
 
 	// HTTPServiceOptions can be used to configure low-level middlewares like middleware.StripSlashes on an
@@ -34,11 +73,45 @@ type BaseLocator struct {
 	OpenAPI                *openapi.Collector
 	SwaggerUIOptions       []func(cfg *swgui.Config)
 
+	// HTTPMetricsMiddleware collects RED metrics of HTTP requests by route, it is nil if metrics are disabled.
+	HTTPMetricsMiddleware func(h http.Handler) http.Handler
+
+	// HTTPCompressionMiddleware compresses HTTP responses, it is nil if compression is disabled.
+	HTTPCompressionMiddleware func(h http.Handler) http.Handler
+
 	Storage                *sqluct.Storage
 	cacheTransfer          *cache.HTTPTransfer
 	cacheInvalidationIndex *cache.InvalidationIndex
 }
 
+// Go runs function in a new goroutine with panic recovery.
+func (l *BaseLocator) Go(ctx context.Context, name string, fn func(ctx context.Context)) {
+	l.Recoverer.Go(ctx, name, fn)
+}
+
+// GoLinked runs function in a new goroutine with panic recovery and a new trace linked to current span.
+//
+// Context is detached from cancellation of parent context, root span is named by name and is failed on panic.
+func (l *BaseLocator) GoLinked(ctx context.Context, name string, fn func(ctx context.Context)) {
+	link := tracing.LinkFromContext(ctx)
+	ctx = context.WithoutCancel(ctx)
+
+	go func() {
+		ctx, span := tracing.StartLinked(ctx, name, link)
+
+		defer func() {
+			if rcv := recover(); rcv != nil {
+				span.SetError(fmt.Errorf("%w: %v", log.ErrPanicked, rcv))
+				l.Recoverer.Handle(ctx, "goroutine", name, rcv, rdebug.Stack())
+			}
+
+			span.End()
+		}()
+
+		fn(ctx)
+	}()
+}
+
 // CacheTransfer provides a shared instance of cache transfer over HTTP.
 func (l *BaseLocator) CacheTransfer() *cache.HTTPTransfer {
 	return l.cacheTransfer
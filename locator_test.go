@@ -0,0 +1,46 @@
+package brick_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/bool64/brick"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opencensus.io/trace"
+)
+
+type spanRecorder chan *trace.SpanData
+
+func (r spanRecorder) ExportSpan(sd *trace.SpanData) {
+	r <- sd
+}
+
+func TestBaseLocator_GoLinked(t *testing.T) {
+	exp := make(spanRecorder, 1)
+
+	trace.RegisterExporter(exp)
+	defer trace.UnregisterExporter(exp)
+
+	l := brick.NoOpLocator()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	ctx, span := trace.StartSpan(ctx, "request", trace.WithSampler(trace.AlwaysSample()))
+
+	cancel()
+
+	l.GoLinked(ctx, "sendEmail", func(ctx context.Context) {
+		assert.NoError(t, ctx.Err())
+
+		panic("failed")
+	})
+
+	sd := <-exp
+	span.End()
+
+	assert.Equal(t, "sendEmail", sd.Name)
+	assert.Equal(t, "panicked: failed", sd.Message)
+	require.Len(t, sd.Links, 1)
+	assert.Equal(t, span.SpanContext().TraceID, sd.Links[0].TraceID)
+	assert.Equal(t, span.SpanContext().SpanID, sd.Links[0].SpanID)
+}
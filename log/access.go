@@ -0,0 +1,154 @@
+package log
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"math/rand"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"go.opencensus.io/trace"
+)
+
+// Access log modes.
+const (
+	AccessLogOff     = "off"
+	AccessLogAll     = "all"
+	AccessLogSampled = "sampled"
+	AccessLogErrors  = "errors"
+)
+
+// Access log formats.
+const (
+	AccessLogFormatCommon   = "common"
+	AccessLogFormatCombined = "combined"
+)
+
+// AccessLog controls logging of completed HTTP requests.
+type AccessLog struct {
+	// Mode is one of "off" (only DEBUG messages), "all", "sampled" or "errors".
+	//
+	// In "errors" mode requests with 4xx and 5xx statuses and slow requests are logged.
+	Mode string `default:"off" enum:"off,all,sampled,errors"`
+
+	// SampleRate is the share of requests to log in "sampled" mode.
+	SampleRate float64 `split_words:"true" default:"0.01"`
+
+	// SlowThreshold is the latency to log request in "errors" mode.
+	SlowThreshold time.Duration `split_words:"true" default:"1s"`
+
+	// PrincipalField is the name of request field with authenticated principal.
+	PrincipalField string `split_words:"true" default:"user.id"`
+
+	// Format enables Common or Combined Log Format output to Writer in addition to structured log,
+	// "common" or "combined".
+	Format string `enum:",common,combined"`
+
+	// Writer receives Common or Combined Log Format lines, default os.Stdout if Format is set.
+	Writer io.Writer `json:"-" ignored:"true"`
+}
+
+func (a AccessLog) enabled(status int, elapsed time.Duration) bool {
+	switch a.Mode {
+	case AccessLogAll:
+		return true
+	case AccessLogSampled:
+		return rand.Float64() < a.SampleRate //nolint:gosec // Weak randomness is fine for sampling.
+	case AccessLogErrors:
+		return status >= http.StatusBadRequest || (a.SlowThreshold > 0 && elapsed >= a.SlowThreshold)
+	}
+
+	return false
+}
+
+type requestFieldsCtxKey struct{}
+
+type requestFields struct {
+	mu             sync.Mutex
+	keysAndValues  []interface{}
+	traceID        trace.TraceID
+	hasTrace       bool
+	debug          bool
+	buffer         *debugBuffer
+	bufferReleased bool
+}
+
+func (f *requestFields) get(key string) interface{} {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for i := 0; i < len(f.keysAndValues)-1; i += 2 {
+		if f.keysAndValues[i] == key {
+			return f.keysAndValues[i+1]
+		}
+	}
+
+	return nil
+}
+
+func (f *requestFields) all() []interface{} {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return f.keysAndValues[:len(f.keysAndValues):len(f.keysAndValues)]
+}
+
+// AddRequestFields adds fields to access log of current HTTP request.
+//
+// Unlike ctxd.AddFields, it makes fields available to outer middlewares, for example
+// authentication middleware can report principal.
+func AddRequestFields(ctx context.Context, keysAndValues ...interface{}) {
+	f, ok := ctx.Value(requestFieldsCtxKey{}).(*requestFields)
+	if !ok {
+		return
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.keysAndValues = append(f.keysAndValues, keysAndValues...)
+}
+
+func withRequestFields(ctx context.Context) (context.Context, *requestFields) {
+	f := &requestFields{}
+
+	return context.WithValue(ctx, requestFieldsCtxKey{}, f), f
+}
+
+func requestID(r *http.Request) string {
+	if id := middleware.GetReqID(r.Context()); id != "" {
+		return id
+	}
+
+	return r.Header.Get(middleware.RequestIDHeader)
+}
+
+// writeCLF writes a line in Common or Combined Log Format.
+func (a AccessLog) writeCLF(r *http.Request, principal interface{}, start time.Time, status, size int) error {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+
+	user := "-"
+	if principal != nil && principal != "" {
+		user = fmt.Sprintf("%v", principal)
+	}
+
+	line := host + " - " + user + " [" + start.Format("02/Jan/2006:15:04:05 -0700") + "] " +
+		strconv.Quote(r.Method+" "+r.RequestURI+" "+r.Proto) + " " +
+		strconv.Itoa(status) + " " + strconv.Itoa(size)
+
+	if a.Format == AccessLogFormatCombined {
+		line += " " + strconv.Quote(r.Referer()) + " " + strconv.Quote(r.UserAgent())
+	}
+
+	_, err = io.WriteString(a.Writer, line+"\n")
+
+	return err
+}
@@ -0,0 +1,308 @@
+package log
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"math/rand"
+	"mime"
+	"net"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+
+	"github.com/bool64/ctxd"
+)
+
+const redacted = "[redacted]"
+
+// DefaultRedactHeaders lists names of headers with credentials that are redacted in logs by default.
+var DefaultRedactHeaders = []string{"Authorization", "Cookie", "Set-Cookie", "X-Api-Key", "X-Debug-Token", "X-Debug-Body"}
+
+// BodyLog controls logging of HTTP request and response bodies.
+//
+// Bodies are captured for selected routes, for requests with debug header
+// or for sampled requests and logged in "http request payload" message.
+// Encoded (for example compressed) bodies are replaced with a placeholder.
+type BodyLog struct {
+	// Routes enables capture for routes, items are "METHOD /pattern", "/pattern" or "*" for all routes.
+	Routes []string
+
+	// SampleRate is the share of requests to capture.
+	SampleRate float64 `split_words:"true"`
+
+	// DebugHeader is the name of request header to enable capture, header value must match DebugToken.
+	DebugHeader string `split_words:"true" default:"X-Debug-Body"`
+
+	// DebugToken is the value of DebugHeader, header is ignored if token is empty.
+	DebugToken string `split_words:"true"`
+
+	// MaxSize limits number of captured bytes of request and response body.
+	MaxSize int `split_words:"true" default:"4096"`
+
+	// ContentTypes lists media types to capture, wildcards are supported.
+	ContentTypes []string `split_words:"true" default:"application/json,application/x-www-form-urlencoded,text/*"`
+
+	// RedactHeaders lists names of headers to redact in logs, default DefaultRedactHeaders.
+	RedactHeaders []string `split_words:"true"`
+
+	// RedactFields lists JSON paths or form fields to redact in bodies.
+	//
+	// Names without dots match keys at any depth, for example "password".
+	// Dotted paths start at document root, "*" matches any key or array item, for example "cards.*.number".
+	RedactFields []string `split_words:"true" default:"password,token,secret"`
+}
+
+func (b BodyLog) routeEnabled(method, pattern string) bool {
+	for _, r := range b.Routes {
+		if r == "*" || r == pattern || r == method+" "+pattern {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (b BodyLog) requestEnabled(r *http.Request) bool {
+	if b.DebugToken != "" && b.DebugHeader != "" && r.Header.Get(b.DebugHeader) == b.DebugToken {
+		return true
+	}
+
+	return b.SampleRate > 0 && rand.Float64() < b.SampleRate //nolint:gosec // Weak randomness is fine for sampling.
+}
+
+func (b BodyLog) contentTypeEnabled(contentType string) bool {
+	mt, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+
+	for _, ct := range b.ContentTypes {
+		if ok, _ := path.Match(ct, mt); ok {
+			return true
+		}
+	}
+
+	return false
+}
+
+// capturedBody keeps head of a body up to a limit.
+type capturedBody struct {
+	limit     int
+	buf       bytes.Buffer
+	truncated bool
+}
+
+// Write implements io.Writer.
+func (c *capturedBody) Write(p []byte) (int, error) {
+	if left := c.limit - c.buf.Len(); left < len(p) {
+		c.truncated = true
+
+		if left > 0 {
+			c.buf.Write(p[:left])
+		}
+
+		return len(p), nil
+	}
+
+	return c.buf.Write(p)
+}
+
+type captureReader struct {
+	io.ReadCloser
+	c *capturedBody
+}
+
+func (r captureReader) Read(p []byte) (int, error) {
+	n, err := r.ReadCloser.Read(p)
+	_, _ = r.c.Write(p[:n])
+
+	return n, err
+}
+
+// value returns redacted body for logging.
+func (b BodyLog) value(c *capturedBody, contentType, contentEncoding string) interface{} {
+	if c.buf.Len() == 0 {
+		return nil
+	}
+
+	// Encoded (for example compressed) body can not be redacted.
+	if contentEncoding != "" && contentEncoding != "identity" {
+		return "[omitted content encoding " + contentEncoding + "]"
+	}
+
+	if !b.contentTypeEnabled(contentType) {
+		return "[omitted content type " + contentType + "]"
+	}
+
+	mt, _, _ := mime.ParseMediaType(contentType) //nolint:errcheck // Media type is validated by contentTypeEnabled.
+
+	switch {
+	case mt == "application/x-www-form-urlencoded":
+		return b.redactForm(c)
+	case mt == "application/json" || strings.HasSuffix(mt, "+json"):
+		return b.redactJSON(c)
+	}
+
+	s := c.buf.String()
+	if c.truncated {
+		s += "...[truncated]"
+	}
+
+	return s
+}
+
+func (b BodyLog) redactForm(c *capturedBody) interface{} {
+	if c.truncated {
+		return "[truncated form]"
+	}
+
+	q, err := url.ParseQuery(c.buf.String())
+	if err != nil {
+		return "[malformed form]"
+	}
+
+	for k := range q {
+		for _, f := range b.RedactFields {
+			if f == k {
+				q[k] = []string{redacted}
+			}
+		}
+	}
+
+	return q.Encode()
+}
+
+func (b BodyLog) redactJSON(c *capturedBody) interface{} {
+	if c.truncated {
+		// Truncated document can not be parsed for redaction.
+		return "[truncated json]"
+	}
+
+	d := json.NewDecoder(&c.buf)
+	d.UseNumber()
+
+	var v interface{}
+	if err := d.Decode(&v); err != nil {
+		return "[malformed json]"
+	}
+
+	for _, f := range b.RedactFields {
+		if strings.Contains(f, ".") {
+			v = redactPath(v, strings.Split(f, "."))
+		} else {
+			v = redactKey(v, f)
+		}
+	}
+
+	return v
+}
+
+func redactKey(v interface{}, key string) interface{} {
+	switch vv := v.(type) {
+	case map[string]interface{}:
+		for k, item := range vv {
+			if k == key {
+				vv[k] = redacted
+			} else {
+				vv[k] = redactKey(item, key)
+			}
+		}
+	case []interface{}:
+		for i, item := range vv {
+			vv[i] = redactKey(item, key)
+		}
+	}
+
+	return v
+}
+
+func redactPath(v interface{}, p []string) interface{} {
+	if len(p) == 0 {
+		return redacted
+	}
+
+	switch vv := v.(type) {
+	case map[string]interface{}:
+		for k, item := range vv {
+			if p[0] == "*" || p[0] == k {
+				vv[k] = redactPath(item, p[1:])
+			}
+		}
+	case []interface{}:
+		if p[0] == "*" {
+			for i, item := range vv {
+				vv[i] = redactPath(item, p[1:])
+			}
+		}
+	}
+
+	return v
+}
+
+// encodingWriter keeps Content-Encoding of response at the moment handler writes header,
+// outer middlewares (for example compression) may change it later.
+type encodingWriter struct {
+	http.ResponseWriter
+
+	written  bool
+	encoding string
+}
+
+func (w *encodingWriter) WriteHeader(status int) {
+	if !w.written && status >= http.StatusOK {
+		w.written = true
+		w.encoding = w.Header().Get("Content-Encoding")
+	}
+
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *encodingWriter) Write(p []byte) (int, error) {
+	if !w.written {
+		w.WriteHeader(http.StatusOK)
+	}
+
+	return w.ResponseWriter.Write(p)
+}
+
+// Flush implements http.Flusher.
+func (w *encodingWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Hijack implements http.Hijacker.
+func (w *encodingWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	if h, ok := w.ResponseWriter.(http.Hijacker); ok {
+		return h.Hijack()
+	}
+
+	return nil, nil, errors.New("response writer does not implement http.Hijacker")
+}
+
+func headersMap(header http.Header, redact []string) ctxd.DeferredJSON {
+	return func() interface{} {
+		headers := make(map[string]string, len(header))
+
+		for k := range header {
+			v := header.Get(k)
+
+			for _, r := range redact {
+				if strings.EqualFold(r, k) {
+					v = redacted
+
+					break
+				}
+			}
+
+			headers[k] = v
+		}
+
+		return headers
+	}
+}
@@ -0,0 +1,180 @@
+package log
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/bool64/ctxd"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// DebugBuffer controls buffering of DEBUG messages of HTTP requests.
+//
+// Messages that are disabled by log level are kept in memory while request is handled and
+// are logged only if request fails, panics or is slow, so that failure has detailed context.
+// Buffered messages keep context fields, including trace ID.
+type DebugBuffer struct {
+	// Enabled enables buffering.
+	Enabled bool
+
+	// FlushStatus is the minimal HTTP response status to log buffered messages.
+	FlushStatus int `split_words:"true" default:"500"`
+
+	// MaxEntries limits number of buffered messages of a request, newer messages are dropped.
+	MaxEntries int `split_words:"true" default:"100"`
+
+	// MaxTotal limits number of buffered messages of all requests in flight.
+	MaxTotal int `split_words:"true" default:"10000"`
+}
+
+// BufferedLogger buffers DEBUG messages of HTTP requests to be logged by HTTPRecover.
+//
+// Please use NewBufferedLogger to create an instance.
+type BufferedLogger struct {
+	cfg   DebugBuffer
+	level zapcore.LevelEnabler
+	next  ctxd.Logger
+	total atomic.Int64
+}
+
+// NewBufferedLogger creates a logger that buffers DEBUG messages of requests if they are not enabled by level.
+func NewBufferedLogger(cfg DebugBuffer, level zapcore.LevelEnabler, logger ctxd.Logger) *BufferedLogger {
+	if cfg.FlushStatus == 0 {
+		cfg.FlushStatus = 500
+	}
+
+	if cfg.MaxEntries == 0 {
+		cfg.MaxEntries = 100
+	}
+
+	if cfg.MaxTotal == 0 {
+		cfg.MaxTotal = 10000
+	}
+
+	return &BufferedLogger{cfg: cfg, level: level, next: logger}
+}
+
+type bufferedEntry struct {
+	ctx           context.Context //nolint:containedctx // Context fields are logged on flush.
+	msg           string
+	keysAndValues []interface{}
+	time          time.Time
+}
+
+type debugBuffer struct {
+	l *BufferedLogger
+
+	mu      sync.Mutex
+	entries []bufferedEntry
+	dropped int
+}
+
+// Debug implements ctxd.Logger.
+func (l *BufferedLogger) Debug(ctx context.Context, msg string, keysAndValues ...interface{}) {
+	if ctxd.IsDebug(ctx) || l.level.Enabled(zap.DebugLevel) {
+		l.next.Debug(ctx, msg, keysAndValues...)
+
+		return
+	}
+
+	f, ok := ctx.Value(requestFieldsCtxKey{}).(*requestFields)
+	if !ok {
+		return
+	}
+
+	f.mu.Lock()
+
+	// Messages of background tasks may arrive after request is complete.
+	if f.bufferReleased {
+		f.mu.Unlock()
+
+		return
+	}
+
+	if f.buffer == nil {
+		f.buffer = &debugBuffer{l: l}
+	}
+
+	b := f.buffer
+	f.mu.Unlock()
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if len(b.entries) >= l.cfg.MaxEntries {
+		b.dropped++
+
+		return
+	}
+
+	if l.total.Add(1) > int64(l.cfg.MaxTotal) {
+		l.total.Add(-1)
+
+		b.dropped++
+
+		return
+	}
+
+	b.entries = append(b.entries, bufferedEntry{
+		ctx:           ctx,
+		msg:           msg,
+		keysAndValues: keysAndValues,
+		time:          time.Now(),
+	})
+}
+
+// Info implements ctxd.Logger.
+func (l *BufferedLogger) Info(ctx context.Context, msg string, keysAndValues ...interface{}) {
+	l.next.Info(ctx, msg, keysAndValues...)
+}
+
+// Important implements ctxd.Logger.
+func (l *BufferedLogger) Important(ctx context.Context, msg string, keysAndValues ...interface{}) {
+	l.next.Important(ctx, msg, keysAndValues...)
+}
+
+// Warn implements ctxd.Logger.
+func (l *BufferedLogger) Warn(ctx context.Context, msg string, keysAndValues ...interface{}) {
+	l.next.Warn(ctx, msg, keysAndValues...)
+}
+
+// Error implements ctxd.Logger.
+func (l *BufferedLogger) Error(ctx context.Context, msg string, keysAndValues ...interface{}) {
+	l.next.Error(ctx, msg, keysAndValues...)
+}
+
+// releaseDebugBuffer discards buffered messages, or logs them if forced or if response status is not lower than
+// configured FlushStatus.
+func (f *requestFields) releaseDebugBuffer(ctx context.Context, force bool, status int) {
+	f.mu.Lock()
+	b := f.buffer
+	f.buffer, f.bufferReleased = nil, true
+	f.mu.Unlock()
+
+	if b == nil {
+		return
+	}
+
+	b.mu.Lock()
+	entries, dropped := b.entries, b.dropped
+	b.entries = nil
+	b.mu.Unlock()
+
+	b.l.total.Add(-int64(len(entries)))
+
+	if !force && status < b.l.cfg.FlushStatus {
+		return
+	}
+
+	for _, e := range entries {
+		kv := append(e.keysAndValues[:len(e.keysAndValues):len(e.keysAndValues)], "log.buffered_at", e.time)
+		b.l.next.Debug(ctxd.WithDebug(e.ctx), e.msg, kv...)
+	}
+
+	if dropped > 0 {
+		b.l.next.Warn(ctx, "buffered debug messages dropped", "count", dropped)
+	}
+}
@@ -0,0 +1,67 @@
+package log_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/bool64/brick/log"
+	"github.com/bool64/ctxd"
+	"github.com/bool64/zapctxd"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestBufferedLogger(t *testing.T) {
+	out := &syncBuffer{}
+	lc := log.NewLevelControl(zap.InfoLevel)
+
+	zl := zapctxd.New(zapctxd.Config{Output: out, StripTime: true})
+	zl.SetLevelEnabler(lc)
+
+	logger := log.NewBufferedLogger(log.DebugBuffer{MaxEntries: 2}, lc, zl)
+
+	h := log.HTTPRecover{
+		Logger:     logger,
+		FieldNames: fieldNames,
+	}.Middleware()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := ctxd.AddFields(r.Context(), "trace.id", r.URL.Query().Get("id"))
+
+		for i := 0; i < 3; i++ {
+			logger.Debug(ctx, "loading item", "step", i)
+		}
+
+		switch r.URL.Path {
+		case "/panic":
+			panic("failed")
+		case "/error":
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+
+	for _, u := range []string{"/ok?id=1", "/error?id=2", "/panic?id=3"} {
+		h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, u, nil))
+	}
+
+	logs := out.String()
+
+	assert.NotContains(t, logs, `"trace.id":"1"`)
+	assert.Equal(t, 2, strings.Count(logs, `"trace.id":"2"`), logs)
+	assert.Equal(t, 2, strings.Count(logs, `"trace.id":"3"`), logs)
+	assert.Contains(t, logs, `"log.buffered_at":`)
+	assert.Contains(t, logs, `"msg":"buffered debug messages dropped"`)
+	assert.Contains(t, logs, `"msg":"request panicked"`)
+	assert.NotContains(t, logs, `"step":2`)
+
+	// Messages out of request are dropped if DEBUG is not enabled.
+	out.Reset()
+	logger.Debug(context.Background(), "background")
+	assert.Empty(t, out.String())
+
+	require.NoError(t, lc.Set(context.Background(), "", zap.DebugLevel, 0, "test"))
+	logger.Debug(context.Background(), "enabled")
+	assert.Contains(t, out.String(), `"msg":"enabled"`)
+}
@@ -0,0 +1,127 @@
+package log
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bool64/brick/tracing"
+	"github.com/bool64/ctxd"
+)
+
+var (
+	errMalformedDebugToken = errors.New("malformed debug token")
+	errExpiredDebugToken   = errors.New("expired debug token")
+	errDebugTokenSignature = errors.New("invalid debug token signature")
+)
+
+// DebugRequests controls elevation of logging to DEBUG level for individual requests.
+//
+// Elevated request context makes ctxd loggers log all messages, including SQL statements of database package.
+type DebugRequests struct {
+	// Header is the name of request header with a debug token, see SignDebugToken.
+	Header string `default:"X-Debug-Token"`
+
+	// Secret is a key to verify debug token signature, header is ignored if secret is empty.
+	Secret string
+
+	// SampledTraces enables DEBUG level for requests with sampled trace.
+	SampledTraces bool `split_words:"true"`
+}
+
+// SignDebugToken creates a token for DebugRequests header.
+//
+// Subject is logged with elevated request to identify who requested debugging.
+func SignDebugToken(secret, subject string, ttl time.Duration) string {
+	payload := strconv.FormatInt(time.Now().Add(ttl).Unix(), 10) + ":" + subject
+
+	return payload + ":" + debugTokenSignature(secret, payload)
+}
+
+func debugTokenSignature(secret, payload string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	_, _ = mac.Write([]byte(payload))
+
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// verifyDebugToken checks token and returns its subject.
+func verifyDebugToken(secret, token string) (string, error) {
+	i := strings.Index(token, ":")
+	j := strings.LastIndex(token, ":")
+
+	if i <= 0 || j <= i {
+		return "", errMalformedDebugToken
+	}
+
+	payload, sig := token[:j], token[j+1:]
+
+	if !hmac.Equal([]byte(sig), []byte(debugTokenSignature(secret, payload))) {
+		return "", errDebugTokenSignature
+	}
+
+	exp, err := strconv.ParseInt(token[:i], 10, 64)
+	if err != nil {
+		return "", errMalformedDebugToken
+	}
+
+	if time.Now().Unix() > exp {
+		return "", errExpiredDebugToken
+	}
+
+	return token[i+1 : j], nil
+}
+
+// Middleware elevates context of debug requests.
+//
+// It should be used after tracing middleware to have access to trace sampling decision.
+func (d DebugRequests) Middleware(logger ctxd.Logger) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx := r.Context()
+			elevate := false
+
+			if token := r.Header.Get(d.Header); token != "" && d.Secret != "" && d.Header != "" {
+				subject, err := verifyDebugToken(d.Secret, token)
+				if err != nil {
+					// Any client can send invalid token, so it is logged at debug level to avoid log flooding.
+					logger.Debug(ctx, "invalid debug token", "error", err)
+				} else {
+					elevate = true
+					ctx = ctxd.AddFields(ctx, "debug.subject", subject)
+					AddRequestFields(ctx, "debug.subject", subject)
+				}
+			}
+
+			if !elevate && d.SampledTraces && tracing.IsSampled(ctx) {
+				elevate = true
+			}
+
+			if elevate {
+				ctx = ctxd.WithDebug(ctx)
+
+				if f, ok := ctx.Value(requestFieldsCtxKey{}).(*requestFields); ok {
+					f.mu.Lock()
+					f.debug = true
+					f.mu.Unlock()
+				}
+
+				r = r.WithContext(ctx)
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func (f *requestFields) isDebug() bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return f.debug
+}
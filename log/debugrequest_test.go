@@ -0,0 +1,55 @@
+package log_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bool64/brick/log"
+	"github.com/bool64/ctxd"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDebugRequests_Middleware(t *testing.T) {
+	logger := &ctxd.LoggerMock{}
+	dr := log.DebugRequests{Header: "X-Debug-Token", Secret: "s3cr3t"}
+
+	h := log.HTTPRecover{Logger: logger, FieldNames: fieldNames}.Middleware()(
+		dr.Middleware(logger)(http.HandlerFunc(func(_ http.ResponseWriter, r *http.Request) {
+			logger.Debug(r.Context(), "handling")
+		})),
+	)
+
+	for _, token := range []string{
+		log.SignDebugToken("s3cr3t", "jane:ticket-123", time.Minute),
+		log.SignDebugToken("wrong", "jane:ticket-123", time.Minute),
+		log.SignDebugToken("s3cr3t", "jane:ticket-123", -time.Minute),
+		"",
+	} {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set("X-Debug-Token", token)
+		h.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	out := logger.String()
+
+	assert.Contains(t, out, `debug mode, debug: handling {"client.ip":"192.0.2.1:1234","debug.subject":"jane:ticket-123",`)
+	assert.Contains(t, out, `debug mode, debug: http request complete {"client.ip"`)
+	assert.Contains(t, out, `"headers":{"X-Debug-Token":"[redacted]"}`)
+	assert.Equal(t, 2, countLines(out, "debug mode, "))
+	assert.Equal(t, 2, countLines(out, "debug: invalid debug token "))
+}
+
+func countLines(s, prefix string) int {
+	n := 0
+
+	for _, l := range strings.Split(s, "\n") {
+		if strings.HasPrefix(l, prefix) {
+			n++
+		}
+	}
+
+	return n
+}
@@ -4,27 +4,36 @@ import (
 	"context"
 	"encoding/json"
 	"net/http"
+	"os"
 	"runtime/debug"
 	"strings"
+	"sync"
 	"time"
 
+	"github.com/bool64/brick/tracing"
 	"github.com/bool64/ctxd"
+	"github.com/bool64/stats"
+	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
 	"github.com/swaggest/rest"
-	"go.opencensus.io/trace"
+	"github.com/swaggest/rest/nethttp"
 )
 
 // HTTPTraceTransaction adds trace transaction info to request context.
 func HTTPTraceTransaction(fields ctxd.FieldNames) func(h http.Handler) http.Handler {
 	return func(h http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			if span := trace.FromContext(r.Context()); span != nil {
+			if span := tracing.FromContext(r.Context()); span != nil {
 				sc := span.SpanContext()
 				ctx := ctxd.AddFields(r.Context(),
-					fields.TraceID, sc.TraceID.String(),
-					fields.TransactionID, sc.SpanID.String(),
+					fields.TraceID, sc.TraceIDString(),
+					fields.TransactionID, sc.SpanIDString(),
 				)
 				r = r.WithContext(ctx)
+
+				if f, ok := ctx.Value(requestFieldsCtxKey{}).(*requestFields); ok {
+					setRequestTrace(f, sc.TraceID)
+				}
 			}
 
 			h.ServeHTTP(w, r)
@@ -54,6 +63,15 @@ type HTTPRecover struct {
 	PrintPanic  bool
 	ExposePanic bool
 	OnPanic     []func(ctx context.Context, rcv interface{}, stack []byte)
+	AccessLog   AccessLog
+
+	// SlowRequests controls detection of slow requests.
+	SlowRequests SlowRequests
+	// Stats is used to count slow requests.
+	Stats stats.Tracker
+
+	// BodyLog controls logging of request and response bodies.
+	BodyLog BodyLog
 }
 
 func (mw HTTPRecover) handlePanic(ctx context.Context, rvr interface{}, msg string) {
@@ -108,20 +126,74 @@ func (mw HTTPRecover) processPanic(ctx context.Context, rvr interface{}, rw http
 // Middleware wraps http handler.
 func (mw HTTPRecover) Middleware() func(handler http.Handler) http.Handler {
 	logger := mw.Logger
+	clf := &sync.Mutex{}
+
+	if mw.Stats == nil {
+		mw.Stats = stats.NoOp{}
+	}
+
+	if mw.SlowRequests.SnapshotInterval == 0 {
+		mw.SlowRequests.SnapshotInterval = time.Minute
+	}
+
+	snapshots := newSnapshotLimiter(mw.SlowRequests.SnapshotInterval)
+
+	if mw.AccessLog.Format != "" && mw.AccessLog.Writer == nil {
+		mw.AccessLog.Writer = os.Stdout
+	}
+
+	if mw.BodyLog.RedactHeaders == nil {
+		mw.BodyLog.RedactHeaders = DefaultRedactHeaders
+	}
+
+	if mw.BodyLog.MaxSize == 0 {
+		mw.BodyLog.MaxSize = 4096
+	}
+
+	if mw.BodyLog.ContentTypes == nil {
+		mw.BodyLog.ContentTypes = []string{"application/json", "application/x-www-form-urlencoded", "text/*"}
+	}
 
 	return func(next http.Handler) http.Handler {
+		var (
+			withRoute rest.HandlerWithRoute
+			route     string
+			method    string
+		)
+
+		if nethttp.HandlerAs(next, &withRoute) {
+			route = withRoute.RoutePattern()
+			method = withRoute.RouteMethod()
+		}
+
+		slowThreshold := mw.SlowRequests.threshold(method, route)
+
 		return http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
-			ctx := r.Context()
+			var (
+				ctx       = r.Context()
+				reqFields *requestFields
+				complete  func(panicked bool)
+			)
 
 			defer func() {
 				rvr := recover()
 
 				//nolint:errorlint,goerr113 // Panic with sentinel error is not wrapped.
-				if rvr == nil || rvr == http.ErrAbortHandler {
-					return
+				panicked := rvr != nil && rvr != http.ErrAbortHandler
+
+				if panicked {
+					// Buffered messages are logged before panic.
+					if reqFields != nil {
+						reqFields.releaseDebugBuffer(ctx, true, 0)
+					}
+
+					mw.processPanic(ctx, rvr, rw)
 				}
 
-				mw.processPanic(ctx, rvr, rw)
+				// Completion runs after recovery to stop slow request watch and to log panicked request.
+				if complete != nil {
+					complete(panicked)
+				}
 			}()
 
 			fields := mw.FieldNames
@@ -133,40 +205,126 @@ func (mw HTTPRecover) Middleware() func(handler http.Handler) http.Handler {
 				fields.HTTPMethod, r.Method,
 			)
 
-			logger.Debug(ctx, "http request started", "headers", headersMap(r.Header))
+			logger.Debug(ctx, "http request started", "headers", headersMap(r.Header, mw.BodyLog.RedactHeaders))
 
-			w := middleware.NewWrapResponseWriter(rw, r.ProtoMajor)
-			start := time.Now()
+			ctx, reqFields = withRequestFields(ctx)
+			r = r.WithContext(ctx)
 
-			next.ServeHTTP(w, r)
+			route, threshold := route, slowThreshold
+			if rctx := chi.RouteContext(ctx); route == "" && rctx != nil {
+				route = rctx.RoutePattern()
+				threshold = mw.SlowRequests.threshold(r.Method, route)
+			}
 
-			elapsed := time.Since(start)
+			var sw *slowWatch
+			if threshold > 0 {
+				sw = mw.SlowRequests.watch(threshold, route, reqFields, snapshots)
+			}
 
-			ctx = ctxd.AddFields(ctx,
-				fields.HTTPResponseStatus, w.Status(),
-				fields.HTTPResponseBytes, w.BytesWritten(),
-				"elapsed", elapsed.String(),
-				"elapsed_ms", float64(elapsed.Nanoseconds())/1000000.0,
+			var (
+				reqBody, respBody *capturedBody
+				respEncoding      *encodingWriter
+				out               = rw
 			)
 
-			logger.Debug(ctx, "http request complete", "resp_headers", headersMap(w.Header()))
+			if mw.BodyLog.routeEnabled(r.Method, route) || mw.BodyLog.requestEnabled(r) {
+				reqBody = &capturedBody{limit: mw.BodyLog.MaxSize}
+				respBody = &capturedBody{limit: mw.BodyLog.MaxSize}
+				respEncoding = &encodingWriter{ResponseWriter: rw}
+				out = respEncoding
+
+				if r.Body != nil {
+					r.Body = captureReader{ReadCloser: r.Body, c: reqBody}
+				}
+			}
+
+			w := middleware.NewWrapResponseWriter(out, r.ProtoMajor)
+			if respBody != nil {
+				w.Tee(respBody)
+			}
+
+			start := time.Now()
+
+			complete = func(panicked bool) {
+				elapsed := time.Since(start)
+				snapshot := sw.stop()
+
+				status := w.Status()
+				if panicked {
+					status = http.StatusInternalServerError
+				}
+
+				if reqFields.isDebug() {
+					ctx = ctxd.WithDebug(ctx)
+				}
+
+				ctx = ctxd.AddFields(ctx,
+					fields.HTTPResponseStatus, status,
+					fields.HTTPResponseBytes, w.BytesWritten(),
+					"elapsed", elapsed.String(),
+					"elapsed_ms", float64(elapsed.Nanoseconds())/1000000.0,
+				)
+
+				reqFields.releaseDebugBuffer(ctx, threshold > 0 && elapsed >= threshold, status)
+
+				logger.Debug(ctx, "http request complete", "resp_headers", headersMap(w.Header(), mw.BodyLog.RedactHeaders))
+
+				if reqBody != nil {
+					mw.logPayload(ctx, route, r, w.Header(), respEncoding.encoding, reqBody, respBody)
+				}
+
+				if threshold > 0 && elapsed >= threshold {
+					mw.reportSlow(ctx, route, elapsed, threshold, snapshot)
+				}
+
+				if !mw.AccessLog.enabled(status, elapsed) {
+					return
+				}
+
+				if mw.AccessLog.Writer != nil {
+					clf.Lock()
+					err := mw.AccessLog.writeCLF(r, reqFields.get(mw.AccessLog.PrincipalField), start, status, w.BytesWritten())
+					clf.Unlock()
+
+					if err != nil {
+						logger.Error(ctx, "failed to write access log", "error", err)
+					}
+				}
+
+				kv := append([]interface{}{"http.route", route, "event.duration", elapsed.Nanoseconds()}, reqFields.all()...)
+
+				if id := requestID(r); id != "" {
+					kv = append(kv, "http.request.id", id)
+				}
+
+				logger.Important(ctx, "http request complete", kv...)
+			}
+
+			next.ServeHTTP(w, r)
 		})
 	}
 }
 
-func headersMap(header http.Header) ctxd.DeferredJSON {
-	return func() interface{} {
-		headers := make(map[string]string, len(header))
-
-		for k := range header {
-			v := header.Get(k)
-			if k == "Authorization" || k == "Cookie" || k == "Set-Cookie" {
-				v = "[redacted]"
-			}
+func (mw HTTPRecover) logPayload(
+	ctx context.Context, route string, r *http.Request, respHeader http.Header, respEncoding string,
+	reqBody, respBody *capturedBody,
+) {
+	mw.Logger.Important(ctx, "http request payload",
+		"http.route", route,
+		"http.request.headers", headersMap(r.Header, mw.BodyLog.RedactHeaders),
+		"http.request.body", mw.BodyLog.value(reqBody, r.Header.Get("Content-Type"), r.Header.Get("Content-Encoding")),
+		"http.response.headers", headersMap(respHeader, mw.BodyLog.RedactHeaders),
+		"http.response.body", mw.BodyLog.value(respBody, respHeader.Get("Content-Type"), respEncoding),
+	)
+}
 
-			headers[k] = v
-		}
+func (mw HTTPRecover) reportSlow(ctx context.Context, route string, elapsed, threshold time.Duration, snapshot []string) {
+	kv := []interface{}{"http.route", route, "event.duration", elapsed.Nanoseconds(), "threshold", threshold.String()}
 
-		return headers
+	if snapshot != nil {
+		kv = append(kv, "goroutine", snapshot)
 	}
+
+	mw.Logger.Warn(ctx, "slow http request", kv...)
+	mw.Stats.Add(ctx, "http_slow_requests_count", 1, "route", route)
 }
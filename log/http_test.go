@@ -0,0 +1,329 @@
+package log_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bool64/brick/compression"
+	"github.com/bool64/brick/log"
+	"github.com/bool64/ctxd"
+	"github.com/bool64/stats"
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/swaggest/openapi-go/openapi3"
+	"github.com/swaggest/rest/web"
+	"github.com/swaggest/usecase"
+	"github.com/swaggest/usecase/status"
+)
+
+var fieldNames = ctxd.FieldNames{
+	ClientIP:           "client.ip",
+	HTTPMethod:         "http.request.method",
+	HTTPResponseBytes:  "http.response.bytes",
+	HTTPResponseStatus: "http.response.status_code",
+	URL:                "url.original",
+	UserAgentOriginal:  "user_agent.original",
+}
+
+func newService(accessLog log.AccessLog, logger ctxd.Logger) *web.Service {
+	type req struct {
+		ID int `path:"id"`
+	}
+
+	s := web.NewService(openapi3.NewReflector(), func(s *web.Service) {
+		s.PanicRecoveryMiddleware = log.HTTPRecover{
+			Logger:     logger,
+			FieldNames: fieldNames,
+			AccessLog:  accessLog,
+		}.Middleware()
+	})
+
+	s.Wrap(func(h http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			log.AddRequestFields(r.Context(), "user.id", "john")
+			h.ServeHTTP(w, r)
+		})
+	})
+
+	s.Get("/items/{id}", usecase.NewInteractor(func(_ context.Context, in req, _ *struct{}) error {
+		if in.ID == 0 {
+			return status.NotFound
+		}
+
+		return nil
+	}))
+
+	return s
+}
+
+func TestHTTPRecover_Middleware_accessLog(t *testing.T) {
+	logger := &ctxd.LoggerMock{}
+	r := newService(log.AccessLog{Mode: log.AccessLogErrors}, logger)
+
+	for _, u := range []string{"/items/1", "/items/0"} {
+		req := httptest.NewRequest(http.MethodGet, u, nil)
+		req.Header.Set("X-Request-Id", "abc")
+		r.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	var entries []string
+
+	for _, e := range logger.LoggedEntries {
+		if e.Level == "important" {
+			entries = append(entries, e.Message)
+
+			assert.Equal(t, "/items/{id}", e.Data["http.route"])
+			assert.Equal(t, "john", e.Data["user.id"])
+			assert.Equal(t, "abc", e.Data["http.request.id"])
+			assert.Equal(t, 404, e.Data["http.response.status_code"])
+		}
+	}
+
+	assert.Equal(t, []string{"http request complete"}, entries)
+}
+
+func TestHTTPRecover_Middleware_combinedLogFormat(t *testing.T) {
+	logger := &ctxd.LoggerMock{}
+	buf := bytes.NewBuffer(nil)
+	r := newService(log.AccessLog{
+		Mode:           log.AccessLogAll,
+		Format:         log.AccessLogFormatCombined,
+		PrincipalField: "user.id",
+		Writer:         buf,
+	}, logger)
+
+	req := httptest.NewRequest(http.MethodGet, "/items/0", nil)
+	req.Header.Set("User-Agent", "test")
+	r.ServeHTTP(httptest.NewRecorder(), req)
+
+	line := buf.String()
+	require.NotEmpty(t, line)
+	assert.Regexp(t, `^192\.0\.2\.1 - john \[[^\]]+\] "GET /items/0 HTTP/1\.1" 404 \d+ "" "test"`+"\n$", line)
+
+	// Structured access log is written in addition to Combined Log Format.
+	require.Len(t, logger.LoggedEntries, 3)
+	assert.Equal(t, "http request complete", logger.LoggedEntries[2].Message)
+	assert.Equal(t, "important", logger.LoggedEntries[2].Level)
+}
+
+func TestHTTPRecover_Middleware_panic(t *testing.T) {
+	logger := &ctxd.LoggerMock{}
+	buf := bytes.NewBuffer(nil)
+
+	h := log.HTTPRecover{
+		Logger:     logger,
+		FieldNames: fieldNames,
+		AccessLog:  log.AccessLog{Mode: log.AccessLogErrors, Format: log.AccessLogFormatCommon, Writer: buf},
+		SlowRequests: log.SlowRequests{
+			Threshold:         10 * time.Millisecond,
+			GoroutineSnapshot: true,
+		},
+	}.Middleware()(http.HandlerFunc(func(_ http.ResponseWriter, _ *http.Request) {
+		time.Sleep(20 * time.Millisecond)
+		panic("oops")
+	}))
+
+	rw := httptest.NewRecorder()
+	h.ServeHTTP(rw, httptest.NewRequest(http.MethodGet, "/panic", nil))
+	assert.Equal(t, http.StatusInternalServerError, rw.Code)
+
+	var messages []string
+
+	for _, e := range logger.LoggedEntries {
+		if e.Level != "debug" {
+			messages = append(messages, e.Level+": "+e.Message)
+		}
+
+		if e.Level == "important" {
+			assert.Equal(t, http.StatusInternalServerError, e.Data["http.response.status_code"])
+		}
+	}
+
+	assert.Equal(t, []string{
+		"error: request panicked",
+		"warn: slow http request",
+		"important: http request complete",
+	}, messages)
+	assert.Contains(t, buf.String(), `"GET /panic HTTP/1.1" 500 `)
+}
+
+func TestHTTPRecover_Middleware_slowRequests(t *testing.T) {
+	logger := &ctxd.LoggerMock{}
+	st := &stats.TrackerMock{}
+
+	h := log.HTTPRecover{
+		Logger:     logger,
+		FieldNames: fieldNames,
+		SlowRequests: log.SlowRequests{
+			Threshold:         time.Second,
+			RouteThresholds:   map[string]time.Duration{"GET /slow": 10 * time.Millisecond},
+			GoroutineSnapshot: true,
+		},
+		Stats: st,
+	}.Middleware()(http.HandlerFunc(func(_ http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/slow" {
+			time.Sleep(50 * time.Millisecond)
+		}
+	}))
+
+	r := chi.NewRouter()
+	r.Get("/slow", h.ServeHTTP)
+	r.Get("/fast", h.ServeHTTP)
+
+	for _, u := range []string{"/slow", "/fast"} {
+		r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, u, nil))
+	}
+
+	var warns []int
+
+	for i, e := range logger.LoggedEntries {
+		if e.Level == "warn" {
+			warns = append(warns, i)
+		}
+	}
+
+	require.Len(t, warns, 1)
+
+	e := logger.LoggedEntries[warns[0]]
+	assert.Equal(t, "slow http request", e.Message)
+	assert.Equal(t, "/slow", e.Data["http.route"])
+	assert.Equal(t, "10ms", e.Data["threshold"])
+	assert.Contains(t, fmt.Sprint(e.Data["goroutine"]), "log_test.TestHTTPRecover_Middleware_slowRequests.func1")
+
+	assert.Equal(t, 1.0, st.Value("http_slow_requests_count", "route", "/slow"))
+}
+
+func TestHTTPRecover_Middleware_snapshotInterval(t *testing.T) {
+	logger := &ctxd.LoggerMock{}
+
+	h := log.HTTPRecover{
+		Logger:     logger,
+		FieldNames: fieldNames,
+		SlowRequests: log.SlowRequests{
+			Threshold:         10 * time.Millisecond,
+			GoroutineSnapshot: true,
+		},
+	}.Middleware()(http.HandlerFunc(func(_ http.ResponseWriter, _ *http.Request) {
+		time.Sleep(30 * time.Millisecond)
+	}))
+
+	r := chi.NewRouter()
+	r.Get("/slow", h.ServeHTTP)
+	r.Get("/other", h.ServeHTTP)
+
+	for _, u := range []string{"/slow", "/slow", "/other"} {
+		r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, u, nil))
+	}
+
+	var snapshots []string
+
+	for _, e := range logger.LoggedEntries {
+		if e.Message == "slow http request" {
+			_, ok := e.Data["goroutine"]
+			snapshots = append(snapshots, fmt.Sprint(e.Data["http.route"], " ", ok))
+		}
+	}
+
+	assert.Equal(t, []string{"/slow true", "/slow false", "/other true"}, snapshots)
+}
+
+func TestHTTPRecover_Middleware_bodyLog(t *testing.T) {
+	logger := &ctxd.LoggerMock{}
+
+	h := log.HTTPRecover{
+		Logger:     logger,
+		FieldNames: fieldNames,
+		BodyLog: log.BodyLog{
+			DebugHeader:  "X-Debug-Body",
+			DebugToken:   "secret-token",
+			RedactFields: []string{"password", "cards.*.number"},
+		},
+	}.Middleware()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, err := io.ReadAll(r.Body)
+		assert.NoError(t, err)
+
+		w.Header().Set("Content-Type", "application/json")
+		_, err = w.Write(b)
+		assert.NoError(t, err)
+	}))
+
+	body := `{"login":"john","password":"123","cards":[{"number":"4111","exp":"12/30"}]}`
+
+	for _, token := range []string{"secret-token", "wrong"} {
+		req := httptest.NewRequest(http.MethodPost, "/login", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", "Bearer abc")
+		req.Header.Set("X-API-Key", "key")
+		req.Header.Set("X-Debug-Body", token)
+
+		rw := httptest.NewRecorder()
+		h.ServeHTTP(rw, req)
+		assert.Equal(t, body, rw.Body.String())
+	}
+
+	var payloads []map[string]interface{}
+
+	for _, e := range logger.LoggedEntries {
+		if e.Message == "http request payload" {
+			payloads = append(payloads, e.Data)
+		}
+	}
+
+	require.Len(t, payloads, 1)
+
+	j, err := json.Marshal(payloads[0])
+	require.NoError(t, err)
+
+	assert.Contains(t, string(j), `"Authorization":"[redacted]"`)
+	assert.Contains(t, string(j), `"X-Api-Key":"[redacted]"`)
+	assert.Contains(t, string(j), `"http.request.body":{"cards":[{"exp":"12/30","number":"[redacted]"}],`+
+		`"login":"john","password":"[redacted]"}`)
+	assert.Contains(t, string(j), `"http.response.body":{"cards":[{"exp":"12/30","number":"[redacted]"}],`)
+}
+
+func TestHTTPRecover_Middleware_bodyLogCompressed(t *testing.T) {
+	logger := &ctxd.LoggerMock{}
+
+	compress, err := compression.Middleware(compression.Config{
+		Enabled:      true,
+		Encodings:    []string{compression.Gzip},
+		ContentTypes: []string{"application/json"},
+	}, nil)
+	require.NoError(t, err)
+
+	h := log.HTTPRecover{
+		Logger:     logger,
+		FieldNames: fieldNames,
+		BodyLog:    log.BodyLog{Routes: []string{"*"}},
+	}.Middleware()(compress(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, err := w.Write([]byte(`{"password":"123"}`))
+		assert.NoError(t, err)
+	})))
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+
+	rw := httptest.NewRecorder()
+	h.ServeHTTP(rw, req)
+	assert.Equal(t, "gzip", rw.Header().Get("Content-Encoding"))
+
+	var payloads []interface{}
+
+	for _, e := range logger.LoggedEntries {
+		if e.Message == "http request payload" {
+			payloads = append(payloads, e.Data["http.response.body"])
+		}
+	}
+
+	assert.Equal(t, []interface{}{"[omitted content encoding gzip]"}, payloads)
+}
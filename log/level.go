@@ -0,0 +1,460 @@
+package log
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"html/template"
+	"net/http"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/bool64/ctxd"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+const (
+	// DefaultLevelTTL is the duration of a level change if it is not specified.
+	DefaultLevelTTL = 10 * time.Minute
+
+	// MaxLevelTTL limits the duration of a level change.
+	MaxLevelTTL = 24 * time.Hour
+)
+
+var errLevelTTL = errors.New("level change duration must be positive and not exceed " + MaxLevelTTL.String())
+
+// LevelControl changes log level at runtime globally or for packages, changes are reverted after a timeout.
+//
+// Package overrides are applied by a logger wrapped with Wrap, they affect messages
+// logged from functions of the package and its subpackages.
+// Errors of use cases are logged by UsecaseErrors middleware, so they are attributed to
+// "github.com/bool64/brick/log" package rather than to the package of a use case.
+type LevelControl struct {
+	// Logger receives audit messages of level changes.
+	Logger ctxd.Logger
+
+	base  zapcore.Level
+	level zap.AtomicLevel
+
+	// pkgLevels is a snapshot of package overrides for lock-free reads, nil if there are no overrides.
+	pkgLevels atomic.Pointer[map[string]zapcore.Level]
+
+	mu       sync.Mutex
+	global   levelChange
+	packages map[string]levelChange
+}
+
+type levelChange struct {
+	Level   zapcore.Level `json:"level"`
+	Until   time.Time     `json:"until"`
+	Changed string        `json:"changed"`
+
+	timer *time.Timer
+}
+
+// NewLevelControl creates level control with the configured level.
+func NewLevelControl(level zapcore.Level) *LevelControl {
+	return &LevelControl{
+		Logger:   ctxd.NoOpLogger{},
+		base:     level,
+		level:    zap.NewAtomicLevelAt(level),
+		packages: make(map[string]levelChange),
+	}
+}
+
+// Enabled implements zapcore.LevelEnabler.
+func (c *LevelControl) Enabled(level zapcore.Level) bool {
+	return c.level.Enabled(level)
+}
+
+// Level returns current global level.
+func (c *LevelControl) Level() zapcore.Level {
+	return c.level.Level()
+}
+
+// Set changes level globally (with empty pkg) or for a package for a limited duration.
+//
+// Zero ttl applies DefaultLevelTTL.
+func (c *LevelControl) Set(ctx context.Context, pkg string, level zapcore.Level, ttl time.Duration, changedBy string) error {
+	if ttl == 0 {
+		ttl = DefaultLevelTTL
+	}
+
+	if ttl < 0 || ttl > MaxLevelTTL {
+		return errLevelTTL
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	ch := levelChange{Level: level, Until: time.Now().Add(ttl), Changed: changedBy}
+
+	var prev levelChange
+
+	if pkg == "" {
+		prev = c.global
+		c.level.SetLevel(level)
+	} else {
+		prev = c.packages[pkg]
+	}
+
+	if prev.timer != nil {
+		prev.timer.Stop()
+	}
+
+	ch.timer = time.AfterFunc(ttl, func() { c.revert(pkg, ch.Until) })
+
+	if pkg == "" {
+		c.global = ch
+	} else {
+		c.packages[pkg] = ch
+		c.storePackageLevels()
+	}
+
+	c.Logger.Important(ctx, "log level changed",
+		"package", pkg, "level", level.String(), "until", ch.Until, "changed_by", changedBy)
+
+	return nil
+}
+
+// Reset reverts level change of a package or global level with empty pkg.
+func (c *LevelControl) Reset(ctx context.Context, pkg string, changedBy string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.reset(pkg)
+
+	c.Logger.Important(ctx, "log level reset", "package", pkg, "changed_by", changedBy)
+}
+
+func (c *LevelControl) revert(pkg string, until time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	ch := c.global
+	if pkg != "" {
+		ch = c.packages[pkg]
+	}
+
+	// Change was replaced.
+	if !ch.Until.Equal(until) {
+		return
+	}
+
+	c.reset(pkg)
+
+	c.Logger.Important(context.Background(), "log level change expired", "package", pkg)
+}
+
+func (c *LevelControl) reset(pkg string) {
+	if pkg == "" {
+		if c.global.timer != nil {
+			c.global.timer.Stop()
+		}
+
+		c.global = levelChange{}
+		c.level.SetLevel(c.base)
+
+		return
+	}
+
+	if ch, ok := c.packages[pkg]; ok && ch.timer != nil {
+		ch.timer.Stop()
+	}
+
+	delete(c.packages, pkg)
+	c.storePackageLevels()
+}
+
+// storePackageLevels updates snapshot of package overrides, it must be called with mu locked.
+func (c *LevelControl) storePackageLevels() {
+	if len(c.packages) == 0 {
+		c.pkgLevels.Store(nil)
+
+		return
+	}
+
+	levels := make(map[string]zapcore.Level, len(c.packages))
+	for p, ch := range c.packages {
+		levels[p] = ch.Level
+	}
+
+	c.pkgLevels.Store(&levels)
+}
+
+// packageLevel returns level override for package or its closest parent package.
+func packageLevel(levels map[string]zapcore.Level, pkg string) (zapcore.Level, bool) {
+	for {
+		if l, ok := levels[pkg]; ok {
+			return l, true
+		}
+
+		i := strings.LastIndex(pkg, "/")
+		if i <= 0 {
+			return 0, false
+		}
+
+		pkg = pkg[:i]
+	}
+}
+
+// Wrap makes a logger that applies package level overrides.
+//
+// Messages of packages with overrides bypass global level of zapctxd logger,
+// so the wrapped logger must have level enabler of LevelControl.
+func (c *LevelControl) Wrap(logger ctxd.Logger) ctxd.Logger {
+	return levelLogger{c: c, next: logger}
+}
+
+type levelLogger struct {
+	c    *LevelControl
+	next ctxd.Logger
+}
+
+func (l levelLogger) enabled(ctx context.Context, level zapcore.Level) (context.Context, bool) {
+	levels := l.c.pkgLevels.Load()
+	if levels == nil {
+		return ctx, true
+	}
+
+	ol, ok := packageLevel(*levels, callerPackage())
+	if !ok {
+		return ctx, true
+	}
+
+	if level < ol {
+		return ctx, false
+	}
+
+	return ctxd.WithDebug(ctx), true
+}
+
+// Debug implements ctxd.Logger.
+func (l levelLogger) Debug(ctx context.Context, msg string, keysAndValues ...interface{}) {
+	if ctx, ok := l.enabled(ctx, zap.DebugLevel); ok {
+		l.next.Debug(ctx, msg, keysAndValues...)
+	}
+}
+
+// Info implements ctxd.Logger.
+func (l levelLogger) Info(ctx context.Context, msg string, keysAndValues ...interface{}) {
+	if ctx, ok := l.enabled(ctx, zap.InfoLevel); ok {
+		l.next.Info(ctx, msg, keysAndValues...)
+	}
+}
+
+// Important implements ctxd.Logger.
+func (l levelLogger) Important(ctx context.Context, msg string, keysAndValues ...interface{}) {
+	l.next.Important(ctx, msg, keysAndValues...)
+}
+
+// Warn implements ctxd.Logger.
+func (l levelLogger) Warn(ctx context.Context, msg string, keysAndValues ...interface{}) {
+	if ctx, ok := l.enabled(ctx, zap.WarnLevel); ok {
+		l.next.Warn(ctx, msg, keysAndValues...)
+	}
+}
+
+// Error implements ctxd.Logger.
+func (l levelLogger) Error(ctx context.Context, msg string, keysAndValues ...interface{}) {
+	if ctx, ok := l.enabled(ctx, zap.ErrorLevel); ok {
+		l.next.Error(ctx, msg, keysAndValues...)
+	}
+}
+
+// framePackages caches package paths by program counters, empty value marks logger wrappers.
+var framePackages sync.Map // map[uintptr]string
+
+// callerPackage returns package path of the first function outside of logger wrappers.
+func callerPackage() string {
+	var pc [16]uintptr
+
+	for _, p := range pc[:runtime.Callers(3, pc[:])] {
+		if pkg := framePackage(p); pkg != "" {
+			return pkg
+		}
+	}
+
+	return ""
+}
+
+// framePackage returns package path of function at program counter or empty string for logger wrappers.
+func framePackage(pc uintptr) string {
+	if v, ok := framePackages.Load(pc); ok {
+		if pkg, ok := v.(string); ok {
+			return pkg
+		}
+	}
+
+	pkg := ""
+	frames := runtime.CallersFrames([]uintptr{pc})
+
+	for {
+		f, more := frames.Next()
+
+		p := f.Function
+		if i := strings.LastIndex(p, "/"); i >= 0 {
+			if j := strings.Index(p[i:], "."); j > 0 {
+				p = p[:i+j]
+			}
+		} else if j := strings.Index(p, "."); j > 0 {
+			p = p[:j]
+		}
+
+		if p != "github.com/bool64/logz/ctxz" && p != "github.com/bool64/ctxd" &&
+			!strings.HasPrefix(f.Function, "github.com/bool64/brick/log.levelLogger.") &&
+			!strings.HasPrefix(f.Function, "github.com/bool64/brick/log.(*SampledLogger).") {
+			pkg = p
+
+			break
+		}
+
+		if !more {
+			break
+		}
+	}
+
+	framePackages.Store(pc, pkg)
+
+	return pkg
+}
+
+type levelState struct {
+	Base     string                 `json:"base"`
+	Level    string                 `json:"level"`
+	Global   *levelChange           `json:"global,omitempty"`
+	Packages map[string]levelChange `json:"packages"`
+}
+
+func (c *LevelControl) state() levelState {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	s := levelState{
+		Base:     c.base.String(),
+		Level:    c.level.Level().String(),
+		Packages: make(map[string]levelChange, len(c.packages)),
+	}
+
+	if !c.global.Until.IsZero() {
+		g := c.global
+		s.Global = &g
+	}
+
+	for p, ch := range c.packages {
+		s.Packages[p] = ch
+	}
+
+	return s
+}
+
+var levelPage = template.Must(template.New("loglevel").Parse(`<!DOCTYPE html>
+<html><head><title>Log Level</title></head><body>
+<h2>Log Level</h2>
+<p>Configured level: <b>{{.State.Base}}</b>, current global level: <b>{{.State.Level}}</b>
+{{with .State.Global}}until {{.Until.Format "2006-01-02 15:04:05"}} ({{.Changed}}){{end}}</p>
+{{if .State.Packages}}<table><tr><th>Package</th><th>Level</th><th>Until</th><th>Changed</th><th></th></tr>
+{{range $p := .Packages}}{{with index $.State.Packages $p}}<tr><td>{{$p}}</td><td>{{.Level}}</td>
+<td>{{.Until.Format "2006-01-02 15:04:05"}}</td><td>{{.Changed}}</td>
+<td><form method="post"><input type="hidden" name="package" value="{{$p}}"/>
+<input type="hidden" name="reset" value="1"/><button>Reset</button></form></td></tr>{{end}}{{end}}
+</table>{{end}}
+<form method="post">
+<label>Package (empty for global) <input name="package" size="60"/></label>
+<label>Level <select name="level">{{range .Levels}}<option>{{.}}</option>{{end}}</select></label>
+<label>Duration <input name="duration" value="10m" size="5"/></label>
+<button>Apply</button>
+</form>
+{{if .State.Global}}<form method="post"><input type="hidden" name="reset" value="1"/>
+<button>Reset global level</button></form>{{end}}
+</body></html>`))
+
+// ServeHTTP shows and changes log levels.
+//
+// GET request renders current state as HTML or as JSON if "application/json" is accepted.
+// POST request with form or query parameters "package", "level", "duration" and "reset" changes levels.
+func (c *LevelControl) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodPost {
+		if err := c.change(r); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+
+			return
+		}
+
+		if !strings.Contains(r.Header.Get("Accept"), "application/json") {
+			http.Redirect(w, r, r.URL.Path, http.StatusSeeOther)
+
+			return
+		}
+	}
+
+	s := c.state()
+
+	if strings.Contains(r.Header.Get("Accept"), "application/json") {
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+
+		if err := json.NewEncoder(w).Encode(s); err != nil {
+			c.Logger.Error(r.Context(), "failed to write log level state", "error", err)
+		}
+
+		return
+	}
+
+	pkgs := make([]string, 0, len(s.Packages))
+	for p := range s.Packages {
+		pkgs = append(pkgs, p)
+	}
+
+	sort.Strings(pkgs)
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+
+	if err := levelPage.Execute(w, map[string]interface{}{
+		"State":    s,
+		"Packages": pkgs,
+		"Levels":   []string{"debug", "info", "warn", "error"},
+	}); err != nil {
+		c.Logger.Error(r.Context(), "failed to render log level page", "error", err)
+	}
+}
+
+func (c *LevelControl) change(r *http.Request) error {
+	if err := r.ParseForm(); err != nil {
+		return err
+	}
+
+	changedBy := r.RemoteAddr
+	if user, _, ok := r.BasicAuth(); ok {
+		changedBy = user + "@" + changedBy
+	}
+
+	pkg := r.Form.Get("package")
+
+	if r.Form.Get("reset") != "" {
+		c.Reset(r.Context(), pkg, changedBy)
+
+		return nil
+	}
+
+	var level zapcore.Level
+	if err := level.UnmarshalText([]byte(r.Form.Get("level"))); err != nil {
+		return err
+	}
+
+	var ttl time.Duration
+
+	if d := r.Form.Get("duration"); d != "" {
+		var err error
+
+		if ttl, err = time.ParseDuration(d); err != nil {
+			return err
+		}
+	}
+
+	return c.Set(r.Context(), pkg, level, ttl, changedBy)
+}
@@ -0,0 +1,119 @@
+package log_test
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/bool64/brick/log"
+	"github.com/bool64/ctxd"
+	"github.com/bool64/zapctxd"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+type syncBuffer struct {
+	mu sync.Mutex
+	bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.Buffer.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.Buffer.String()
+}
+
+func TestLevelControl(t *testing.T) {
+	out := &syncBuffer{}
+	audit := &ctxd.LoggerMock{}
+	ctx := context.Background()
+
+	lc := log.NewLevelControl(zap.ErrorLevel)
+	lc.Logger = audit
+
+	zl := zapctxd.New(zapctxd.Config{Output: out, StripTime: true})
+	zl.SetLevelEnabler(lc)
+
+	logger := lc.Wrap(zl)
+
+	logger.Debug(ctx, "hidden debug")
+	logger.Warn(ctx, "hidden warn")
+
+	require.NoError(t, lc.Set(ctx, "github.com/bool64/brick/log_test", zap.DebugLevel, time.Minute, "test"))
+	logger.Debug(ctx, "package debug")
+
+	lc.Reset(ctx, "github.com/bool64/brick/log_test", "test")
+	logger.Debug(ctx, "hidden debug")
+
+	require.NoError(t, lc.Set(ctx, "", zap.WarnLevel, 10*time.Millisecond, "test"))
+	logger.Warn(ctx, "global warn")
+	assert.Equal(t, zap.WarnLevel, lc.Level())
+
+	auditLog := func() string {
+		audit.Lock()
+		defer audit.Unlock()
+
+		return audit.String()
+	}
+
+	assert.Eventually(t, func() bool {
+		return strings.Contains(auditLog(), `important: log level change expired {"package":""}`)
+	}, time.Second, time.Millisecond)
+	assert.Equal(t, zap.ErrorLevel, lc.Level())
+	logger.Warn(ctx, "hidden warn")
+
+	assert.NotContains(t, out.String(), "hidden")
+	assert.Contains(t, out.String(), "package debug")
+	assert.Contains(t, out.String(), "global warn")
+
+	assert.Error(t, lc.Set(ctx, "", zap.DebugLevel, 48*time.Hour, "test"))
+
+	assert.Contains(t, auditLog(), `important: log level changed {"changed_by":"test","level":"debug",`+
+		`"package":"github.com/bool64/brick/log_test"`)
+}
+
+func TestLevelControl_ServeHTTP(t *testing.T) {
+	lc := log.NewLevelControl(zap.ErrorLevel)
+
+	req := httptest.NewRequest(http.MethodPost, "/loglevel", strings.NewReader(url.Values{
+		"package":  []string{"github.com/bool64/brick/database"},
+		"level":    []string{"debug"},
+		"duration": []string{"1h"},
+	}.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	rw := httptest.NewRecorder()
+	lc.ServeHTTP(rw, req)
+	assert.Equal(t, http.StatusSeeOther, rw.Code)
+
+	req = httptest.NewRequest(http.MethodGet, "/loglevel", nil)
+	req.Header.Set("Accept", "application/json")
+
+	rw = httptest.NewRecorder()
+	lc.ServeHTTP(rw, req)
+	assert.Equal(t, http.StatusOK, rw.Code)
+	assert.Contains(t, rw.Body.String(), `"base":"error","level":"error","packages":{"github.com/bool64/brick/database":{"level":"debug",`)
+
+	rw = httptest.NewRecorder()
+	lc.ServeHTTP(rw, httptest.NewRequest(http.MethodGet, "/loglevel", nil))
+	assert.Contains(t, rw.Body.String(), "<td>github.com/bool64/brick/database</td><td>debug</td>")
+
+	rw = httptest.NewRecorder()
+	lc.ServeHTTP(rw, httptest.NewRequest(http.MethodPost, "/loglevel?level=loud", nil))
+	assert.Equal(t, http.StatusBadRequest, rw.Code)
+}
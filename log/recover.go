@@ -0,0 +1,95 @@
+package log
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime/debug"
+	"strings"
+
+	"github.com/bool64/ctxd"
+	"github.com/bool64/stats"
+	"github.com/swaggest/usecase"
+	"github.com/swaggest/usecase/status"
+)
+
+// ErrPanicked is wrapped by errors of recovered use case panics.
+var ErrPanicked = errors.New("panicked")
+
+// Recoverer handles panics of use cases, background goroutines and other tasks.
+//
+// Recovered panic is logged with stack, counted in stats and passed to OnPanic hooks.
+type Recoverer struct {
+	Logger  ctxd.Logger
+	Stats   stats.Tracker
+	OnPanic []func(ctx context.Context, rcv interface{}, stack []byte)
+}
+
+// Handle logs and reports recovered panic.
+//
+// Source describes the kind of panicked task, for example "goroutine", name identifies the task.
+func (r Recoverer) Handle(ctx context.Context, source, name string, rcv interface{}, stack []byte) {
+	if r.Logger != nil {
+		r.Logger.Error(ctx, source+" panicked",
+			"name", name,
+			"panic", rcv,
+			"stack", strings.Split(string(stack), "\n"),
+		)
+	}
+
+	if r.Stats != nil {
+		r.Stats.Add(ctx, "panics_recovered_count", 1, "source", source, "name", name)
+	}
+
+	defer func() {
+		if rcv := recover(); rcv != nil && r.Logger != nil {
+			r.Logger.Error(ctx, "panic while handling panic", "panic", rcv)
+		}
+	}()
+
+	for _, onPanic := range r.OnPanic {
+		onPanic(ctx, rcv, stack)
+	}
+}
+
+// Go runs function in a new goroutine and recovers its panic.
+//
+// Context is passed to function as is, use context.WithoutCancel to detach from request cancellation.
+func (r Recoverer) Go(ctx context.Context, name string, fn func(ctx context.Context)) {
+	go func() {
+		defer func() {
+			if rcv := recover(); rcv != nil {
+				r.Handle(ctx, "goroutine", name, rcv, debug.Stack())
+			}
+		}()
+
+		fn(ctx)
+	}()
+}
+
+// UseCaseMiddleware recovers use case panics into status.Internal errors.
+//
+// Use cases served over HTTP are already protected by HTTPRecover, this middleware
+// is useful for use cases invoked by workers, consumers or CLI commands.
+func (r Recoverer) UseCaseMiddleware() usecase.Middleware {
+	return usecase.MiddlewareFunc(func(u usecase.Interactor) usecase.Interactor {
+		var withName usecase.HasName
+
+		name := ""
+		if usecase.As(u, &withName) {
+			name = withName.Name()
+		}
+
+		return usecase.Interact(func(ctx context.Context, input, output interface{}) (err error) {
+			defer func() {
+				if rcv := recover(); rcv != nil {
+					r.Handle(ctx, "use case", name, rcv, debug.Stack())
+
+					err = status.Wrap(fmt.Errorf("%w: %v", ErrPanicked, rcv), status.Internal)
+				}
+			}()
+
+			return u.Interact(ctx, input, output)
+		})
+	})
+}
@@ -0,0 +1,63 @@
+package log_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/bool64/brick/log"
+	"github.com/bool64/ctxd"
+	"github.com/bool64/stats"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/swaggest/rest"
+	"github.com/swaggest/usecase"
+)
+
+func TestRecoverer(t *testing.T) {
+	logger := &ctxd.LoggerMock{}
+	st := &stats.TrackerMock{}
+	reported := make(chan interface{}, 2)
+
+	r := log.Recoverer{
+		Logger: logger,
+		Stats:  st,
+		OnPanic: []func(ctx context.Context, rcv interface{}, stack []byte){
+			func(_ context.Context, rcv interface{}, stack []byte) {
+				assert.NotEmpty(t, stack)
+
+				reported <- rcv
+			},
+		},
+	}
+
+	u := usecase.NewInteractor(func(_ context.Context, _ struct{}, _ *struct{}) error {
+		panic("use case failed")
+	})
+	u.SetName("failing")
+
+	err := usecase.Wrap(u, r.UseCaseMiddleware()).Interact(context.Background(), struct{}{}, &struct{}{})
+	require.Error(t, err)
+	assert.True(t, errors.Is(err, log.ErrPanicked))
+	assert.EqualError(t, err, "internal: panicked: use case failed")
+
+	code, _ := rest.Err(err)
+	assert.Equal(t, 500, code)
+	assert.Equal(t, "use case failed", <-reported)
+
+	r.Go(context.Background(), "worker", func(_ context.Context) {
+		panic("goroutine failed")
+	})
+
+	select {
+	case rcv := <-reported:
+		assert.Equal(t, "goroutine failed", rcv)
+	case <-time.After(time.Second):
+		assert.Fail(t, "goroutine panic was not reported")
+	}
+
+	assert.Equal(t, 1.0, st.Value("panics_recovered_count", "source", "use case", "name", "failing"))
+	assert.Equal(t, 1.0, st.Value("panics_recovered_count", "source", "goroutine", "name", "worker"))
+	assert.Contains(t, logger.String(), `error: use case panicked {"name":"failing","panic":"use case failed","stack":[`)
+}
@@ -0,0 +1,217 @@
+package log
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/bool64/ctxd"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+const samplingMaxMessages = 10000
+
+// Sampling controls suppression of repeated log messages.
+//
+// Messages are identified by level and text, IMPORTANT messages are never suppressed.
+type Sampling struct {
+	// Initial is the number of messages logged per Tick before sampling starts, zero disables sampling.
+	Initial int
+
+	// Thereafter enables logging of every Mth message after Initial, zero suppresses all of them.
+	Thereafter int `default:"100"`
+
+	// Tick is the period of Initial counter.
+	Tick time.Duration `default:"1s"`
+
+	// RateLimits maps message text to maximum number of messages per second.
+	RateLimits map[string]float64 `split_words:"true"`
+
+	// ReportInterval is the period of reporting suppressed messages.
+	ReportInterval time.Duration `split_words:"true" default:"1m"`
+}
+
+// SampledLogger suppresses repeated messages and periodically reports suppressed counts.
+//
+// Messages that are disabled by log level are passed to next logger without sampling.
+type SampledLogger struct {
+	cfg   Sampling
+	level zapcore.LevelEnabler
+	next  ctxd.Logger
+
+	mu         sync.Mutex
+	counters   map[samplingKey]*samplingCounter
+	lastReport time.Time
+}
+
+type samplingKey struct {
+	level string
+	msg   string
+}
+
+type samplingCounter struct {
+	window     time.Time
+	n          int
+	tokens     float64
+	last       time.Time
+	suppressed int
+}
+
+// NewSampledLogger wraps logger with sampling, nil level enables all messages.
+func NewSampledLogger(cfg Sampling, level zapcore.LevelEnabler, logger ctxd.Logger) *SampledLogger {
+	if cfg.Tick == 0 {
+		cfg.Tick = time.Second
+	}
+
+	if cfg.ReportInterval == 0 {
+		cfg.ReportInterval = time.Minute
+	}
+
+	return &SampledLogger{
+		cfg:        cfg,
+		level:      level,
+		next:       logger,
+		counters:   make(map[samplingKey]*samplingCounter),
+		lastReport: time.Now(),
+	}
+}
+
+func (s *SampledLogger) enabled() bool {
+	return s.cfg.Initial > 0 || len(s.cfg.RateLimits) > 0
+}
+
+func (s *SampledLogger) allow(ctx context.Context, level zapcore.Level, msg string) bool {
+	if !s.enabled() {
+		return true
+	}
+
+	// Disabled messages are not counted, they are dropped or buffered by next logger.
+	if s.level != nil && !s.level.Enabled(level) && !ctxd.IsDebug(ctx) {
+		return true
+	}
+
+	now := time.Now()
+
+	s.mu.Lock()
+
+	if now.Sub(s.lastReport) >= s.cfg.ReportInterval {
+		s.lastReport = now
+
+		// Summary is not related to the message that triggered it.
+		defer s.Flush(context.Background())
+	}
+
+	defer s.mu.Unlock()
+
+	k := samplingKey{level: level.String(), msg: msg}
+
+	c, ok := s.counters[k]
+	if !ok {
+		if len(s.counters) >= samplingMaxMessages {
+			return true
+		}
+
+		c = &samplingCounter{window: now, last: now}
+
+		if rate, ok := s.cfg.RateLimits[msg]; ok {
+			c.tokens = max(rate, 1)
+		}
+
+		s.counters[k] = c
+	}
+
+	if rate, ok := s.cfg.RateLimits[msg]; ok {
+		c.tokens = min(max(rate, 1), c.tokens+now.Sub(c.last).Seconds()*rate)
+		c.last = now
+
+		if c.tokens < 1 {
+			c.suppressed++
+
+			return false
+		}
+
+		c.tokens--
+	}
+
+	if s.cfg.Initial > 0 {
+		if now.Sub(c.window) >= s.cfg.Tick {
+			c.window = now
+			c.n = 0
+		}
+
+		c.n++
+
+		if c.n > s.cfg.Initial && (s.cfg.Thereafter <= 0 || (c.n-s.cfg.Initial)%s.cfg.Thereafter != 0) {
+			c.suppressed++
+
+			return false
+		}
+	}
+
+	return true
+}
+
+// Flush reports suppressed messages and removes idle counters.
+func (s *SampledLogger) Flush(ctx context.Context) {
+	type report struct {
+		samplingKey
+		count int
+	}
+
+	var reports []report
+
+	s.mu.Lock()
+
+	now := time.Now()
+	s.lastReport = now
+
+	for k, c := range s.counters {
+		if c.suppressed > 0 {
+			reports = append(reports, report{samplingKey: k, count: c.suppressed})
+			c.suppressed = 0
+		} else if now.Sub(c.window) > s.cfg.ReportInterval && now.Sub(c.last) > s.cfg.ReportInterval {
+			delete(s.counters, k)
+		}
+	}
+
+	s.mu.Unlock()
+
+	for _, r := range reports {
+		s.next.Important(ctx, "log messages suppressed",
+			"message", r.msg, "level", r.level, "count", r.count)
+	}
+}
+
+// Debug implements ctxd.Logger.
+func (s *SampledLogger) Debug(ctx context.Context, msg string, keysAndValues ...interface{}) {
+	if s.allow(ctx, zap.DebugLevel, msg) {
+		s.next.Debug(ctx, msg, keysAndValues...)
+	}
+}
+
+// Info implements ctxd.Logger.
+func (s *SampledLogger) Info(ctx context.Context, msg string, keysAndValues ...interface{}) {
+	if s.allow(ctx, zap.InfoLevel, msg) {
+		s.next.Info(ctx, msg, keysAndValues...)
+	}
+}
+
+// Important implements ctxd.Logger.
+func (s *SampledLogger) Important(ctx context.Context, msg string, keysAndValues ...interface{}) {
+	s.next.Important(ctx, msg, keysAndValues...)
+}
+
+// Warn implements ctxd.Logger.
+func (s *SampledLogger) Warn(ctx context.Context, msg string, keysAndValues ...interface{}) {
+	if s.allow(ctx, zap.WarnLevel, msg) {
+		s.next.Warn(ctx, msg, keysAndValues...)
+	}
+}
+
+// Error implements ctxd.Logger.
+func (s *SampledLogger) Error(ctx context.Context, msg string, keysAndValues ...interface{}) {
+	if s.allow(ctx, zap.ErrorLevel, msg) {
+		s.next.Error(ctx, msg, keysAndValues...)
+	}
+}
@@ -0,0 +1,99 @@
+package log_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bool64/brick/log"
+	"github.com/bool64/ctxd"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+func TestSampledLogger(t *testing.T) {
+	out := &ctxd.LoggerMock{}
+	ctx := context.Background()
+
+	sl := log.NewSampledLogger(log.Sampling{
+		Initial:    2,
+		Thereafter: 5,
+		Tick:       time.Hour,
+		RateLimits: map[string]float64{"limited": 1},
+	}, nil, out)
+
+	for i := 0; i < 12; i++ {
+		sl.Error(ctx, "flapping")
+		sl.Warn(ctx, "limited")
+		sl.Important(ctx, "important")
+	}
+
+	sl.Info(ctx, "other")
+
+	s := out.String()
+	assert.Equal(t, 4, strings.Count(s, ": flapping ")) // 1, 2, 7, 12.
+	assert.Equal(t, 1, strings.Count(s, ": limited "))
+	assert.Equal(t, 12, strings.Count(s, ": important "))
+	assert.Equal(t, 1, strings.Count(s, ": other "))
+
+	sl.Flush(ctx)
+
+	s = out.String()
+	assert.Contains(t, s, `important: log messages suppressed {"count":8,"level":"error","message":"flapping"}`)
+	assert.Contains(t, s, `important: log messages suppressed {"count":11,"level":"warn","message":"limited"}`)
+
+	sl.Flush(ctx)
+	assert.Equal(t, 2, strings.Count(out.String(), "log messages suppressed"))
+}
+
+func TestSampledLogger_disabled(t *testing.T) {
+	out := &ctxd.LoggerMock{}
+	ctx := context.Background()
+
+	sl := log.NewSampledLogger(log.Sampling{}, nil, out)
+
+	for i := 0; i < 10; i++ {
+		sl.Error(ctx, "flapping")
+	}
+
+	sl.Flush(ctx)
+
+	assert.Equal(t, 10, strings.Count(out.String(), ": flapping "))
+	assert.NotContains(t, out.String(), "suppressed")
+}
+
+func TestSampledLogger_level(t *testing.T) {
+	out := &ctxd.LoggerMock{}
+	ctx := context.Background()
+
+	sl := log.NewSampledLogger(log.Sampling{Initial: 2, Tick: time.Hour}, zap.InfoLevel, out)
+
+	for i := 0; i < 10; i++ {
+		sl.Debug(ctx, "disabled")
+		sl.Debug(ctxd.WithDebug(ctx), "elevated")
+	}
+
+	// Disabled messages are passed to next logger without sampling.
+	assert.Equal(t, 10, strings.Count(out.String(), ": disabled "))
+	assert.Equal(t, 2, strings.Count(out.String(), ": elevated "))
+
+	sl.Flush(ctx)
+	assert.Contains(t, out.String(), `important: log messages suppressed {"count":8,"level":"debug","message":"elevated"}`)
+	assert.NotContains(t, out.String(), `"message":"disabled"`)
+}
+
+func TestSampledLogger_reportInterval(t *testing.T) {
+	out := &ctxd.LoggerMock{}
+	ctx := ctxd.AddFields(context.Background(), "request.id", "abc")
+
+	sl := log.NewSampledLogger(log.Sampling{Initial: 1, Tick: time.Hour, ReportInterval: time.Millisecond}, nil, out)
+
+	sl.Error(ctx, "flapping")
+	sl.Error(ctx, "flapping")
+	time.Sleep(2 * time.Millisecond)
+	sl.Error(ctx, "flapping")
+
+	assert.Contains(t, out.String(), `important: log messages suppressed {"count":2,"level":"error","message":"flapping"}`)
+	assert.Contains(t, out.String(), `error: flapping {"request.id":"abc"}`)
+}
@@ -0,0 +1,178 @@
+package log
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bool64/brick/opencensus"
+	"github.com/bool64/brick/runtime"
+	"go.opencensus.io/trace"
+)
+
+// SlowRequests controls detection of slow HTTP requests.
+type SlowRequests struct {
+	// Threshold is the default latency of a slow request, zero value disables detection.
+	Threshold time.Duration
+
+	// RouteThresholds overrides Threshold for routes, keys are "METHOD /pattern" or "/pattern".
+	//
+	// Example: "GET /orders/{id}:3s,/reports:10s".
+	RouteThresholds map[string]time.Duration `split_words:"true"`
+
+	// ForceSample enables tail sampling of traces to export traces of slow requests,
+	// it is only supported with OpenCensus tracing backend.
+	ForceSample bool `split_words:"true"`
+
+	// GoroutineSnapshot enables logging of handling goroutine stack at the moment request became slow.
+	GoroutineSnapshot bool `split_words:"true"`
+
+	// SnapshotInterval limits goroutine snapshots to one per route in the interval, default 1m.
+	// Snapshot briefly stops the world, so it should be rare.
+	SnapshotInterval time.Duration `split_words:"true" default:"1m"`
+}
+
+// Enabled returns true if any threshold is configured.
+func (s SlowRequests) Enabled() bool {
+	if s.Threshold > 0 {
+		return true
+	}
+
+	for _, t := range s.RouteThresholds {
+		if t > 0 {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (s SlowRequests) threshold(method, pattern string) time.Duration {
+	if t, ok := s.RouteThresholds[method+" "+pattern]; ok {
+		return t
+	}
+
+	if t, ok := s.RouteThresholds[pattern]; ok {
+		return t
+	}
+
+	return s.Threshold
+}
+
+// slowWatch acts when request exceeds threshold while still being handled.
+type slowWatch struct {
+	timer *time.Timer
+
+	mu    sync.Mutex
+	stack string
+}
+
+// snapshotLimiter allows one goroutine snapshot per route in the interval.
+type snapshotLimiter struct {
+	interval time.Duration
+
+	mu   sync.Mutex
+	last map[string]time.Time
+}
+
+func newSnapshotLimiter(interval time.Duration) *snapshotLimiter {
+	return &snapshotLimiter{interval: interval, last: make(map[string]time.Time)}
+}
+
+func (l *snapshotLimiter) allow(route string) bool {
+	now := time.Now()
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if last, ok := l.last[route]; ok && now.Sub(last) < l.interval {
+		return false
+	}
+
+	l.last[route] = now
+
+	return true
+}
+
+func (s SlowRequests) watch(threshold time.Duration, route string, reqFields *requestFields, snapshots *snapshotLimiter) *slowWatch {
+	if !s.ForceSample && !s.GoroutineSnapshot {
+		return nil
+	}
+
+	var (
+		w   = &slowWatch{}
+		gid uint64
+	)
+
+	if s.GoroutineSnapshot {
+		gid = runtime.GoroutineID()
+	}
+
+	w.timer = time.AfterFunc(threshold, func() {
+		if s.ForceSample {
+			if id, ok := reqFields.trace(); ok {
+				opencensus.ForceSampleTrace(id)
+			}
+		}
+
+		if gid != 0 && snapshots.allow(route) {
+			st := runtime.GoroutineStack(gid)
+
+			w.mu.Lock()
+			w.stack = st
+			w.mu.Unlock()
+		}
+	})
+
+	return w
+}
+
+func (w *slowWatch) stop() []string {
+	if w == nil {
+		return nil
+	}
+
+	w.timer.Stop()
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.stack == "" {
+		return nil
+	}
+
+	return strings.Split(w.stack, "\n")
+}
+
+// setRequestTrace makes trace ID available to outer middlewares.
+func setRequestTrace(f *requestFields, id trace.TraceID) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.traceID = id
+	f.hasTrace = true
+}
+
+func (f *requestFields) trace() (trace.TraceID, bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return f.traceID, f.hasTrace
+}
+
+// RequestTraceID returns trace ID of current HTTP request.
+//
+// Unlike trace.FromContext, it is available in outer middlewares and in panic hooks of HTTPRecover.
+func RequestTraceID(ctx context.Context) string {
+	f, ok := ctx.Value(requestFieldsCtxKey{}).(*requestFields)
+	if !ok {
+		return ""
+	}
+
+	if id, ok := f.trace(); ok {
+		return id.String()
+	}
+
+	return ""
+}
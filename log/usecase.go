@@ -2,16 +2,50 @@ package log
 
 import (
 	"context"
+	"errors"
 	"strconv"
 
 	"github.com/bool64/ctxd"
+	"github.com/swaggest/rest"
 	"github.com/swaggest/usecase"
+	"github.com/swaggest/usecase/status"
 )
 
-// UsecaseErrors logs use case errors.
-func UsecaseErrors(logger ctxd.Logger) usecase.Middleware {
+// UsecaseErrorLevels maps status code names, for example "NOT_FOUND", to log levels.
+//
+// Levels are "debug", "info", "warn", "error" and "off", codes that are not listed are logged at "error".
+type UsecaseErrorLevels map[string]string
+
+// DefaultUsecaseErrorLevels logs client errors at debug and warn levels.
+func DefaultUsecaseErrorLevels() UsecaseErrorLevels {
+	return UsecaseErrorLevels{
+		status.Canceled.String():           "debug",
+		status.InvalidArgument.String():    "debug",
+		status.NotFound.String():           "debug",
+		status.AlreadyExists.String():      "debug",
+		status.OutOfRange.String():         "debug",
+		status.FailedPrecondition.String(): "warn",
+		status.Aborted.String():            "warn",
+		status.PermissionDenied.String():   "warn",
+		status.Unauthenticated.String():    "warn",
+		status.ResourceExhausted.String():  "warn",
+	}
+}
+
+// UsecaseErrors logs use case errors with level depending on error status.
+//
+// Optional levels override DefaultUsecaseErrorLevels.
+// Messages are attributed to this package by package level overrides of LevelControl.
+func UsecaseErrors(logger ctxd.Logger, levels ...UsecaseErrorLevels) usecase.Middleware {
 	unknownIndex := 0
 
+	lvl := DefaultUsecaseErrorLevels()
+	for _, l := range levels {
+		for code, level := range l {
+			lvl[code] = level
+		}
+	}
+
 	return usecase.MiddlewareFunc(func(u usecase.Interactor) usecase.Interactor {
 		var (
 			withName  usecase.HasName
@@ -36,10 +70,38 @@ func UsecaseErrors(logger ctxd.Logger) usecase.Middleware {
 		return usecase.Interact(func(ctx context.Context, input, output interface{}) error {
 			err := u.Interact(ctx, input, output)
 			if err != nil {
-				logger.Error(ctx, "usecase failed", "error", err, "name", name)
+				logUsecaseError(ctx, logger, lvl, name, err)
 			}
 
 			return err
 		})
 	})
 }
+
+func logUsecaseError(ctx context.Context, logger ctxd.Logger, levels UsecaseErrorLevels, name string, err error) {
+	st := status.Unknown
+
+	var withStatus rest.ErrWithCanonicalStatus
+	if errors.As(err, &withStatus) {
+		st = withStatus.Status()
+	}
+
+	kv := []interface{}{"error", err, "name", name, "status", st.String()}
+
+	var se ctxd.StructuredError
+	if errors.As(err, &se) {
+		kv = append(kv, se.Tuples()...)
+	}
+
+	switch levels[st.String()] {
+	case "off":
+	case "debug":
+		logger.Debug(ctx, "usecase failed", kv...)
+	case "info":
+		logger.Info(ctx, "usecase failed", kv...)
+	case "warn":
+		logger.Warn(ctx, "usecase failed", kv...)
+	default:
+		logger.Error(ctx, "usecase failed", kv...)
+	}
+}
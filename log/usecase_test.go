@@ -0,0 +1,41 @@
+package log_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/bool64/brick/log"
+	"github.com/bool64/ctxd"
+	"github.com/stretchr/testify/assert"
+	"github.com/swaggest/usecase"
+	"github.com/swaggest/usecase/status"
+)
+
+func TestUsecaseErrors(t *testing.T) {
+	logger := &ctxd.LoggerMock{}
+
+	u := usecase.NewInteractor(func(_ context.Context, in error, _ *struct{}) error {
+		return in
+	})
+	u.SetName("test")
+
+	for _, levels := range []log.UsecaseErrorLevels{nil, {"NOT_FOUND": "off", "ABORTED": "error"}} {
+		uc := usecase.Wrap(u, log.UsecaseErrors(logger, levels))
+
+		for _, err := range []error{
+			status.NotFound,
+			status.Wrap(errors.New("conflict"), status.Aborted),
+			ctxd.NewError(context.Background(), "failed", "order.id", 123),
+		} {
+			assert.Error(t, uc.Interact(context.Background(), err, &struct{}{}))
+		}
+	}
+
+	assert.Equal(t, `debug: usecase failed {"error":5,"name":"test","status":"NOT_FOUND"}
+warn: usecase failed {"error":{},"name":"test","status":"ABORTED"}
+error: usecase failed {"error":"failed","name":"test","order.id":123,"status":"UNKNOWN"}
+error: usecase failed {"error":{},"name":"test","status":"ABORTED"}
+error: usecase failed {"error":"failed","name":"test","order.id":123,"status":"UNKNOWN"}
+`, logger.String())
+}
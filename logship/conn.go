@@ -0,0 +1,73 @@
+package logship
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"time"
+)
+
+var errUnsupportedNetwork = errors.New("unsupported network, expected udp or tcp")
+
+// parseAddr splits address like "udp://host:port" into network and host.
+func parseAddr(addr string) (network, host string, err error) {
+	u, err := url.Parse(addr)
+	if err != nil {
+		return "", "", err
+	}
+
+	switch u.Scheme {
+	case "udp", "tcp":
+	default:
+		return "", "", fmt.Errorf("%w: %s", errUnsupportedNetwork, addr)
+	}
+
+	return u.Scheme, u.Host, nil
+}
+
+// conn is a lazily dialed connection that is reset on write failure.
+type conn struct {
+	network string
+	addr    string
+	c       net.Conn
+}
+
+func (c *conn) write(ctx context.Context, b []byte) error {
+	if c.c == nil {
+		var d net.Dialer
+
+		nc, err := d.DialContext(ctx, c.network, c.addr)
+		if err != nil {
+			return err
+		}
+
+		c.c = nc
+	}
+
+	if dl, ok := ctx.Deadline(); ok {
+		_ = c.c.SetWriteDeadline(dl) //nolint:errcheck // Write fails on broken connection anyway.
+	} else {
+		_ = c.c.SetWriteDeadline(time.Time{}) //nolint:errcheck // Write fails on broken connection anyway.
+	}
+
+	if _, err := c.c.Write(b); err != nil {
+		_ = c.close() //nolint:errcheck // Connection is discarded.
+
+		return err
+	}
+
+	return nil
+}
+
+func (c *conn) close() error {
+	if c.c == nil {
+		return nil
+	}
+
+	err := c.c.Close()
+	c.c = nil
+
+	return err
+}
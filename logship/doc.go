@@ -0,0 +1,2 @@
+// Package logship sends log lines to remote collectors.
+package logship
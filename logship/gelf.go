@@ -0,0 +1,211 @@
+package logship
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	gelfChunkSize = 8192
+	gelfMaxChunks = 128
+)
+
+var errMessageTooLarge = errors.New("message is too large")
+
+// GELF sends messages to Graylog-compatible collector.
+//
+// Messages are sent as chunked datagrams over UDP or as null-terminated frames over TCP.
+type GELF struct {
+	// Host identifies the source of messages, default hostname.
+	Host string
+
+	mu   sync.Mutex
+	conn conn
+}
+
+// NewGELF creates GELF sink from address like "udp://localhost:12201".
+func NewGELF(addr string) (*GELF, error) {
+	network, host, err := parseAddr(addr)
+	if err != nil {
+		return nil, err
+	}
+
+	g := &GELF{conn: conn{network: network, addr: host}}
+	g.Host, _ = os.Hostname() //nolint:errcheck // Host is optional.
+
+	return g, nil
+}
+
+// Send implements Sink.
+func (g *GELF) Send(ctx context.Context, lines [][]byte) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	for _, line := range lines {
+		m, err := json.Marshal(gelfMessage(g.Host, line))
+		if err != nil {
+			return err
+		}
+
+		if g.conn.network == "tcp" {
+			err = g.conn.write(ctx, append(m, 0))
+		} else {
+			err = g.writeChunked(ctx, m)
+		}
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Close closes connection.
+func (g *GELF) Close() error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	return g.conn.close()
+}
+
+func (g *GELF) writeChunked(ctx context.Context, m []byte) error {
+	if len(m) <= gelfChunkSize {
+		return g.conn.write(ctx, m)
+	}
+
+	const (
+		headerSize = 12
+		dataSize   = gelfChunkSize - headerSize
+	)
+
+	count := (len(m) + dataSize - 1) / dataSize
+	if count > gelfMaxChunks {
+		return errMessageTooLarge
+	}
+
+	header := make([]byte, headerSize)
+	header[0], header[1] = 0x1e, 0x0f
+
+	if _, err := rand.Read(header[2:10]); err != nil {
+		return err
+	}
+
+	header[11] = byte(count)
+
+	for i := 0; i < count; i++ {
+		header[10] = byte(i)
+
+		chunk := m[i*dataSize : min(len(m), (i+1)*dataSize)]
+
+		if err := g.conn.write(ctx, append(header[:headerSize:headerSize], chunk...)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// gelfMessage converts JSON log line to GELF 1.1 message, line is used as short message if it is not JSON.
+func gelfMessage(host string, line []byte) map[string]interface{} {
+	m := map[string]interface{}{
+		"version": "1.1",
+		"host":    host,
+	}
+
+	fields := map[string]interface{}{}
+
+	d := json.NewDecoder(bytes.NewReader(line))
+	d.UseNumber()
+
+	if err := d.Decode(&fields); err != nil {
+		m["short_message"] = string(line)
+		m["timestamp"] = unixTime(time.Now())
+
+		return m
+	}
+
+	m["short_message"], m["level"], m["timestamp"] = lineMeta(fields)
+
+	for k, v := range fields {
+		switch v := v.(type) {
+		case string, json.Number:
+			m["_"+gelfFieldName(k)] = v
+		default:
+			j, err := json.Marshal(v)
+			if err == nil {
+				m["_"+gelfFieldName(k)] = string(j)
+			}
+		}
+	}
+
+	return m
+}
+
+// gelfFieldName replaces characters that are not allowed in GELF field names.
+func gelfFieldName(k string) string {
+	if k == "id" {
+		return "id_"
+	}
+
+	return strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '_' || r == '.' || r == '-' {
+			return r
+		}
+
+		return '_'
+	}, k)
+}
+
+// lineMeta removes message, level and time from fields of zap JSON log line
+// and returns message, syslog severity and unix timestamp.
+func lineMeta(fields map[string]interface{}) (msg string, severity int, ts float64) {
+	msg, _ = fields["msg"].(string)
+	delete(fields, "msg")
+
+	lvl, _ := fields["level"].(string)
+	delete(fields, "level")
+
+	severity = syslogSeverity(lvl)
+
+	t := time.Now()
+
+	if s, ok := fields["time"].(string); ok {
+		if pt, err := time.Parse("2006-01-02T15:04:05.000Z0700", s); err == nil {
+			t = pt
+
+			delete(fields, "time")
+		}
+	}
+
+	return msg, severity, unixTime(t)
+}
+
+func unixTime(t time.Time) float64 {
+	return float64(t.UnixMicro()) / 1e6
+}
+
+// syslogSeverity converts zap level to syslog severity.
+func syslogSeverity(level string) int {
+	switch level {
+	case "debug":
+		return 7
+	case "info":
+		return 6
+	case "warn":
+		return 4
+	case "error":
+		return 3
+	case "dpanic", "panic", "fatal":
+		return 2
+	default:
+		return 5
+	}
+}
@@ -0,0 +1,57 @@
+package logship
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+var errUnexpectedStatus = errors.New("unexpected response status")
+
+// HTTP posts batches of log lines as newline-delimited JSON.
+type HTTP struct {
+	Client *http.Client
+
+	url string
+}
+
+// NewHTTP creates HTTP sink.
+func NewHTTP(url string) *HTTP {
+	return &HTTP{
+		Client: http.DefaultClient,
+		url:    url,
+	}
+}
+
+// Send implements Sink.
+func (h *HTTP) Send(ctx context.Context, lines [][]byte) error {
+	body := bytes.Join(lines, []byte("\n"))
+	body = append(body, '\n')
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, h.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Content-Type", "application/x-ndjson")
+
+	resp, err := h.Client.Do(req)
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+
+	_, _ = io.Copy(io.Discard, resp.Body) //nolint:errcheck // Response body is drained for connection reuse.
+
+	if resp.StatusCode >= http.StatusMultipleChoices {
+		return fmt.Errorf("%w: %d", errUnexpectedStatus, resp.StatusCode)
+	}
+
+	return nil
+}
@@ -0,0 +1,371 @@
+package logship
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+// Drop policies.
+const (
+	DropNewest = "newest"
+	DropOldest = "oldest"
+)
+
+var errUnknownDropPolicy = errors.New("unknown drop policy, expected newest or oldest")
+
+// Sink delivers a batch of log lines.
+type Sink interface {
+	Send(ctx context.Context, lines [][]byte) error
+}
+
+// Config controls log shipping.
+type Config struct {
+	// GELF enables sending to Graylog-compatible collector, for example "udp://localhost:12201" or "tcp://localhost:12201".
+	GELF string
+
+	// HTTP enables posting batches of JSON lines to URL.
+	HTTP string
+
+	// Syslog enables sending RFC 5424 messages to syslog collector, for example "udp://localhost:514".
+	Syslog string
+
+	// BufferSize limits number of lines waiting for delivery per sink.
+	BufferSize int `split_words:"true" default:"10000"`
+
+	// BatchSize is the max number of lines sent at once.
+	BatchSize int `split_words:"true" default:"100"`
+
+	// FlushInterval is the max time a line waits for a batch to fill.
+	FlushInterval time.Duration `split_words:"true" default:"1s"`
+
+	// DropPolicy selects lines to drop when buffer is full, "newest" drops incoming lines, "oldest" drops buffered ones.
+	DropPolicy string `split_words:"true" default:"newest"`
+
+	// Timeout limits delivery of a batch.
+	Timeout time.Duration `default:"5s"`
+}
+
+// Enabled returns true if any sink is configured.
+func (c Config) Enabled() bool {
+	return c.GELF != "" || c.HTTP != "" || c.Syslog != ""
+}
+
+func (c *Config) setDefaults() error {
+	if c.BufferSize <= 0 {
+		c.BufferSize = 10000
+	}
+
+	if c.BatchSize <= 0 {
+		c.BatchSize = 100
+	}
+
+	if c.FlushInterval <= 0 {
+		c.FlushInterval = time.Second
+	}
+
+	if c.Timeout <= 0 {
+		c.Timeout = 5 * time.Second
+	}
+
+	switch c.DropPolicy {
+	case "":
+		c.DropPolicy = DropNewest
+	case DropNewest, DropOldest:
+	default:
+		return fmt.Errorf("%w: %s", errUnknownDropPolicy, c.DropPolicy)
+	}
+
+	return nil
+}
+
+// Shipper buffers log lines and sends them to a sink in batches.
+//
+// Write never blocks, lines are dropped according to drop policy when buffer is full.
+type Shipper struct {
+	// OnError receives delivery errors, errors are printed to stderr by default.
+	OnError func(err error)
+
+	cfg  Config
+	sink Sink
+
+	mu      sync.Mutex
+	buf     [][]byte
+	dropped uint64
+
+	// ctx is canceled when context of Close is done to abort delivery.
+	ctx    context.Context //nolint:containedctx // Context is shared with delivery goroutine.
+	cancel func()
+
+	wake     chan struct{}
+	flush    chan chan struct{}
+	stop     chan struct{}
+	stopped  chan struct{}
+	stopOnce sync.Once
+}
+
+// NewShipper creates shipper and starts delivery.
+func NewShipper(sink Sink, cfg Config) (*Shipper, error) {
+	if err := cfg.setDefaults(); err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	s := &Shipper{
+		ctx:     ctx,
+		cancel:  cancel,
+		cfg:     cfg,
+		sink:    sink,
+		wake:    make(chan struct{}, 1),
+		flush:   make(chan chan struct{}),
+		stop:    make(chan struct{}),
+		stopped: make(chan struct{}),
+	}
+
+	go s.run()
+
+	return s, nil
+}
+
+// Write implements io.Writer, each line of p is shipped as a separate message.
+func (s *Shipper) Write(p []byte) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, line := range bytes.Split(p, []byte("\n")) {
+		if len(line) == 0 {
+			continue
+		}
+
+		if len(s.buf) >= s.cfg.BufferSize {
+			s.dropped++
+
+			if s.cfg.DropPolicy == DropNewest {
+				continue
+			}
+
+			s.buf = s.buf[1:]
+		}
+
+		s.buf = append(s.buf, append([]byte(nil), line...))
+	}
+
+	if len(s.buf) >= s.cfg.BatchSize {
+		select {
+		case s.wake <- struct{}{}:
+		default:
+		}
+	}
+
+	return len(p), nil
+}
+
+// Dropped returns number of lines dropped due to full buffer or failed delivery.
+func (s *Shipper) Dropped() uint64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.dropped
+}
+
+// Flush sends buffered lines and waits for delivery.
+func (s *Shipper) Flush(ctx context.Context) error {
+	done := make(chan struct{})
+
+	select {
+	case s.flush <- done:
+	case <-s.stopped:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Close sends buffered lines, stops delivery and closes sink.
+//
+// Lines that are not delivered before context is done are dropped.
+func (s *Shipper) Close(ctx context.Context) error {
+	stop := context.AfterFunc(ctx, s.cancel)
+	defer stop()
+
+	s.stopOnce.Do(func() {
+		close(s.stop)
+	})
+
+	<-s.stopped
+	s.cancel()
+
+	if c, ok := s.sink.(io.Closer); ok {
+		return c.Close()
+	}
+
+	return nil
+}
+
+func (s *Shipper) run() {
+	t := time.NewTicker(s.cfg.FlushInterval)
+
+	defer func() {
+		t.Stop()
+		close(s.stopped)
+	}()
+
+	for {
+		select {
+		case <-s.wake:
+			s.send(false)
+		case <-t.C:
+			s.send(true)
+		case done := <-s.flush:
+			s.send(true)
+			close(done)
+		case <-s.stop:
+			s.send(true)
+
+			return
+		}
+	}
+}
+
+// send delivers buffered lines, incomplete batch is only sent if all is true.
+//
+// Remaining lines are dropped when delivery is aborted by Close.
+func (s *Shipper) send(all bool) {
+	for {
+		s.mu.Lock()
+
+		if s.ctx.Err() != nil {
+			s.dropped += uint64(len(s.buf))
+			s.buf = nil
+			s.mu.Unlock()
+
+			return
+		}
+
+		n := min(len(s.buf), s.cfg.BatchSize)
+		if n == 0 || (!all && n < s.cfg.BatchSize) {
+			s.mu.Unlock()
+
+			return
+		}
+
+		batch := s.buf[:n:n]
+		s.buf = s.buf[n:]
+
+		s.mu.Unlock()
+
+		ctx, cancel := context.WithTimeout(s.ctx, s.cfg.Timeout)
+		err := s.sink.Send(ctx, batch)
+
+		cancel()
+
+		if err != nil {
+			s.mu.Lock()
+			s.dropped += uint64(n)
+			s.mu.Unlock()
+
+			s.failed(err)
+		}
+	}
+}
+
+func (s *Shipper) failed(err error) {
+	if s.OnError != nil {
+		s.OnError(err)
+
+		return
+	}
+
+	_, _ = fmt.Fprintf(os.Stderr, "logship: failed to send logs: %v\n", err)
+}
+
+// Shippers sends log lines to multiple sinks.
+type Shippers []*Shipper
+
+// New creates shippers for configured sinks.
+func New(cfg Config) (Shippers, error) {
+	if err := cfg.setDefaults(); err != nil {
+		return nil, err
+	}
+
+	var sinks []Sink
+
+	if cfg.GELF != "" {
+		s, err := NewGELF(cfg.GELF)
+		if err != nil {
+			return nil, err
+		}
+
+		sinks = append(sinks, s)
+	}
+
+	if cfg.HTTP != "" {
+		sinks = append(sinks, NewHTTP(cfg.HTTP))
+	}
+
+	if cfg.Syslog != "" {
+		s, err := NewSyslog(cfg.Syslog)
+		if err != nil {
+			return nil, err
+		}
+
+		sinks = append(sinks, s)
+	}
+
+	res := make(Shippers, 0, len(sinks))
+
+	for _, sink := range sinks {
+		s, err := NewShipper(sink, cfg)
+		if err != nil {
+			return nil, err
+		}
+
+		res = append(res, s)
+	}
+
+	return res, nil
+}
+
+// Write implements io.Writer.
+func (ss Shippers) Write(p []byte) (int, error) {
+	for _, s := range ss {
+		_, _ = s.Write(p) //nolint:errcheck // Shipper does not fail writes.
+	}
+
+	return len(p), nil
+}
+
+// Flush sends buffered lines of all shippers.
+func (ss Shippers) Flush(ctx context.Context) error {
+	var errs []error
+
+	for _, s := range ss {
+		errs = append(errs, s.Flush(ctx))
+	}
+
+	return errors.Join(errs...)
+}
+
+// Close closes all shippers, lines that are not delivered before context is done are dropped.
+func (ss Shippers) Close(ctx context.Context) error {
+	var errs []error
+
+	for _, s := range ss {
+		errs = append(errs, s.Close(ctx))
+	}
+
+	return errors.Join(errs...)
+}
@@ -0,0 +1,279 @@
+package logship_test
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/bool64/brick/logship"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const line = `{"level":"error","time":"2024-01-02T03:04:05.678Z","msg":"failed","id":123,"ctx":{"a":1}}`
+
+func TestNew_http(t *testing.T) {
+	var (
+		mu     sync.Mutex
+		bodies []string
+	)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(_ http.ResponseWriter, r *http.Request) {
+		b, err := io.ReadAll(r.Body)
+		assert.NoError(t, err)
+		assert.Equal(t, "application/x-ndjson", r.Header.Get("Content-Type"))
+
+		mu.Lock()
+		defer mu.Unlock()
+
+		bodies = append(bodies, string(b))
+	}))
+	defer srv.Close()
+
+	s, err := logship.New(logship.Config{HTTP: srv.URL, BatchSize: 2, FlushInterval: time.Hour})
+	require.NoError(t, err)
+
+	_, err = s.Write([]byte("{\"a\":1}\n"))
+	require.NoError(t, err)
+
+	_, err = s.Write([]byte("{\"a\":2}\n{\"a\":3}\n"))
+	require.NoError(t, err)
+
+	require.NoError(t, s.Flush(context.Background()))
+	require.NoError(t, s.Close(context.Background()))
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	assert.Equal(t, []string{"{\"a\":1}\n{\"a\":2}\n", "{\"a\":3}\n"}, bodies)
+}
+
+func TestNew_gelfUDP(t *testing.T) {
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	defer func() {
+		require.NoError(t, pc.Close())
+	}()
+
+	s, err := logship.New(logship.Config{GELF: "udp://" + pc.LocalAddr().String()})
+	require.NoError(t, err)
+
+	_, err = s.Write([]byte(line + "\n"))
+	require.NoError(t, err)
+
+	_, err = s.Write([]byte(`{"level":"info","msg":"big","data":"` + strings.Repeat("a", 10000) + `"}`))
+	require.NoError(t, err)
+
+	require.NoError(t, s.Flush(context.Background()))
+
+	buf := make([]byte, 10000)
+
+	require.NoError(t, pc.SetReadDeadline(time.Now().Add(time.Second)))
+	n, _, err := pc.ReadFrom(buf)
+	require.NoError(t, err)
+
+	var m map[string]interface{}
+
+	require.NoError(t, json.Unmarshal(buf[:n], &m))
+	assert.Equal(t, "1.1", m["version"])
+	assert.Equal(t, "failed", m["short_message"])
+	assert.Equal(t, 3.0, m["level"])
+	assert.Equal(t, 1704164645.678, m["timestamp"])
+	assert.Equal(t, 123.0, m["_id_"])
+	assert.Equal(t, `{"a":1}`, m["_ctx"])
+	assert.NotContains(t, m, "_msg")
+
+	var chunks [][]byte
+
+	for i := 0; i < 2; i++ {
+		n, _, err := pc.ReadFrom(buf)
+		require.NoError(t, err)
+		require.Equal(t, []byte{0x1e, 0x0f}, buf[:2])
+		assert.Equal(t, byte(i), buf[10])
+		assert.Equal(t, byte(2), buf[11])
+
+		chunks = append(chunks, append([]byte(nil), buf[12:n]...))
+	}
+
+	require.NoError(t, json.Unmarshal(append(chunks[0], chunks[1]...), &m))
+	assert.Equal(t, "big", m["short_message"])
+	assert.Len(t, m["_data"], 10000)
+
+	require.NoError(t, s.Close(context.Background()))
+}
+
+func TestNew_syslogTCP(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	defer func() {
+		require.NoError(t, ln.Close())
+	}()
+
+	received := make(chan string, 1)
+
+	go func() {
+		c, err := ln.Accept()
+		if !assert.NoError(t, err) {
+			return
+		}
+
+		r := bufio.NewReader(c)
+
+		size, err := r.ReadString(' ')
+		assert.NoError(t, err)
+
+		n, err := strconv.Atoi(strings.TrimSpace(size))
+		assert.NoError(t, err)
+
+		b := make([]byte, n)
+		_, err = io.ReadFull(r, b)
+		assert.NoError(t, err)
+
+		received <- string(b)
+	}()
+
+	s, err := logship.New(logship.Config{Syslog: "tcp://" + ln.Addr().String()})
+	require.NoError(t, err)
+
+	syslog := s[0]
+
+	_, err = syslog.Write([]byte(line))
+	require.NoError(t, err)
+	require.NoError(t, syslog.Close(context.Background()))
+
+	m := <-received
+	assert.True(t, strings.HasPrefix(m, "<11>1 "), m)
+	assert.True(t, strings.HasSuffix(m, " - - "+line), m)
+}
+
+type blockingSink struct {
+	mu      sync.Mutex
+	sending chan struct{}
+	release chan struct{}
+	lines   []string
+}
+
+func (s *blockingSink) Send(_ context.Context, lines [][]byte) error {
+	select {
+	case s.sending <- struct{}{}:
+	default:
+	}
+
+	<-s.release
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, l := range lines {
+		s.lines = append(s.lines, string(l))
+	}
+
+	return nil
+}
+
+func TestShipper_Write_drop(t *testing.T) {
+	for policy, expected := range map[string][]string{
+		logship.DropNewest: {"0", "1", "2", "3"},
+		logship.DropOldest: {"0", "3", "4", "5"},
+	} {
+		t.Run(policy, func(t *testing.T) {
+			sink := &blockingSink{sending: make(chan struct{}, 1), release: make(chan struct{})}
+
+			s, err := logship.NewShipper(sink, logship.Config{BufferSize: 3, BatchSize: 1, DropPolicy: policy})
+			require.NoError(t, err)
+
+			_, err = s.Write([]byte("0"))
+			require.NoError(t, err)
+
+			// The first line is taken by blocked sink.
+			<-sink.sending
+
+			for _, l := range []string{"1", "2", "3", "4", "5"} {
+				_, err = s.Write([]byte(l))
+				require.NoError(t, err)
+			}
+
+			assert.Equal(t, uint64(2), s.Dropped())
+
+			close(sink.release)
+			require.NoError(t, s.Close(context.Background()))
+
+			assert.Equal(t, expected, sink.lines)
+		})
+	}
+}
+
+type failingSink struct{}
+
+func (failingSink) Send(_ context.Context, _ [][]byte) error {
+	return errors.New("failed")
+}
+
+func TestShipper_Dropped_failed(t *testing.T) {
+	var failed []string
+
+	s, err := logship.NewShipper(failingSink{}, logship.Config{BatchSize: 2, FlushInterval: time.Hour})
+	require.NoError(t, err)
+
+	s.OnError = func(err error) { failed = append(failed, err.Error()) }
+
+	_, err = s.Write([]byte("0\n1\n2"))
+	require.NoError(t, err)
+	require.NoError(t, s.Close(context.Background()))
+
+	assert.Equal(t, uint64(3), s.Dropped())
+	assert.Equal(t, []string{"failed", "failed"}, failed)
+}
+
+type hangingSink struct {
+	calls int
+}
+
+func (s *hangingSink) Send(ctx context.Context, _ [][]byte) error {
+	s.calls++
+	<-ctx.Done()
+
+	return ctx.Err()
+}
+
+func TestShipper_Close_deadline(t *testing.T) {
+	sink := &hangingSink{}
+
+	s, err := logship.NewShipper(sink, logship.Config{BatchSize: 1, FlushInterval: time.Hour, Timeout: time.Minute})
+	require.NoError(t, err)
+
+	s.OnError = func(error) {}
+
+	_, err = s.Write([]byte("0\n1\n2\n3"))
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+
+	require.NoError(t, s.Close(ctx))
+	assert.Less(t, time.Since(start), time.Second)
+	assert.Equal(t, uint64(4), s.Dropped())
+	assert.LessOrEqual(t, sink.calls, 2)
+}
+
+func TestNew_invalid(t *testing.T) {
+	_, err := logship.New(logship.Config{GELF: "http://localhost"})
+	assert.EqualError(t, err, "unsupported network, expected udp or tcp: http://localhost")
+
+	_, err = logship.New(logship.Config{HTTP: "http://localhost", DropPolicy: "random"})
+	assert.EqualError(t, err, "unknown drop policy, expected newest or oldest: random")
+}
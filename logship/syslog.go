@@ -0,0 +1,103 @@
+package logship
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// syslogFacility is the "user-level messages" facility.
+const syslogFacility = 1
+
+// Syslog sends RFC 5424 messages with JSON log line as message body.
+//
+// Messages are sent as datagrams over UDP or with octet counting framing over TCP.
+type Syslog struct {
+	// Host identifies the source of messages, default hostname.
+	Host string
+
+	// AppName identifies the application, default executable name.
+	AppName string
+
+	mu   sync.Mutex
+	conn conn
+	pid  string
+}
+
+// NewSyslog creates syslog sink from address like "udp://localhost:514".
+func NewSyslog(addr string) (*Syslog, error) {
+	network, host, err := parseAddr(addr)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &Syslog{
+		AppName: filepath.Base(os.Args[0]),
+		conn:    conn{network: network, addr: host},
+		pid:     strconv.Itoa(os.Getpid()),
+	}
+	s.Host, _ = os.Hostname() //nolint:errcheck // Host is optional.
+
+	return s, nil
+}
+
+// Send implements Sink.
+func (s *Syslog) Send(ctx context.Context, lines [][]byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, line := range lines {
+		m := s.message(line)
+
+		if s.conn.network == "tcp" {
+			m = append([]byte(strconv.Itoa(len(m))+" "), m...)
+		}
+
+		if err := s.conn.write(ctx, m); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Close closes connection.
+func (s *Syslog) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.conn.close()
+}
+
+func (s *Syslog) message(line []byte) []byte {
+	var fields struct {
+		Level string `json:"level"`
+	}
+
+	severity := 5
+	if err := json.Unmarshal(line, &fields); err == nil {
+		severity = syslogSeverity(fields.Level)
+	}
+
+	var b bytes.Buffer
+
+	b.WriteString("<" + strconv.Itoa(syslogFacility*8+severity) + ">1 ")
+	b.WriteString(time.Now().UTC().Format(time.RFC3339Nano) + " ")
+	b.WriteString(nilValue(s.Host) + " " + nilValue(s.AppName) + " " + s.pid + " - - ")
+	b.Write(line)
+
+	return b.Bytes()
+}
+
+func nilValue(s string) string {
+	if s == "" {
+		return "-"
+	}
+
+	return s
+}
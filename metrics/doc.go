@@ -0,0 +1,2 @@
+// Package metrics provides native Prometheus metrics of HTTP server.
+package metrics
@@ -0,0 +1,162 @@
+package metrics
+
+import (
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Route labels of requests that are not identified by route pattern.
+const (
+	RouteUnmatched = "unmatched"
+	RouteOther     = "other"
+)
+
+// Config describes RED (rate, errors, duration) metrics of HTTP requests.
+type Config struct {
+	// Enabled enables metrics of HTTP requests.
+	Enabled bool `default:"true"`
+
+	// Buckets are upper bounds of request duration histogram in seconds.
+	Buckets []float64 `default:"0.005,0.01,0.025,0.05,0.1,0.25,0.5,1,2.5,5,10"`
+
+	// MaxRoutes limits the number of distinct route labels, requests of further routes are labeled as "other".
+	MaxRoutes int `split_words:"true" default:"500"`
+
+	// OpenCensusViews enables legacy HTTP metrics of OpenCensus views bridged to Prometheus,
+	// for example http_server_latency_by_path.
+	// Legacy metrics are enabled by default to migrate dashboards and alerts,
+	// they will be disabled by default in a future release.
+	OpenCensusViews bool `split_words:"true" default:"true"`
+}
+
+// Middleware creates router middleware that collects metrics of HTTP requests.
+//
+// Requests are counted with "http_server_requests_total" and measured with "http_server_request_duration_seconds",
+// both labeled by method, route pattern and status class, for example "GET", "/users/{id}" and "2xx".
+// Requests that do not match any route are labeled with "unmatched" route.
+//
+// Middleware should be used on chi router (for example with web.Service.Use), route pattern is taken from
+// chi.RouteContext after request is handled.
+func Middleware(cfg Config, reg prometheus.Registerer) (func(http.Handler) http.Handler, error) {
+	if !cfg.Enabled {
+		return func(next http.Handler) http.Handler { return next }, nil
+	}
+
+	buckets := cfg.Buckets
+	if len(buckets) == 0 {
+		buckets = prometheus.DefBuckets
+	}
+
+	labels := []string{"method", "route", "status_class"}
+
+	requests := prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "http_server_requests_total",
+		Help: "Number of handled HTTP requests.",
+	}, labels)
+
+	duration := prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "http_server_request_duration_seconds",
+		Help:    "Duration of handled HTTP requests.",
+		Buckets: buckets,
+	}, labels)
+
+	if err := reg.Register(requests); err != nil {
+		return nil, err
+	}
+
+	if err := reg.Register(duration); err != nil {
+		reg.Unregister(requests)
+
+		return nil, err
+	}
+
+	g := routeGuard{max: cfg.MaxRoutes, routes: make(map[string]bool)}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+			ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+			completed := false
+
+			// Deferred to count requests that panic through middleware with 500 status.
+			defer func() {
+				st := ww.Status()
+
+				switch {
+				case !completed:
+					st = http.StatusInternalServerError
+				case st == 0:
+					st = http.StatusOK
+				}
+
+				route := RouteUnmatched
+				if rctx := chi.RouteContext(r.Context()); rctx != nil && rctx.RoutePattern() != "" {
+					route = g.label(rctx.RoutePattern())
+				}
+
+				values := []string{methodLabel(r.Method), route, strconv.Itoa(st/100) + "xx"}
+
+				requests.WithLabelValues(values...).Inc()
+				duration.WithLabelValues(values...).Observe(time.Since(start).Seconds())
+			}()
+
+			next.ServeHTTP(ww, r)
+
+			completed = true
+		})
+	}, nil
+}
+
+// routeGuard limits cardinality of route labels.
+type routeGuard struct {
+	max int
+
+	mu     sync.RWMutex
+	routes map[string]bool
+}
+
+func (g *routeGuard) label(route string) string {
+	if g.max <= 0 {
+		return route
+	}
+
+	g.mu.RLock()
+	known := g.routes[route]
+	g.mu.RUnlock()
+
+	if known {
+		return route
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if g.routes[route] {
+		return route
+	}
+
+	if len(g.routes) >= g.max {
+		return RouteOther
+	}
+
+	g.routes[route] = true
+
+	return route
+}
+
+// methodLabel limits cardinality of method label with arbitrary methods of clients.
+func methodLabel(method string) string {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut, http.MethodPatch,
+		http.MethodDelete, http.MethodConnect, http.MethodOptions, http.MethodTrace:
+		return method
+	default:
+		return "OTHER"
+	}
+}
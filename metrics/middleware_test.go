@@ -0,0 +1,82 @@
+package metrics_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/bool64/brick/metrics"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/swaggest/openapi-go/openapi3"
+	"github.com/swaggest/rest/web"
+	"github.com/swaggest/usecase"
+	"github.com/swaggest/usecase/status"
+)
+
+func TestMiddleware(t *testing.T) {
+	reg := prometheus.NewRegistry()
+
+	mw, err := metrics.Middleware(metrics.Config{Enabled: true, Buckets: []float64{1}, MaxRoutes: 2}, reg)
+	require.NoError(t, err)
+
+	_, err = metrics.Middleware(metrics.Config{Enabled: true}, reg)
+	require.Error(t, err, "metrics are already registered")
+
+	r := web.NewService(openapi3.NewReflector())
+	r.Use(mw)
+
+	type req struct {
+		ID int `path:"id"`
+	}
+
+	r.Get("/items/{id}", usecase.NewInteractor(func(_ context.Context, in req, _ *struct{}) error {
+		if in.ID == 0 {
+			return status.NotFound
+		}
+
+		return nil
+	}))
+	r.Get("/panic", usecase.NewInteractor(func(_ context.Context, _ struct{}, _ *struct{}) error {
+		panic("failed")
+	}))
+	r.Get("/users", usecase.NewInteractor(func(_ context.Context, _ struct{}, _ *struct{}) error {
+		return nil
+	}))
+
+	for _, u := range []string{"/items/1", "/items/2", "/items/0", "/panic", "/users", "/missing/1", "/missing/2"} {
+		r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, u, nil))
+	}
+
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("PURGE", "/items/1", nil))
+
+	rw := httptest.NewRecorder()
+	promhttp.HandlerFor(reg, promhttp.HandlerOpts{}).ServeHTTP(rw, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+	m := rw.Body.String()
+
+	assert.Contains(t, m, `http_server_requests_total{method="GET",route="/items/{id}",status_class="2xx"} 2`)
+	assert.Contains(t, m, `http_server_requests_total{method="GET",route="/items/{id}",status_class="4xx"} 1`)
+	assert.Contains(t, m, `http_server_requests_total{method="GET",route="/panic",status_class="5xx"} 1`)
+	assert.Contains(t, m, `http_server_requests_total{method="GET",route="other",status_class="2xx"} 1`)
+	assert.Contains(t, m, `http_server_requests_total{method="GET",route="unmatched",status_class="4xx"} 2`)
+	assert.Contains(t, m, `http_server_requests_total{method="OTHER",route="unmatched",status_class="4xx"} 1`)
+	assert.Contains(t, m, `http_server_request_duration_seconds_bucket{method="GET",route="/items/{id}",status_class="2xx",le="1"} 2`)
+}
+
+func TestMiddleware_disabled(t *testing.T) {
+	reg := prometheus.NewRegistry()
+
+	mw, err := metrics.Middleware(metrics.Config{}, reg)
+	require.NoError(t, err)
+
+	h := http.HandlerFunc(func(_ http.ResponseWriter, _ *http.Request) {})
+	mw(h).ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+
+	mf, err := reg.Gather()
+	require.NoError(t, err)
+	assert.Empty(t, mf)
+}
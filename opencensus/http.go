@@ -2,29 +2,99 @@ package opencensus
 
 import (
 	"net/http"
+	"strconv"
+	"time"
 
+	"github.com/go-chi/chi/v5/middleware"
 	"github.com/swaggest/rest"
 	"github.com/swaggest/rest/nethttp"
 	"go.opencensus.io/plugin/ochttp"
+	"go.opencensus.io/stats"
+	"go.opencensus.io/tag"
+	"go.opencensus.io/trace/propagation"
 )
 
 // Middleware instruments router with OpenCensus metrics.
 func Middleware(handler http.Handler) http.Handler {
-	var withRoute rest.HandlerWithRoute
+	return PropagationMiddleware(nil)(handler)
+}
 
-	if nethttp.HandlerAs(handler, &withRoute) {
-		method := withRoute.RouteMethod()
-		pattern := withRoute.RoutePattern()
-
-		return ochttp.WithRouteTag(&ochttp.Handler{
-			FormatSpanName: func(_ *http.Request) string {
-				return method + " " + pattern
-			},
-			Handler: handler,
-		}, method+pattern)
+// PropagationMiddleware instruments router with OpenCensus metrics and extracts
+// remote span context of incoming requests with format, B3 is used if format is nil.
+func PropagationMiddleware(format propagation.HTTPFormat) func(handler http.Handler) http.Handler {
+	return func(handler http.Handler) http.Handler {
+		var withRoute rest.HandlerWithRoute
+
+		if nethttp.HandlerAs(handler, &withRoute) {
+			method := withRoute.RouteMethod()
+			pattern := withRoute.RoutePattern()
+
+			return ochttp.WithRouteTag(&ochttp.Handler{
+				FormatSpanName: func(_ *http.Request) string {
+					return method + " " + pattern
+				},
+				Propagation: format,
+				Handler:     handler,
+			}, method+pattern)
+		}
+
+		return &ochttp.Handler{
+			Propagation: format,
+			Handler:     handler,
+		}
 	}
+}
+
+// ViewsMiddleware records HTTP server measures of ochttp without starting spans.
+//
+// It keeps Views populated when requests are traced with another backend.
+func ViewsMiddleware(handler http.Handler) http.Handler {
+	var (
+		withRoute rest.HandlerWithRoute
+		route     string
+	)
 
-	return &ochttp.Handler{
-		Handler: handler,
+	if nethttp.HandlerAs(handler, &withRoute) {
+		route = withRoute.RouteMethod() + withRoute.RoutePattern()
 	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+
+		mutators := []tag.Mutator{
+			tag.Upsert(ochttp.Host, r.Host),
+			tag.Upsert(ochttp.Path, r.URL.Path),
+			tag.Upsert(ochttp.Method, r.Method),
+		}
+
+		if route != "" {
+			mutators = append(mutators, tag.Upsert(ochttp.KeyServerRoute, route))
+		}
+
+		// Invalid tag values are skipped in the same way as in ochttp.
+		ctx, _ := tag.New(r.Context(), mutators...) //nolint:errcheck
+
+		stats.Record(ctx, ochttp.ServerRequestCount.M(1))
+
+		ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+
+		handler.ServeHTTP(ww, r.WithContext(ctx))
+
+		st := ww.Status()
+		if st == 0 {
+			st = http.StatusOK
+		}
+
+		m := []stats.Measurement{
+			ochttp.ServerLatency.M(float64(time.Since(start)) / float64(time.Millisecond)),
+			ochttp.ServerResponseBytes.M(int64(ww.BytesWritten())),
+		}
+
+		if r.ContentLength >= 0 {
+			m = append(m, ochttp.ServerRequestBytes.M(r.ContentLength))
+		}
+
+		_ = stats.RecordWithTags(ctx, //nolint:errcheck // Status code is a valid tag value.
+			[]tag.Mutator{tag.Upsert(ochttp.StatusCode, strconv.Itoa(st))}, m...)
+	})
 }
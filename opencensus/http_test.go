@@ -0,0 +1,41 @@
+package opencensus_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/bool64/brick/opencensus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opencensus.io/plugin/ochttp"
+	"go.opencensus.io/stats/view"
+	"go.opencensus.io/tag"
+	"go.opencensus.io/trace"
+)
+
+func TestViewsMiddleware(t *testing.T) {
+	v := &view.View{
+		Name:        "test/views_middleware/responses",
+		Measure:     ochttp.ServerLatency,
+		TagKeys:     []tag.Key{ochttp.Method, ochttp.StatusCode},
+		Aggregation: view.Count(),
+	}
+
+	require.NoError(t, view.Register(v))
+	defer view.Unregister(v)
+
+	h := opencensus.ViewsMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Nil(t, trace.FromContext(r.Context()))
+		w.WriteHeader(http.StatusNotFound)
+	}))
+
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+
+	rows, err := view.RetrieveData(v.Name)
+	require.NoError(t, err)
+	require.Len(t, rows, 1)
+
+	assert.Equal(t, []tag.Tag{{Key: ochttp.Method, Value: "GET"}, {Key: ochttp.StatusCode, Value: "404"}}, rows[0].Tags)
+	assert.Equal(t, int64(1), rows[0].Data.(*view.CountData).Value)
+}
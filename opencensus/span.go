@@ -19,6 +19,8 @@ import (
 //		trace.StringAttribute("key", "value"),
 //	)
 //	defer finish(&err)
+//
+// Deprecated: use tracing.AddSpan, it supports OpenTelemetry backend and records errors as span events.
 func AddSpan(ctx context.Context, attributes ...trace.Attribute) (context.Context, func(*error)) {
 	ctx, span := trace.StartSpan(ctx, runtime.CallerFunc(2)) //nolint:spancheck
 	span.AddAttributes(attributes...)
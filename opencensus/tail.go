@@ -0,0 +1,363 @@
+package opencensus
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.opencensus.io/trace"
+)
+
+const (
+	tailTTL         = time.Minute
+	tailSweepPeriod = 5 * time.Second
+)
+
+var (
+	tailSampler atomic.Pointer[tailSampling]
+
+	exportersMu sync.Mutex
+	exporters   = map[trace.Exporter]struct{}{}
+)
+
+// EnableTailSampling defers export decision of a trace until its local root span ends.
+//
+// All spans are recorded and buffered, trace is exported if it was sampled by head sampler
+// or if it was marked with ForceSample before local root span ended.
+// Please note, outgoing requests propagate trace as sampled, because sampling decision is not known yet.
+//
+// Exporters have to be registered with RegisterExporter to be affected by tail sampling.
+// Returned function disables tail sampling and restores head sampler.
+func EnableTailSampling(head trace.Sampler, options ...func(o *TailOptions)) (disable func()) {
+	return enableTailSampling(head, head, nil, options)
+}
+
+// TailOptions configures tail sampling.
+type TailOptions struct {
+	// MaxTraces limits the number of buffered local traces, default 10000.
+	// New traces are sampled by head (or restore) sampler while the buffer is full.
+	MaxTraces int
+
+	// MaxSpans limits the number of buffered spans of a local trace, default 1000.
+	// Extra spans are dropped.
+	MaxSpans int
+}
+
+// TailTrace is a local trace with ended root span.
+type TailTrace struct {
+	Root *trace.SpanData
+
+	// Spans of local trace, including root span.
+	Spans []*trace.SpanData
+}
+
+// TailPolicy decides if a trace should be exported when its local root span ends.
+type TailPolicy interface {
+	SampleTrace(t TailTrace) bool
+}
+
+// EnablePolicySampling enables tail sampling with export decision made by policy.
+//
+// Traces with sampled remote parent and traces marked with ForceSample are exported regardless of policy.
+// Returned function disables tail sampling and applies restore sampler.
+//
+// Restore sampler also makes head decision for new traces while the buffer of pending traces is full.
+func EnablePolicySampling(policy TailPolicy, restore trace.Sampler, options ...func(o *TailOptions)) (disable func()) {
+	return enableTailSampling(func(p trace.SamplingParameters) trace.SamplingDecision {
+		return trace.SamplingDecision{Sample: p.HasRemoteParent && p.ParentContext.IsSampled()}
+	}, restore, policy, options)
+}
+
+func enableTailSampling(head, restore trace.Sampler, policy TailPolicy, options []func(o *TailOptions)) (disable func()) {
+	o := TailOptions{}
+	for _, opt := range options {
+		opt(&o)
+	}
+
+	if o.MaxTraces <= 0 {
+		o.MaxTraces = 10000
+	}
+
+	if o.MaxSpans <= 0 {
+		o.MaxSpans = 1000
+	}
+
+	t := &tailSampling{
+		head:      head,
+		overflow:  restore,
+		policy:    policy,
+		maxTraces: o.MaxTraces,
+		maxSpans:  o.MaxSpans,
+		traces:    make(map[trace.TraceID]*tailTrace),
+		decided:   make(map[trace.TraceID]decidedTrace, o.MaxTraces),
+	}
+
+	exportersMu.Lock()
+	defer exportersMu.Unlock()
+
+	if prev := tailSampler.Swap(t); prev != nil {
+		trace.UnregisterExporter(prev)
+	} else {
+		for e := range exporters {
+			trace.UnregisterExporter(e)
+		}
+	}
+
+	trace.RegisterExporter(t)
+	trace.ApplyConfig(trace.Config{DefaultSampler: t.sample})
+
+	return func() {
+		exportersMu.Lock()
+		defer exportersMu.Unlock()
+
+		if !tailSampler.CompareAndSwap(t, nil) {
+			return
+		}
+
+		trace.UnregisterExporter(t)
+		trace.ApplyConfig(trace.Config{DefaultSampler: restore})
+
+		for e := range exporters {
+			trace.RegisterExporter(e)
+		}
+	}
+}
+
+// RegisterExporter adds trace exporter, export is deferred if tail sampling is enabled.
+func RegisterExporter(e trace.Exporter) {
+	exportersMu.Lock()
+	defer exportersMu.Unlock()
+
+	exporters[e] = struct{}{}
+
+	if tailSampler.Load() == nil {
+		trace.RegisterExporter(e)
+	}
+}
+
+// UnregisterExporter removes trace exporter.
+func UnregisterExporter(e trace.Exporter) {
+	exportersMu.Lock()
+	defer exportersMu.Unlock()
+
+	delete(exporters, e)
+	trace.UnregisterExporter(e)
+}
+
+// ForceSample marks trace of context to be exported regardless of head sampling decision.
+//
+// It has no effect if tail sampling is not enabled or if local root span has already ended.
+func ForceSample(ctx context.Context) {
+	if span := trace.FromContext(ctx); span != nil {
+		ForceSampleTrace(span.SpanContext().TraceID)
+	}
+}
+
+// ForceSampleTrace marks trace to be exported regardless of head sampling decision.
+func ForceSampleTrace(id trace.TraceID) {
+	if t := tailSampler.Load(); t != nil {
+		t.force(id)
+	}
+}
+
+// IsSampled returns true if trace of context is sampled by head sampler.
+//
+// With tail sampling enabled all spans are recorded as sampled, so this function
+// reports original sampling decision instead of span flag.
+func IsSampled(ctx context.Context) bool {
+	span := trace.FromContext(ctx)
+	if span == nil {
+		return false
+	}
+
+	sc := span.SpanContext()
+
+	if t := tailSampler.Load(); t != nil {
+		if sampled, ok := t.headSampled(sc.TraceID); ok {
+			return sampled
+		}
+	}
+
+	return sc.IsSampled()
+}
+
+type tailTrace struct {
+	head    bool
+	sampled bool
+	updated time.Time
+	spans   []*trace.SpanData
+}
+
+type decidedTrace struct {
+	head    bool
+	sampled bool
+}
+
+type tailSampling struct {
+	head     trace.Sampler
+	overflow trace.Sampler
+	policy   TailPolicy
+
+	maxTraces int
+	maxSpans  int
+
+	mu        sync.Mutex
+	traces    map[trace.TraceID]*tailTrace
+	lastSweep time.Time
+
+	// Decided traces are kept in a bounded FIFO to route late child spans.
+	decided      map[trace.TraceID]decidedTrace
+	decidedOrder []trace.TraceID
+	decidedNext  int
+}
+
+// sample is called for local root spans only, child spans inherit sampling flag.
+func (t *tailSampling) sample(p trace.SamplingParameters) trace.SamplingDecision {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	tt, ok := t.traces[p.TraceID]
+	if !ok {
+		// Trace can not be buffered, export decision is made by overflow sampler.
+		if len(t.traces) >= t.maxTraces {
+			return t.overflow(p)
+		}
+
+		tt = &tailTrace{}
+		t.traces[p.TraceID] = tt
+	}
+
+	d := t.head(p)
+
+	tt.head = tt.head || d.Sample
+	tt.sampled = tt.sampled || d.Sample
+	tt.updated = time.Now()
+
+	return trace.SamplingDecision{Sample: true}
+}
+
+// decide moves trace to the set of decided traces.
+func (t *tailSampling) decide(id trace.TraceID, tt *tailTrace) {
+	delete(t.traces, id)
+
+	if len(t.decidedOrder) < t.maxTraces {
+		t.decidedOrder = append(t.decidedOrder, id)
+	} else {
+		delete(t.decided, t.decidedOrder[t.decidedNext])
+		t.decidedOrder[t.decidedNext] = id
+		t.decidedNext = (t.decidedNext + 1) % t.maxTraces
+	}
+
+	t.decided[id] = decidedTrace{head: tt.head, sampled: tt.sampled}
+}
+
+func (t *tailSampling) headSampled(id trace.TraceID) (sampled bool, ok bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if tt, ok := t.traces[id]; ok {
+		return tt.head, true
+	}
+
+	if d, ok := t.decided[id]; ok {
+		return d.head, true
+	}
+
+	return false, false
+}
+
+func (t *tailSampling) force(id trace.TraceID) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if tt, ok := t.traces[id]; ok {
+		tt.sampled = true
+	}
+}
+
+// ExportSpan implements trace.Exporter.
+func (t *tailSampling) ExportSpan(sd *trace.SpanData) {
+	var export []*trace.SpanData
+
+	t.mu.Lock()
+
+	tt, ok := t.traces[sd.TraceID]
+	if !ok {
+		// Spans of decided traces follow decision, spans of unknown traces were sampled by head.
+		if d, decided := t.decided[sd.TraceID]; !decided || d.sampled {
+			export = []*trace.SpanData{sd}
+		}
+	}
+
+	switch {
+	case !ok:
+	case sd.ParentSpanID == trace.SpanID{} || sd.HasRemoteParent:
+		spans := append(tt.spans, sd)
+
+		if !tt.sampled && t.policy != nil {
+			tt.sampled = t.policy.SampleTrace(TailTrace{Root: sd, Spans: spans})
+		}
+
+		if tt.sampled {
+			export = spans
+		}
+
+		t.decide(sd.TraceID, tt)
+	case len(tt.spans) < t.maxSpans:
+		tt.spans = append(tt.spans, sd)
+		tt.updated = time.Now()
+	}
+
+	if time.Since(t.lastSweep) >= tailSweepPeriod {
+		export = append(export, t.sweep()...)
+	}
+
+	t.mu.Unlock()
+
+	if len(export) == 0 {
+		return
+	}
+
+	// Exporters are called without lock, so that a slow exporter does not block registration.
+	for _, e := range registeredExporters() {
+		for _, s := range export {
+			e.ExportSpan(s)
+		}
+	}
+}
+
+func registeredExporters() []trace.Exporter {
+	exportersMu.Lock()
+	defer exportersMu.Unlock()
+
+	list := make([]trace.Exporter, 0, len(exporters))
+
+	for e := range exporters {
+		list = append(list, e)
+	}
+
+	return list
+}
+
+// sweep removes abandoned traces and returns their spans if they are sampled.
+func (t *tailSampling) sweep() []*trace.SpanData {
+	now := time.Now()
+	t.lastSweep = now
+
+	var export []*trace.SpanData
+
+	for id, tt := range t.traces {
+		if now.Sub(tt.updated) < tailTTL {
+			continue
+		}
+
+		if tt.sampled {
+			export = append(export, tt.spans...)
+		}
+
+		delete(t.traces, id)
+	}
+
+	return export
+}
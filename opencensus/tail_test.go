@@ -0,0 +1,219 @@
+package opencensus_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/bool64/brick/opencensus"
+	"github.com/stretchr/testify/assert"
+	"go.opencensus.io/trace"
+)
+
+type exporterMock struct {
+	mu    sync.Mutex
+	spans []string
+}
+
+func (e *exporterMock) ExportSpan(s *trace.SpanData) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.spans = append(e.spans, s.Name)
+}
+
+func TestEnableTailSampling(t *testing.T) {
+	exp := &exporterMock{}
+
+	opencensus.RegisterExporter(exp)
+	defer opencensus.UnregisterExporter(exp)
+
+	disable := opencensus.EnableTailSampling(trace.NeverSample())
+	defer disable()
+
+	run := func(name string, force bool) {
+		ctx, root := trace.StartSpan(context.Background(), name)
+		_, child := trace.StartSpan(ctx, name+"-child")
+
+		child.End()
+
+		if force {
+			opencensus.ForceSample(ctx)
+		}
+
+		root.End()
+	}
+
+	run("regular", false)
+	run("forced", true)
+
+	assert.Equal(t, []string{"forced-child", "forced"}, exp.spans)
+
+	disable()
+
+	ctx, root := trace.StartSpan(context.Background(), "after")
+	opencensus.ForceSample(ctx)
+	root.End()
+
+	assert.Len(t, exp.spans, 2)
+}
+
+type policyFunc func(t opencensus.TailTrace) bool
+
+func (f policyFunc) SampleTrace(t opencensus.TailTrace) bool {
+	return f(t)
+}
+
+func TestEnablePolicySampling(t *testing.T) {
+	exp := &exporterMock{}
+
+	opencensus.RegisterExporter(exp)
+	defer opencensus.UnregisterExporter(exp)
+
+	var decided []string
+
+	disable := opencensus.EnablePolicySampling(policyFunc(func(t opencensus.TailTrace) bool {
+		decided = append(decided, t.Root.Name)
+
+		return len(t.Spans) == 2 && t.Spans[0].Name == "keep-child"
+	}), trace.ProbabilitySampler(1e-4))
+	defer disable()
+
+	run := func(name string, force bool) {
+		ctx, root := trace.StartSpan(context.Background(), name)
+		_, child := trace.StartSpan(ctx, name+"-child")
+
+		child.End()
+
+		if force {
+			opencensus.ForceSample(ctx)
+		}
+
+		root.End()
+	}
+
+	run("keep", false)
+	run("drop", false)
+	run("forced", true)
+
+	assert.Equal(t, []string{"keep-child", "keep", "forced-child", "forced"}, exp.spans)
+	assert.Equal(t, []string{"keep", "drop"}, decided)
+}
+
+func TestEnablePolicySampling_overflow(t *testing.T) {
+	exp := &exporterMock{}
+
+	opencensus.RegisterExporter(exp)
+	defer opencensus.UnregisterExporter(exp)
+
+	disable := opencensus.EnablePolicySampling(policyFunc(func(t opencensus.TailTrace) bool {
+		return t.Root.Name == "keep"
+	}), trace.AlwaysSample())
+	defer disable()
+
+	// Decided traces do not occupy buffer.
+	for i := 0; i < 20000; i++ {
+		_, root := trace.StartSpan(context.Background(), "drop")
+		root.End()
+	}
+
+	_, root := trace.StartSpan(context.Background(), "keep")
+	root.End()
+
+	assert.Equal(t, []string{"keep"}, exp.spans)
+
+	// Traces that do not fit buffer of pending traces are sampled by restore sampler.
+	pending := make([]*trace.Span, 0, 10000)
+
+	for i := 0; i < 10000; i++ {
+		_, root := trace.StartSpan(context.Background(), "pending")
+		pending = append(pending, root)
+	}
+
+	_, root = trace.StartSpan(context.Background(), "overflow")
+	root.End()
+
+	for _, root := range pending {
+		root.End()
+	}
+
+	assert.Equal(t, []string{"keep", "overflow"}, exp.spans)
+}
+
+func TestEnableTailSampling_manyTraces(t *testing.T) {
+	exp := &exporterMock{}
+
+	opencensus.RegisterExporter(exp)
+	defer opencensus.UnregisterExporter(exp)
+
+	disable := opencensus.EnableTailSampling(trace.NeverSample())
+	defer disable()
+
+	for i := 0; i < 20000; i++ {
+		_, root := trace.StartSpan(context.Background(), "regular")
+		root.End()
+	}
+
+	ctx, root := trace.StartSpan(context.Background(), "slow")
+	opencensus.ForceSampleTrace(root.SpanContext().TraceID)
+	assert.False(t, opencensus.IsSampled(ctx))
+	root.End()
+
+	assert.Equal(t, []string{"slow"}, exp.spans)
+}
+
+func TestEnablePolicySampling_options(t *testing.T) {
+	exp := &exporterMock{}
+
+	opencensus.RegisterExporter(exp)
+	defer opencensus.UnregisterExporter(exp)
+
+	disable := opencensus.EnablePolicySampling(policyFunc(func(t opencensus.TailTrace) bool {
+		return t.Root.Name == "keep"
+	}), trace.AlwaysSample(), func(o *opencensus.TailOptions) {
+		o.MaxTraces = 2
+		o.MaxSpans = 1
+	})
+	defer disable()
+
+	ctx, root := trace.StartSpan(context.Background(), "keep")
+
+	for _, name := range []string{"child1", "child2"} {
+		_, span := trace.StartSpan(ctx, name)
+		span.End()
+	}
+
+	_, pending := trace.StartSpan(context.Background(), "pending")
+
+	_, overflow := trace.StartSpan(context.Background(), "overflow")
+	overflow.End()
+
+	root.End()
+	pending.End()
+
+	assert.Equal(t, []string{"overflow", "child1", "keep"}, exp.spans)
+}
+
+type unregisteringExporter struct {
+	exporterMock
+}
+
+func (e *unregisteringExporter) ExportSpan(s *trace.SpanData) {
+	opencensus.UnregisterExporter(e)
+	e.exporterMock.ExportSpan(s)
+}
+
+func TestEnableTailSampling_exporterLock(t *testing.T) {
+	exp := &unregisteringExporter{}
+
+	opencensus.RegisterExporter(exp)
+	defer opencensus.UnregisterExporter(exp)
+
+	disable := opencensus.EnableTailSampling(trace.AlwaysSample())
+	defer disable()
+
+	_, root := trace.StartSpan(context.Background(), "root")
+	root.End()
+
+	assert.Equal(t, []string{"root"}, exp.spans)
+}
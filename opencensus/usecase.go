@@ -15,6 +15,9 @@ type errorWithStatus interface {
 }
 
 // UseCaseMiddleware is a tracing usecase middleware.
+//
+// Deprecated: use tracing.UseCaseMiddleware, it supports OpenTelemetry backend,
+// records fields tagged with `trace` as span attributes and errors as span events.
 type UseCaseMiddleware struct {
 	WithInput bool
 }
@@ -0,0 +1,297 @@
+// Package otlp configures OpenTelemetry OTLP exporters.
+package otlp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/bool64/ctxd"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlpmetric/otlpmetricgrpc"
+	"go.opentelemetry.io/otel/exporters/otlp/otlpmetric/otlpmetrichttp"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+// Protocols.
+const (
+	ProtocolGRPC = "grpc"
+	ProtocolHTTP = "http/protobuf"
+)
+
+var (
+	errUnknownProtocol    = errors.New("unknown OTLP protocol, expected grpc or http/protobuf")
+	errUnknownCompression = errors.New("unknown OTLP compression, expected gzip or none")
+)
+
+type deps interface {
+	CtxdLogger() ctxd.Logger
+	OnShutdown(name string, fn func())
+}
+
+// Config defines OTLP exporter settings.
+type Config struct {
+	// Endpoint is the host and port of OTLP receiver.
+	// For example, localhost:4317 for gRPC or localhost:4318 for HTTP.
+	Endpoint string
+
+	// Protocol is the transport protocol, "grpc" or "http/protobuf".
+	Protocol string `default:"grpc"`
+
+	// Insecure disables TLS.
+	Insecure bool
+
+	// Headers are sent with every export request, for example "Authorization:Bearer token".
+	Headers map[string]string
+
+	// Compression of export requests, "gzip" or "none".
+	Compression string `default:"gzip"`
+
+	// Timeout limits a single export request.
+	Timeout time.Duration `default:"10s"`
+
+	// Traces enables export of OpenTelemetry spans, it requires "opentelemetry" tracing backend.
+	Traces bool `default:"true"`
+
+	// Metrics enables export of OpenTelemetry metrics, meter provider is set as global.
+	Metrics bool
+
+	// MetricsInterval is the period of metrics export.
+	MetricsInterval time.Duration `split_words:"true" default:"1m"`
+
+	// BatchTimeout is the max delay of sending a batch of spans.
+	BatchTimeout time.Duration `split_words:"true" default:"5s"`
+
+	// MaxQueueSize limits number of spans waiting for export, spans are dropped when queue is full.
+	MaxQueueSize int `split_words:"true" default:"2048"`
+
+	// MaxExportBatchSize limits number of spans in a batch.
+	MaxExportBatchSize int `split_words:"true" default:"512"`
+
+	// OnError is the hook to be called when export fails.
+	// If no custom hook is set, errors are logged.
+	// Optional.
+	OnError func(err error) `json:"-"`
+}
+
+func (cfg *Config) setDefaults() {
+	if cfg.Protocol == "" {
+		cfg.Protocol = ProtocolGRPC
+	}
+
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = 10 * time.Second
+	}
+
+	if cfg.BatchTimeout <= 0 {
+		cfg.BatchTimeout = 5 * time.Second
+	}
+
+	if cfg.MaxQueueSize <= 0 {
+		cfg.MaxQueueSize = 2048
+	}
+
+	if cfg.MaxExportBatchSize <= 0 {
+		cfg.MaxExportBatchSize = 512
+	}
+}
+
+func (cfg Config) validate() error {
+	switch cfg.Protocol {
+	case ProtocolGRPC, ProtocolHTTP:
+	default:
+		return fmt.Errorf("%w: %s", errUnknownProtocol, cfg.Protocol)
+	}
+
+	switch cfg.Compression {
+	case "gzip", "none", "":
+	default:
+		return fmt.Errorf("%w: %s", errUnknownCompression, cfg.Compression)
+	}
+
+	return nil
+}
+
+// Setup configures OTLP exporters for OpenTelemetry traces and metrics.
+//
+// Span exporter is registered in global tracer provider that is set up by brick.NewBaseLocator
+// with "opentelemetry" tracing backend. Buffered data is flushed on shutdown.
+//
+// Add OTLP to service configuration:
+//
+//	OTLP otlp.Config `split_words:"true"`
+func Setup(cfg Config, l deps) error {
+	if cfg.Endpoint == "" {
+		l.CtxdLogger().Info(context.Background(), "skipping otlp setup")
+
+		return nil
+	}
+
+	cfg.setDefaults()
+
+	if err := cfg.validate(); err != nil {
+		return err
+	}
+
+	if cfg.OnError == nil {
+		cfg.OnError = func(err error) {
+			l.CtxdLogger().Error(context.Background(), "otlp exporter failed",
+				"msg", err.Error(),
+				"type", fmt.Sprintf("%T", err),
+				"error", err)
+		}
+	}
+
+	otel.SetErrorHandler(otel.ErrorHandlerFunc(cfg.OnError))
+
+	if cfg.Traces {
+		if err := setupTraces(cfg, l); err != nil {
+			return err
+		}
+	}
+
+	if cfg.Metrics {
+		if err := setupMetrics(cfg, l); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func setupTraces(cfg Config, l deps) error {
+	tp, ok := otel.GetTracerProvider().(*sdktrace.TracerProvider)
+	if !ok {
+		l.CtxdLogger().Info(context.Background(), "skipping otlp traces, tracing backend is not opentelemetry")
+
+		return nil
+	}
+
+	var (
+		exp sdktrace.SpanExporter
+		err error
+	)
+
+	if cfg.Protocol == ProtocolHTTP {
+		opts := []otlptracehttp.Option{
+			otlptracehttp.WithEndpoint(cfg.Endpoint),
+			otlptracehttp.WithHeaders(cfg.Headers),
+			otlptracehttp.WithTimeout(cfg.Timeout),
+		}
+
+		if cfg.Insecure {
+			opts = append(opts, otlptracehttp.WithInsecure())
+		}
+
+		if cfg.Compression == "gzip" {
+			opts = append(opts, otlptracehttp.WithCompression(otlptracehttp.GzipCompression))
+		}
+
+		exp, err = otlptracehttp.New(context.Background(), opts...)
+	} else {
+		opts := []otlptracegrpc.Option{
+			otlptracegrpc.WithEndpoint(cfg.Endpoint),
+			otlptracegrpc.WithHeaders(cfg.Headers),
+			otlptracegrpc.WithTimeout(cfg.Timeout),
+		}
+
+		if cfg.Insecure {
+			opts = append(opts, otlptracegrpc.WithInsecure())
+		}
+
+		if cfg.Compression == "gzip" {
+			opts = append(opts, otlptracegrpc.WithCompressor("gzip"))
+		}
+
+		exp, err = otlptracegrpc.New(context.Background(), opts...)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	l.CtxdLogger().Info(context.Background(), "setting up otlp traces")
+
+	bsp := sdktrace.NewBatchSpanProcessor(exp,
+		sdktrace.WithBatchTimeout(cfg.BatchTimeout),
+		sdktrace.WithMaxQueueSize(cfg.MaxQueueSize),
+		sdktrace.WithMaxExportBatchSize(cfg.MaxExportBatchSize),
+		sdktrace.WithExportTimeout(cfg.Timeout),
+	)
+
+	tp.RegisterSpanProcessor(bsp)
+	l.OnShutdown("flush_otlp_traces", func() {
+		if err := bsp.Shutdown(context.Background()); err != nil {
+			cfg.OnError(err)
+		}
+	})
+
+	return nil
+}
+
+func setupMetrics(cfg Config, l deps) error {
+	var (
+		exp sdkmetric.Exporter
+		err error
+	)
+
+	if cfg.Protocol == ProtocolHTTP {
+		opts := []otlpmetrichttp.Option{
+			otlpmetrichttp.WithEndpoint(cfg.Endpoint),
+			otlpmetrichttp.WithHeaders(cfg.Headers),
+			otlpmetrichttp.WithTimeout(cfg.Timeout),
+		}
+
+		if cfg.Insecure {
+			opts = append(opts, otlpmetrichttp.WithInsecure())
+		}
+
+		if cfg.Compression == "gzip" {
+			opts = append(opts, otlpmetrichttp.WithCompression(otlpmetrichttp.GzipCompression))
+		}
+
+		exp, err = otlpmetrichttp.New(context.Background(), opts...)
+	} else {
+		opts := []otlpmetricgrpc.Option{
+			otlpmetricgrpc.WithEndpoint(cfg.Endpoint),
+			otlpmetricgrpc.WithHeaders(cfg.Headers),
+			otlpmetricgrpc.WithTimeout(cfg.Timeout),
+		}
+
+		if cfg.Insecure {
+			opts = append(opts, otlpmetricgrpc.WithInsecure())
+		}
+
+		if cfg.Compression == "gzip" {
+			opts = append(opts, otlpmetricgrpc.WithCompressor("gzip"))
+		}
+
+		exp, err = otlpmetricgrpc.New(context.Background(), opts...)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	l.CtxdLogger().Info(context.Background(), "setting up otlp metrics")
+
+	mp := sdkmetric.NewMeterProvider(sdkmetric.WithReader(
+		sdkmetric.NewPeriodicReader(exp,
+			sdkmetric.WithInterval(cfg.MetricsInterval),
+			sdkmetric.WithTimeout(cfg.Timeout),
+		),
+	))
+
+	otel.SetMeterProvider(mp)
+	l.OnShutdown("flush_otlp_metrics", func() {
+		if err := mp.Shutdown(context.Background()); err != nil {
+			cfg.OnError(err)
+		}
+	})
+
+	return nil
+}
@@ -0,0 +1,158 @@
+package otlp_test
+
+import (
+	"compress/gzip"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/bool64/brick/otlp"
+	"github.com/bool64/ctxd"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	collmetrics "go.opentelemetry.io/proto/otlp/collector/metrics/v1"
+	colltrace "go.opentelemetry.io/proto/otlp/collector/trace/v1"
+	"google.golang.org/protobuf/proto"
+)
+
+type locator struct {
+	ctxd.LoggerMock
+	shutdown map[string]func()
+}
+
+func (l *locator) CtxdLogger() ctxd.Logger {
+	return &l.LoggerMock
+}
+
+func (l *locator) OnShutdown(name string, fn func()) {
+	l.shutdown[name] = fn
+}
+
+// receiver is a stand-in for OTLP/HTTP collector.
+type receiver struct {
+	mu      sync.Mutex
+	headers []string
+	spans   []string
+	metrics []string
+}
+
+func (rc *receiver) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body := r.Body
+
+	if r.Header.Get("Content-Encoding") == "gzip" {
+		zr, err := gzip.NewReader(r.Body)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+
+			return
+		}
+
+		body = zr
+	}
+
+	b, err := io.ReadAll(body)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+
+		return
+	}
+
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+
+	rc.headers = append(rc.headers, r.Header.Get("Authorization"))
+
+	switch r.URL.Path {
+	case "/v1/traces":
+		var req colltrace.ExportTraceServiceRequest
+		if err := proto.Unmarshal(b, &req); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+
+			return
+		}
+
+		for _, rs := range req.GetResourceSpans() {
+			for _, ss := range rs.GetScopeSpans() {
+				for _, s := range ss.GetSpans() {
+					rc.spans = append(rc.spans, s.GetName())
+				}
+			}
+		}
+	case "/v1/metrics":
+		var req collmetrics.ExportMetricsServiceRequest
+		if err := proto.Unmarshal(b, &req); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+
+			return
+		}
+
+		for _, rm := range req.GetResourceMetrics() {
+			for _, sm := range rm.GetScopeMetrics() {
+				for _, m := range sm.GetMetrics() {
+					rc.metrics = append(rc.metrics, m.GetName())
+				}
+			}
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/x-protobuf")
+}
+
+func TestSetup(t *testing.T) {
+	rc := &receiver{}
+	srv := httptest.NewServer(rc)
+	defer srv.Close()
+
+	tp := sdktrace.NewTracerProvider()
+	otel.SetTracerProvider(tp)
+
+	l := &locator{shutdown: map[string]func(){}}
+
+	require.NoError(t, otlp.Setup(otlp.Config{
+		Endpoint:    strings.TrimPrefix(srv.URL, "http://"),
+		Protocol:    otlp.ProtocolHTTP,
+		Insecure:    true,
+		Headers:     map[string]string{"Authorization": "Bearer token"},
+		Compression: "gzip",
+		Traces:      true,
+		Metrics:     true,
+	}, l))
+
+	_, span := otel.Tracer("test").Start(context.Background(), "operation")
+	span.End()
+
+	c, err := otel.Meter("test").Int64Counter("jobs_count")
+	require.NoError(t, err)
+	c.Add(context.Background(), 1)
+
+	require.Len(t, l.shutdown, 2)
+
+	for _, fn := range l.shutdown {
+		fn()
+	}
+
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+
+	assert.Equal(t, []string{"operation"}, rc.spans)
+	assert.Equal(t, []string{"jobs_count"}, rc.metrics)
+	assert.Equal(t, []string{"Bearer token", "Bearer token"}, rc.headers)
+	assert.Contains(t, l.String(), "setting up otlp traces")
+}
+
+func TestSetup_skip(t *testing.T) {
+	l := &locator{shutdown: map[string]func(){}}
+
+	require.NoError(t, otlp.Setup(otlp.Config{}, l))
+	assert.Empty(t, l.shutdown)
+	assert.Contains(t, l.String(), "skipping otlp setup")
+
+	assert.EqualError(t, otlp.Setup(otlp.Config{Endpoint: "localhost:4317", Protocol: "thrift"}, l),
+		"unknown OTLP protocol, expected grpc or http/protobuf: thrift")
+}
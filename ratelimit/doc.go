@@ -0,0 +1,2 @@
+// Package ratelimit provides HTTP rate limiting middleware.
+package ratelimit
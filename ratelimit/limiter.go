@@ -0,0 +1,91 @@
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+)
+
+// Limiter checks whether an event identified by key fits the rule.
+//
+// Implementations can keep state in memory or in a shared storage to limit
+// a cluster of application instances.
+type Limiter interface {
+	// Allow consumes one event for the key and returns zero if event is allowed,
+	// or a positive delay after which event could be allowed.
+	Allow(ctx context.Context, key string, rule Rule) (time.Duration, error)
+}
+
+// NewMemory creates in-memory token bucket limiter.
+func NewMemory() *Memory {
+	return &Memory{
+		buckets: make(map[string]*bucket),
+		now:     time.Now,
+	}
+}
+
+// Memory is an in-memory token bucket limiter.
+//
+// Please use NewMemory to create an instance.
+type Memory struct {
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	lastSweep time.Time
+	now       func() time.Time
+}
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+	full   time.Duration
+}
+
+const sweepInterval = time.Minute
+
+// Allow implements Limiter.
+func (m *Memory) Allow(_ context.Context, key string, rule Rule) (time.Duration, error) {
+	if rule.Limit <= 0 || rule.Period <= 0 {
+		return 0, nil
+	}
+
+	burst := float64(rule.burst())
+	perToken := rule.Period / time.Duration(rule.Limit)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := m.now()
+
+	if now.Sub(m.lastSweep) > sweepInterval {
+		m.sweep(now)
+	}
+
+	b, ok := m.buckets[key]
+	if !ok {
+		b = &bucket{tokens: burst, last: now, full: perToken * time.Duration(burst)}
+		m.buckets[key] = b
+	}
+
+	b.tokens = math.Min(burst, b.tokens+float64(now.Sub(b.last))/float64(perToken))
+	b.last = now
+
+	if b.tokens >= 1 {
+		b.tokens--
+
+		return 0, nil
+	}
+
+	return time.Duration((1 - b.tokens) * float64(perToken)), nil
+}
+
+// sweep removes buckets that are refilled completely, so that they are equal to absent ones.
+func (m *Memory) sweep(now time.Time) {
+	m.lastSweep = now
+
+	for k, b := range m.buckets {
+		if now.Sub(b.last) >= b.full {
+			delete(m.buckets, k)
+		}
+	}
+}
@@ -0,0 +1,202 @@
+package ratelimit
+
+import (
+	"context"
+	"encoding/json"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bool64/ctxd"
+	"github.com/bool64/stats"
+	"github.com/swaggest/rest"
+	"github.com/swaggest/rest/nethttp"
+	"github.com/swaggest/usecase/status"
+)
+
+// KeyFunc identifies a client of HTTP request, empty key disables limiting for the request.
+type KeyFunc func(r *http.Request) string
+
+// ByIP identifies client by remote IP address.
+//
+// Use middleware.RealIP to populate remote address from proxy headers.
+func ByIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+
+	return host
+}
+
+// ByHeader identifies client by value of request header, for example API key.
+func ByHeader(name string) KeyFunc {
+	return func(r *http.Request) string {
+		return r.Header.Get(name)
+	}
+}
+
+// Rule defines a limit of requests per period.
+type Rule struct {
+	// Route is a route pattern to apply the rule to, for example "/users/{id}",
+	// optionally prefixed with method, for example "POST /orders".
+	// Empty route matches all routes and shares the limit between them.
+	Route string
+
+	// Limit is the number of requests allowed per period.
+	Limit int
+
+	// Period is the duration of limit, default is one second.
+	Period time.Duration
+
+	// Burst is the maximum number of requests allowed at once, default is Limit.
+	Burst int
+
+	// Key identifies the client, default is ByIP.
+	Key KeyFunc
+}
+
+func (r Rule) burst() int {
+	if r.Burst > 0 {
+		return r.Burst
+	}
+
+	return r.Limit
+}
+
+func (r Rule) matches(method, pattern string) bool {
+	if r.Route == "" || r.Route == pattern {
+		return true
+	}
+
+	return r.Route == method+" "+pattern
+}
+
+// Config describes rate limiting middleware.
+type Config struct {
+	// Rules are checked for each request that matches route, request is rejected if any of rules fails.
+	Rules []Rule
+
+	// Limiter keeps the state of limits, default is NewMemory.
+	Limiter Limiter
+
+	// Logger is used to report limiter failures, requests are allowed if limiter fails.
+	Logger ctxd.Logger
+
+	// Stats is used to count rejected requests.
+	Stats stats.Tracker
+}
+
+type rule struct {
+	Rule
+	id string
+}
+
+// Middleware creates rate limiting middleware.
+//
+// It is a handler wrapper and should be added with web.Service Wrap to have access to route patterns.
+// Rejected requests are served with 429 Too Many Requests status and Retry-After header.
+func Middleware(cfg Config) func(http.Handler) http.Handler {
+	if cfg.Limiter == nil {
+		cfg.Limiter = NewMemory()
+	}
+
+	if cfg.Logger == nil {
+		cfg.Logger = ctxd.NoOpLogger{}
+	}
+
+	if cfg.Stats == nil {
+		cfg.Stats = stats.NoOp{}
+	}
+
+	return func(handler http.Handler) http.Handler {
+		if nethttp.IsWrapperChecker(handler) {
+			return handler
+		}
+
+		var (
+			withRoute       rest.HandlerWithRoute
+			method, pattern string
+		)
+
+		if nethttp.HandlerAs(handler, &withRoute) {
+			method = withRoute.RouteMethod()
+			pattern = withRoute.RoutePattern()
+		}
+
+		var rules []rule
+
+		for i, r := range cfg.Rules {
+			if !r.matches(method, pattern) {
+				continue
+			}
+
+			if r.Period == 0 {
+				r.Period = time.Second
+			}
+
+			if r.Key == nil {
+				r.Key = ByIP
+			}
+
+			rules = append(rules, rule{Rule: r, id: strconv.Itoa(i) + ":" + r.Route})
+		}
+
+		if len(rules) == 0 {
+			return handler
+		}
+
+		route := strings.TrimSpace(method + " " + pattern)
+
+		return http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+			ctx := r.Context()
+
+			for _, rl := range rules {
+				key := rl.Key(r)
+				if key == "" {
+					continue
+				}
+
+				retryAfter, err := cfg.Limiter.Allow(ctx, rl.id+":"+key, rl.Rule)
+				if err != nil {
+					cfg.Logger.Error(ctx, "rate limiter failed", "error", err, "rule", rl.Route)
+
+					continue
+				}
+
+				if retryAfter > 0 {
+					cfg.Stats.Add(ctx, "http_rate_limited_count", 1, "route", route, "rule", rl.Route)
+
+					tooManyRequests(ctx, cfg.Logger, rw, retryAfter)
+
+					return
+				}
+			}
+
+			handler.ServeHTTP(rw, r)
+		})
+	}
+}
+
+func tooManyRequests(ctx context.Context, logger ctxd.Logger, rw http.ResponseWriter, retryAfter time.Duration) {
+	code, resp := rest.Err(status.ResourceExhausted)
+	resp.ErrorText = "too many requests"
+
+	j, err := json.Marshal(resp)
+	if err != nil {
+		logger.Error(ctx, "failed to marshal rate limit response", "error", err)
+
+		return
+	}
+
+	rw.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+	rw.Header().Set("Content-Type", "application/json; charset=utf-8")
+	rw.WriteHeader(code)
+
+	if _, err := rw.Write(append(j, '\n')); err != nil {
+		logger.Error(ctx, "failed to write rate limit response", "error", err)
+	}
+}
@@ -0,0 +1,60 @@
+package ratelimit_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/bool64/brick/ratelimit"
+	"github.com/stretchr/testify/assert"
+	"github.com/swaggest/openapi-go/openapi3"
+	"github.com/swaggest/rest/web"
+	"github.com/swaggest/usecase"
+)
+
+func TestMiddleware(t *testing.T) {
+	r := web.NewService(openapi3.NewReflector())
+
+	r.Wrap(ratelimit.Middleware(ratelimit.Config{
+		Rules: []ratelimit.Rule{
+			{Route: "GET /limited/{id}", Limit: 2, Period: time.Minute, Key: ratelimit.ByHeader("X-Api-Key")},
+		},
+	}))
+
+	type req struct {
+		ID int `path:"id"`
+	}
+
+	r.Get("/limited/{id}", usecase.NewInteractor(func(_ context.Context, _ req, _ *struct{}) error {
+		return nil
+	}))
+	r.Get("/free", usecase.NewInteractor(func(_ context.Context, _ struct{}, _ *struct{}) error {
+		return nil
+	}))
+
+	do := func(path, key string) *httptest.ResponseRecorder {
+		rw := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.Header.Set("X-Api-Key", key)
+		r.ServeHTTP(rw, req)
+
+		return rw
+	}
+
+	assert.Equal(t, http.StatusNoContent, do("/limited/1", "foo").Code)
+	assert.Equal(t, http.StatusNoContent, do("/limited/2", "foo").Code)
+
+	rw := do("/limited/3", "foo")
+	assert.Equal(t, http.StatusTooManyRequests, rw.Code)
+	assert.Equal(t, "30", rw.Header().Get("Retry-After"))
+	assert.Equal(t, `{"status":"RESOURCE_EXHAUSTED","error":"too many requests"}`+"\n", rw.Body.String())
+
+	assert.Equal(t, http.StatusNoContent, do("/limited/1", "bar").Code)
+	assert.Equal(t, http.StatusNoContent, do("/limited/1", "").Code)
+
+	for i := 0; i < 5; i++ {
+		assert.Equal(t, http.StatusNoContent, do("/free", "foo").Code)
+	}
+}
@@ -0,0 +1,2 @@
+// Package report sends panics and internal errors to error tracking services.
+package report
@@ -0,0 +1,135 @@
+package report
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"reflect"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Event describes a reported panic or error.
+type Event struct {
+	ID          string                 `json:"event_id"`
+	Time        time.Time              `json:"timestamp"`
+	Level       string                 `json:"level"`
+	Message     string                 `json:"message"`
+	Type        string                 `json:"type"`
+	Stack       []Frame                `json:"stack,omitempty"`
+	Request     *Request               `json:"request,omitempty"`
+	TraceID     string                 `json:"trace_id,omitempty"`
+	Release     string                 `json:"release,omitempty"`
+	Environment string                 `json:"environment,omitempty"`
+	ServerName  string                 `json:"server_name,omitempty"`
+	Extra       map[string]interface{} `json:"extra,omitempty"`
+}
+
+// Request describes HTTP request of an event.
+type Request struct {
+	Method     string `json:"method,omitempty"`
+	URL        string `json:"url,omitempty"`
+	ClientIP   string `json:"client_ip,omitempty"`
+	UserAgent  string `json:"user_agent,omitempty"`
+	StatusCode int    `json:"status_code,omitempty"`
+}
+
+// Frame is a stack trace frame, the innermost call is the first.
+type Frame struct {
+	Function string `json:"function"`
+	File     string `json:"file"`
+	Line     int    `json:"line"`
+}
+
+// Event levels.
+const (
+	LevelFatal = "fatal"
+	LevelError = "error"
+)
+
+func newEventID() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+
+	return hex.EncodeToString(b)
+}
+
+// ParseStack parses stack trace produced by runtime/debug.Stack.
+func ParseStack(stack []byte) []Frame {
+	lines := strings.Split(strings.TrimSpace(string(stack)), "\n")
+
+	var frames []Frame
+
+	// First line is goroutine header, followed by pairs of function and location lines.
+	for i := 1; i+1 < len(lines); i += 2 {
+		fn := lines[i]
+		if p := strings.LastIndex(fn, "("); p > 0 {
+			fn = fn[:p]
+		}
+
+		loc := strings.TrimSpace(lines[i+1])
+		if p := strings.LastIndex(loc, " +0x"); p > 0 {
+			loc = loc[:p]
+		}
+
+		f := Frame{Function: fn, File: loc}
+
+		if p := strings.LastIndex(loc, ":"); p > 0 {
+			if line, err := strconv.Atoi(loc[p+1:]); err == nil {
+				f.File = loc[:p]
+				f.Line = line
+			}
+		}
+
+		frames = append(frames, f)
+	}
+
+	return frames
+}
+
+// ErrorStack returns stack of the innermost error in chain that has StackTrace method,
+// or nil if there is no such error.
+//
+// StackTrace method should return a slice of program counters, for example
+// errors of github.com/pkg/errors are supported.
+func ErrorStack(err error) []Frame {
+	var pcs []uintptr
+
+	for ; err != nil; err = errors.Unwrap(err) {
+		m := reflect.ValueOf(err).MethodByName("StackTrace")
+		if !m.IsValid() || m.Type().NumIn() != 0 || m.Type().NumOut() != 1 {
+			continue
+		}
+
+		st := m.Call(nil)[0]
+		if st.Kind() != reflect.Slice || st.Type().Elem().Kind() != reflect.Uintptr {
+			continue
+		}
+
+		pcs = make([]uintptr, st.Len())
+		for i := range pcs {
+			pcs[i] = uintptr(st.Index(i).Uint())
+		}
+	}
+
+	if len(pcs) == 0 {
+		return nil
+	}
+
+	var (
+		res    []Frame
+		frames = runtime.CallersFrames(pcs)
+	)
+
+	for {
+		f, more := frames.Next()
+
+		res = append(res, Frame{Function: f.Function, File: f.File, Line: f.Line})
+
+		if !more {
+			return res
+		}
+	}
+}
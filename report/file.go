@@ -0,0 +1,44 @@
+package report
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"sync"
+)
+
+// File appends events as JSON lines to a file, it can be used as a local stand-in for error tracking service.
+type File struct {
+	mu sync.Mutex
+	f  *os.File
+}
+
+// NewFile opens file for appending.
+func NewFile(path string) (*File, error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600) //nolint:gosec // Path is configured.
+	if err != nil {
+		return nil, err
+	}
+
+	return &File{f: f}, nil
+}
+
+// Send implements Sink.
+func (s *File) Send(_ context.Context, e Event) error {
+	j, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	_, err = s.f.Write(append(j, '\n'))
+
+	return err
+}
+
+// Close closes the file.
+func (s *File) Close() error {
+	return s.f.Close()
+}
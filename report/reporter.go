@@ -0,0 +1,298 @@
+package report
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/bool64/brick/log"
+	"github.com/bool64/brick/tracing"
+	"github.com/bool64/ctxd"
+	"github.com/bool64/dev/version"
+	"github.com/swaggest/rest"
+	"github.com/swaggest/usecase"
+)
+
+var (
+	errQueueFull = errors.New("error reporting queue is full")
+	errClosed    = errors.New("error reporting is closed")
+)
+
+// Sink delivers events.
+type Sink interface {
+	Send(ctx context.Context, e Event) error
+}
+
+// Config controls error reporting.
+type Config struct {
+	// SentryDSN enables sending events to Sentry-compatible service.
+	SentryDSN string `split_words:"true"`
+
+	// File enables appending events as JSON lines to a file.
+	File string
+
+	// Timeout limits delivery of an event to a remote service.
+	Timeout time.Duration `default:"5s"`
+
+	// QueueSize limits number of events waiting for delivery, further events are dropped.
+	QueueSize int `split_words:"true" default:"100"`
+}
+
+// Enabled returns true if any sink is configured.
+func (c Config) Enabled() bool {
+	return c.SentryDSN != "" || c.File != ""
+}
+
+// Reporter captures panics and internal errors and sends them to sinks.
+//
+// Events are delivered in background, Close delivers pending events on shutdown.
+type Reporter struct {
+	// Logger receives delivery errors.
+	Logger ctxd.Logger
+
+	// FieldNames are used to find request metadata in context fields.
+	FieldNames ctxd.FieldNames
+
+	// Release is the version of application, default version.Info().Version.
+	Release string
+
+	// Environment is the name of environment where application runs.
+	Environment string
+
+	// TraceID returns trace ID of context if there is no OpenCensus span in it.
+	TraceID func(ctx context.Context) string
+
+	Sinks []Sink
+
+	// QueueSize limits number of events waiting for delivery, default 100.
+	QueueSize int
+
+	serverName string
+
+	startOnce sync.Once
+	mu        sync.RWMutex
+	closed    bool
+	queue     chan queuedEvent
+	stopped   chan struct{}
+}
+
+type queuedEvent struct {
+	ctx context.Context //nolint:containedctx // Context is passed to sinks in background.
+	e   Event
+}
+
+// NewReporter creates reporter with configured sinks.
+func NewReporter(cfg Config) (*Reporter, error) {
+	r := &Reporter{
+		Logger:    ctxd.NoOpLogger{},
+		Release:   version.Info().Version,
+		QueueSize: cfg.QueueSize,
+	}
+
+	r.serverName, _ = os.Hostname() //nolint:errcheck // Server name is optional.
+
+	if cfg.SentryDSN != "" {
+		s, err := NewSentry(cfg.SentryDSN, cfg.Timeout)
+		if err != nil {
+			return nil, err
+		}
+
+		r.Sinks = append(r.Sinks, s)
+	}
+
+	if cfg.File != "" {
+		s, err := NewFile(cfg.File)
+		if err != nil {
+			return nil, err
+		}
+
+		r.Sinks = append(r.Sinks, s)
+	}
+
+	return r, nil
+}
+
+// OnPanic reports recovered panic, it can be used in log.HTTPRecover OnPanic hooks.
+func (r *Reporter) OnPanic(ctx context.Context, rcv interface{}, stack []byte) {
+	e := r.event(ctx, LevelFatal)
+
+	e.Message = fmt.Sprintf("%v", rcv)
+	e.Type = fmt.Sprintf("%T", rcv)
+	e.Stack = trimPanic(ParseStack(stack))
+
+	if err, ok := rcv.(error); ok {
+		e.Message = err.Error()
+	}
+
+	r.send(ctx, e)
+}
+
+// ReportError reports an error.
+//
+// Stack is reported if error carries it, see ErrorStack.
+func (r *Reporter) ReportError(ctx context.Context, err error) {
+	e := r.event(ctx, LevelError)
+
+	e.Message = err.Error()
+	e.Type = fmt.Sprintf("%T", err)
+	e.Stack = ErrorStack(err)
+
+	r.send(ctx, e)
+}
+
+// UseCaseMiddleware reports use case errors that result in 5xx HTTP status.
+func (r *Reporter) UseCaseMiddleware() usecase.Middleware {
+	return usecase.MiddlewareFunc(func(u usecase.Interactor) usecase.Interactor {
+		var withName usecase.HasName
+
+		name := ""
+		if usecase.As(u, &withName) {
+			name = withName.Name()
+		}
+
+		return usecase.Interact(func(ctx context.Context, input, output interface{}) error {
+			err := u.Interact(ctx, input, output)
+			if err == nil {
+				return nil
+			}
+
+			// Recovered panics are reported with OnPanic.
+			if errors.Is(err, log.ErrPanicked) {
+				return err
+			}
+
+			if code, _ := rest.Err(err); code >= 500 {
+				r.ReportError(ctxd.AddFields(ctx, "use_case", name), err)
+			}
+
+			return err
+		})
+	})
+}
+
+func (r *Reporter) event(ctx context.Context, level string) Event {
+	e := Event{
+		ID:          newEventID(),
+		Time:        time.Now().UTC(),
+		Level:       level,
+		Release:     r.Release,
+		Environment: r.Environment,
+		ServerName:  r.serverName,
+	}
+
+	if span := tracing.FromContext(ctx); span != nil {
+		e.TraceID = span.SpanContext().TraceIDString()
+	} else if r.TraceID != nil {
+		e.TraceID = r.TraceID(ctx)
+	}
+
+	fields := ctxd.Tuples(ctxd.Fields(ctx)).Fields()
+	if len(fields) == 0 {
+		return e
+	}
+
+	e.Extra = fields
+
+	req := Request{}
+	req.Method, _ = fields[r.FieldNames.HTTPMethod].(string)
+	req.URL, _ = fields[r.FieldNames.URL].(string)
+	req.ClientIP, _ = fields[r.FieldNames.ClientIP].(string)
+	req.UserAgent, _ = fields[r.FieldNames.UserAgentOriginal].(string)
+
+	if req.Method != "" || req.URL != "" {
+		e.Request = &req
+	}
+
+	return e
+}
+
+// Close delivers pending events and closes sinks, events reported after Close are dropped.
+func (r *Reporter) Close() error {
+	r.start()
+
+	r.mu.Lock()
+	closed := r.closed
+
+	if !closed {
+		r.closed = true
+		close(r.queue)
+	}
+	r.mu.Unlock()
+
+	<-r.stopped
+
+	if closed {
+		return nil
+	}
+
+	var errs []error
+
+	for _, s := range r.Sinks {
+		if c, ok := s.(io.Closer); ok {
+			errs = append(errs, c.Close())
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+func (r *Reporter) start() {
+	r.startOnce.Do(func() {
+		size := r.QueueSize
+		if size <= 0 {
+			size = 100
+		}
+
+		r.queue = make(chan queuedEvent, size)
+		r.stopped = make(chan struct{})
+
+		go r.run()
+	})
+}
+
+func (r *Reporter) run() {
+	defer close(r.stopped)
+
+	for q := range r.queue {
+		for _, s := range r.Sinks {
+			if err := s.Send(q.ctx, q.e); err != nil {
+				r.Logger.Error(q.ctx, "failed to report event", "error", err, "event_id", q.e.ID)
+			}
+		}
+	}
+}
+
+// send enqueues event for delivery, request cancellation does not abort delivery.
+func (r *Reporter) send(ctx context.Context, e Event) {
+	r.start()
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if r.closed {
+		r.Logger.Error(ctx, "failed to report event", "error", errClosed, "event_id", e.ID)
+
+		return
+	}
+
+	select {
+	case r.queue <- queuedEvent{ctx: context.WithoutCancel(ctx), e: e}:
+	default:
+		r.Logger.Error(ctx, "failed to report event", "error", errQueueFull, "event_id", e.ID)
+	}
+}
+
+// trimPanic removes frames of recovery handlers.
+func trimPanic(frames []Frame) []Frame {
+	for i, f := range frames {
+		if f.Function == "panic" {
+			return frames[i+1:]
+		}
+	}
+
+	return frames
+}
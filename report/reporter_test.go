@@ -0,0 +1,208 @@
+package report_test
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/bool64/brick/log"
+	"github.com/bool64/brick/report"
+	"github.com/bool64/ctxd"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/swaggest/usecase"
+	"github.com/swaggest/usecase/status"
+)
+
+func TestReporter_OnPanic(t *testing.T) {
+	var envelope []string
+
+	srv := httptest.NewServer(http.HandlerFunc(func(_ http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/api/42/envelope/", r.URL.Path)
+		assert.Contains(t, r.Header.Get("X-Sentry-Auth"), "sentry_key=pub")
+
+		b, err := io.ReadAll(r.Body)
+		assert.NoError(t, err)
+
+		envelope = strings.Split(string(b), "\n")
+	}))
+	defer srv.Close()
+
+	file := filepath.Join(t.TempDir(), "errors.jsonl")
+
+	r, err := report.NewReporter(report.Config{
+		SentryDSN: strings.Replace(srv.URL, "http://", "http://pub@", 1) + "/42",
+		File:      file,
+	})
+	require.NoError(t, err)
+
+	r.Release = "v1.2.3"
+	r.FieldNames = ctxd.FieldNames{
+		URL: "url", HTTPMethod: "method", ClientIP: "client_ip", UserAgentOriginal: "user_agent",
+	}
+
+	h := log.HTTPRecover{
+		Logger:     ctxd.NoOpLogger{},
+		FieldNames: r.FieldNames,
+		OnPanic:    []func(ctx context.Context, rcv interface{}, stack []byte){r.OnPanic},
+	}.Middleware()(http.HandlerFunc(func(_ http.ResponseWriter, _ *http.Request) {
+		panic("oops")
+	}))
+
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/foo", nil))
+	require.NoError(t, r.Close())
+
+	require.Len(t, envelope, 3)
+	assert.Contains(t, envelope[1], `{"type":"event","length":`)
+
+	var se map[string]interface{}
+
+	require.NoError(t, json.Unmarshal([]byte(envelope[2]), &se))
+	assert.Equal(t, "fatal", se["level"])
+	assert.Equal(t, "v1.2.3", se["release"])
+	assert.Equal(t, map[string]interface{}{
+		"method": "GET", "url": "/foo",
+		"headers": map[string]interface{}{"User-Agent": ""},
+		"env":     map[string]interface{}{"REMOTE_ADDR": "192.0.2.1:1234"},
+	}, se["request"])
+	assert.Contains(t, envelope[2], `"type":"string","value":"oops"`)
+
+	f, err := os.Open(file)
+	require.NoError(t, err)
+
+	defer f.Close()
+
+	s := bufio.NewScanner(f)
+	require.True(t, s.Scan())
+
+	var e report.Event
+
+	require.NoError(t, json.Unmarshal(s.Bytes(), &e))
+	assert.Equal(t, "oops", e.Message)
+	assert.Equal(t, "/foo", e.Request.URL)
+	require.NotEmpty(t, e.Stack)
+	assert.Contains(t, e.Stack[0].Function, "report_test.TestReporter_OnPanic.func")
+	assert.Contains(t, e.Stack[0].File, "reporter_test.go")
+}
+
+type sinkMock []report.Event
+
+func (s *sinkMock) Send(_ context.Context, e report.Event) error {
+	*s = append(*s, e)
+
+	return nil
+}
+
+func TestReporter_UseCaseMiddleware(t *testing.T) {
+	sink := &sinkMock{}
+	r := &report.Reporter{Sinks: []report.Sink{sink}, Logger: ctxd.NoOpLogger{}}
+
+	u := usecase.Wrap(usecase.NewInteractor(func(_ context.Context, in string, _ *struct{}) error {
+		if in == "internal" {
+			return errors.New("failed")
+		}
+
+		return status.NotFound
+	}), r.UseCaseMiddleware())
+
+	assert.Error(t, u.Interact(context.Background(), "internal", &struct{}{}))
+	assert.Error(t, u.Interact(context.Background(), "not found", &struct{}{}))
+	require.NoError(t, r.Close())
+
+	require.Len(t, *sink, 1)
+	assert.Equal(t, "failed", (*sink)[0].Message)
+	assert.Equal(t, "error", (*sink)[0].Level)
+}
+
+type stackError struct {
+	error
+	pcs []uintptr
+}
+
+func (e stackError) StackTrace() []uintptr {
+	return e.pcs
+}
+
+func newStackError(msg string) error {
+	pcs := make([]uintptr, 10)
+
+	return stackError{error: errors.New(msg), pcs: pcs[:runtime.Callers(2, pcs)]}
+}
+
+func TestReporter_ReportError_stack(t *testing.T) {
+	sink := &sinkMock{}
+	r := &report.Reporter{Sinks: []report.Sink{sink}, Logger: ctxd.NoOpLogger{}}
+
+	r.ReportError(context.Background(), errors.New("plain"))
+	r.ReportError(context.Background(), fmt.Errorf("wrapped: %w", newStackError("origin")))
+	require.NoError(t, r.Close())
+
+	require.Len(t, *sink, 2)
+	assert.Empty(t, (*sink)[0].Stack)
+
+	require.NotEmpty(t, (*sink)[1].Stack)
+	assert.Equal(t, "github.com/bool64/brick/report_test.TestReporter_ReportError_stack", (*sink)[1].Stack[0].Function)
+	assert.Contains(t, (*sink)[1].Stack[0].File, "reporter_test.go")
+}
+
+type blockingSink struct {
+	sinkMock
+	started chan struct{}
+	unblock chan struct{}
+	closed  bool
+}
+
+func (s *blockingSink) Send(ctx context.Context, e report.Event) error {
+	s.started <- struct{}{}
+	<-s.unblock
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	return s.sinkMock.Send(ctx, e)
+}
+
+func (s *blockingSink) Close() error {
+	s.closed = true
+
+	return nil
+}
+
+func TestReporter_Close(t *testing.T) {
+	sink := &blockingSink{started: make(chan struct{}, 10), unblock: make(chan struct{})}
+	r := &report.Reporter{Sinks: []report.Sink{sink}, Logger: ctxd.NoOpLogger{}, QueueSize: 2}
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	// First event is taken by delivery and blocks it, two more fill the queue, the last one is dropped.
+	for _, msg := range []string{"first", "second", "third", "fourth"} {
+		r.ReportError(ctx, errors.New(msg))
+
+		if msg == "first" {
+			<-sink.started
+		}
+	}
+
+	cancel()
+	close(sink.unblock)
+
+	require.NoError(t, r.Close())
+	require.NoError(t, r.Close())
+	r.ReportError(ctx, errors.New("closed"))
+
+	assert.True(t, sink.closed)
+	require.Len(t, sink.sinkMock, 3)
+	assert.Equal(t, "first", sink.sinkMock[0].Message)
+	assert.Equal(t, "third", sink.sinkMock[2].Message)
+}
@@ -0,0 +1,185 @@
+package report
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+var (
+	errMalformedDSN     = errors.New("malformed Sentry DSN, expected https://key@host/project")
+	errUnexpectedStatus = errors.New("unexpected response status")
+)
+
+// Sentry sends events to Sentry-compatible service using envelope endpoint.
+type Sentry struct {
+	Client *http.Client
+
+	dsn      string
+	endpoint string
+	auth     string
+}
+
+// NewSentry creates Sentry sink from DSN.
+func NewSentry(dsn string, timeout time.Duration) (*Sentry, error) {
+	u, err := url.Parse(dsn)
+	if err != nil {
+		return nil, err
+	}
+
+	if u.User == nil || u.User.Username() == "" {
+		return nil, errMalformedDSN
+	}
+
+	p := strings.TrimSuffix(u.Path, "/")
+	i := strings.LastIndex(p, "/")
+
+	if i < 0 || i == len(p)-1 {
+		return nil, errMalformedDSN
+	}
+
+	project := p[i+1:]
+
+	return &Sentry{
+		Client:   &http.Client{Timeout: timeout},
+		dsn:      dsn,
+		endpoint: u.Scheme + "://" + u.Host + p[:i] + "/api/" + project + "/envelope/",
+		auth:     "Sentry sentry_version=7, sentry_client=brick/1.0, sentry_key=" + u.User.Username(),
+	}, nil
+}
+
+type sentryFrame struct {
+	Function string `json:"function"`
+	AbsPath  string `json:"abs_path"`
+	Lineno   int    `json:"lineno"`
+	InApp    bool   `json:"in_app"`
+}
+
+type sentryStacktrace struct {
+	Frames []sentryFrame `json:"frames"`
+}
+
+type sentryException struct {
+	Type       string            `json:"type"`
+	Value      string            `json:"value"`
+	Stacktrace *sentryStacktrace `json:"stacktrace,omitempty"`
+}
+
+type sentryEvent struct {
+	EventID     string `json:"event_id"`
+	Timestamp   string `json:"timestamp"`
+	Level       string `json:"level"`
+	Platform    string `json:"platform"`
+	Release     string `json:"release,omitempty"`
+	Environment string `json:"environment,omitempty"`
+	ServerName  string `json:"server_name,omitempty"`
+	Exception   struct {
+		Values []sentryException `json:"values"`
+	} `json:"exception"`
+	Request  map[string]interface{} `json:"request,omitempty"`
+	Contexts map[string]interface{} `json:"contexts,omitempty"`
+	Extra    map[string]interface{} `json:"extra,omitempty"`
+}
+
+func (s *Sentry) envelope(e Event) ([]byte, error) {
+	se := sentryEvent{
+		EventID:     e.ID,
+		Timestamp:   e.Time.Format(time.RFC3339Nano),
+		Level:       e.Level,
+		Platform:    "go",
+		Release:     e.Release,
+		Environment: e.Environment,
+		ServerName:  e.ServerName,
+		Extra:       e.Extra,
+	}
+
+	ex := sentryException{Type: e.Type, Value: e.Message}
+
+	if len(e.Stack) > 0 {
+		ex.Stacktrace = &sentryStacktrace{}
+	}
+
+	// Sentry expects the innermost frame to be the last.
+	for i := len(e.Stack) - 1; i >= 0; i-- {
+		f := e.Stack[i]
+		ex.Stacktrace.Frames = append(ex.Stacktrace.Frames, sentryFrame{
+			Function: f.Function,
+			AbsPath:  f.File,
+			Lineno:   f.Line,
+			InApp:    !strings.HasPrefix(f.Function, "runtime.") && !strings.HasPrefix(f.Function, "net/http."),
+		})
+	}
+
+	se.Exception.Values = []sentryException{ex}
+
+	if e.Request != nil {
+		se.Request = map[string]interface{}{
+			"method":  e.Request.Method,
+			"url":     e.Request.URL,
+			"headers": map[string]string{"User-Agent": e.Request.UserAgent},
+			"env":     map[string]string{"REMOTE_ADDR": e.Request.ClientIP},
+		}
+	}
+
+	if e.TraceID != "" {
+		se.Contexts = map[string]interface{}{"trace": map[string]string{"trace_id": e.TraceID}}
+	}
+
+	payload, err := json.Marshal(se)
+	if err != nil {
+		return nil, err
+	}
+
+	header, err := json.Marshal(map[string]string{
+		"event_id": e.ID,
+		"sent_at":  time.Now().UTC().Format(time.RFC3339Nano),
+		"dsn":      s.dsn,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	item := `{"type":"event","length":` + fmt.Sprint(len(payload)) + `}`
+
+	return bytes.Join([][]byte{header, []byte(item), payload}, []byte("\n")), nil
+}
+
+// Send implements Sink.
+func (s *Sentry) Send(ctx context.Context, e Event) error {
+	body, err := s.envelope(e)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Content-Type", "application/x-sentry-envelope")
+	req.Header.Set("X-Sentry-Auth", s.auth)
+
+	resp, err := s.Client.Do(req)
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+
+	if resp.StatusCode >= http.StatusBadRequest {
+		b, _ := io.ReadAll(io.LimitReader(resp.Body, 1024)) //nolint:errcheck // Best effort error details.
+
+		return fmt.Errorf("%w %d: %s", errUnexpectedStatus, resp.StatusCode, string(b))
+	}
+
+	return nil
+}
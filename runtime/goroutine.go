@@ -0,0 +1,55 @@
+package runtime
+
+import (
+	"bytes"
+	"runtime"
+	"strconv"
+)
+
+// GoroutineID returns ID of current goroutine.
+//
+// It should only be used for diagnostics.
+func GoroutineID() uint64 {
+	buf := make([]byte, 64)
+	buf = buf[:runtime.Stack(buf, false)]
+
+	// Stack starts with "goroutine 123 [running]:".
+	buf = bytes.TrimPrefix(buf, []byte("goroutine "))
+
+	if i := bytes.IndexByte(buf, ' '); i > 0 {
+		buf = buf[:i]
+	}
+
+	id, err := strconv.ParseUint(string(buf), 10, 64)
+	if err != nil {
+		return 0
+	}
+
+	return id
+}
+
+// GoroutineStack returns stack trace of goroutine by ID or empty string if goroutine is not found.
+func GoroutineStack(id uint64) string {
+	buf := make([]byte, 1<<16)
+
+	for {
+		n := runtime.Stack(buf, true)
+		if n < len(buf) {
+			buf = buf[:n]
+
+			break
+		}
+
+		buf = make([]byte, 2*len(buf))
+	}
+
+	prefix := []byte("goroutine " + strconv.FormatUint(id, 10) + " [")
+
+	for _, g := range bytes.Split(buf, []byte("\n\n")) {
+		if bytes.HasPrefix(g, prefix) {
+			return string(g)
+		}
+	}
+
+	return ""
+}
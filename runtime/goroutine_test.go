@@ -0,0 +1,24 @@
+package runtime_test
+
+import (
+	"testing"
+
+	"github.com/bool64/brick/runtime"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGoroutineStack(t *testing.T) {
+	id := make(chan uint64)
+	done := make(chan struct{})
+
+	go func() {
+		id <- runtime.GoroutineID()
+		<-done
+	}()
+
+	st := runtime.GoroutineStack(<-id)
+	close(done)
+
+	assert.Contains(t, st, "[chan receive]")
+	assert.Contains(t, st, "runtime_test.TestGoroutineStack.func1")
+}
@@ -0,0 +1,210 @@
+package tracing
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/swaggest/rest"
+)
+
+const redacted = "[redacted]"
+
+// TagName is a name of struct tag that declares span attributes.
+//
+// Field tagged with `trace:"user.id"` becomes span attribute "user.id",
+// option "redact" (`trace:"user.email,redact"`) replaces attribute value with "[redacted]",
+// `trace:",redact"` only redacts field when input is recorded with UseCaseMiddleware.WithInput.
+//
+// Fields of embedded structs are also collected.
+const TagName = "trace"
+
+type fieldAttribute struct {
+	index  []int
+	key    string
+	redact bool
+}
+
+type typeAttributes struct {
+	fields []fieldAttribute
+	redact bool
+}
+
+var attributeFields sync.Map // map[reflect.Type]typeAttributes
+
+func structFields(t reflect.Type) typeAttributes {
+	if cached, ok := attributeFields.Load(t); ok {
+		return cached.(typeAttributes) //nolint:errcheck // Type is known.
+	}
+
+	var res typeAttributes
+
+	collectFields(t, nil, &res)
+	attributeFields.Store(t, res)
+
+	return res
+}
+
+func collectFields(t reflect.Type, index []int, res *typeAttributes) {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		idx := append(index[:len(index):len(index)], i)
+
+		tag, ok := f.Tag.Lookup(TagName)
+		if !ok {
+			if f.Anonymous && f.Type.Kind() == reflect.Struct {
+				collectFields(f.Type, idx, res)
+			}
+
+			continue
+		}
+
+		if !f.IsExported() || tag == "-" {
+			continue
+		}
+
+		key, opts, _ := strings.Cut(tag, ",")
+		fa := fieldAttribute{index: idx, key: key, redact: opts == "redact"}
+		res.redact = res.redact || fa.redact
+
+		res.fields = append(res.fields, fa)
+	}
+}
+
+func structValue(v interface{}) (reflect.Value, bool) {
+	rv := reflect.ValueOf(v)
+
+	for rv.Kind() == reflect.Ptr || rv.Kind() == reflect.Interface {
+		if rv.IsNil() {
+			return rv, false
+		}
+
+		rv = rv.Elem()
+	}
+
+	return rv, rv.Kind() == reflect.Struct
+}
+
+// StructAttributes collects span attributes from fields of a struct that are tagged with `trace`, see TagName.
+//
+// Nil pointer fields are skipped.
+//
+//	ctx, finish := tracing.AddSpan(ctx, tracing.StructAttributes(req)...)
+func StructAttributes(v interface{}) []Attribute {
+	rv, ok := structValue(v)
+	if !ok {
+		return nil
+	}
+
+	fields := structFields(rv.Type()).fields
+	if len(fields) == 0 {
+		return nil
+	}
+
+	res := make([]Attribute, 0, len(fields))
+
+	for _, f := range fields {
+		if f.key == "" {
+			continue
+		}
+
+		if f.redact {
+			res = append(res, String(f.key, redacted))
+
+			continue
+		}
+
+		fv := rv.FieldByIndex(f.index)
+		if (fv.Kind() == reflect.Ptr || fv.Kind() == reflect.Interface) && fv.IsNil() {
+			continue
+		}
+
+		res = append(res, Attribute{Key: f.key, Value: attributeValue(fv)})
+	}
+
+	return res
+}
+
+func attributeValue(v reflect.Value) interface{} {
+	if s, ok := v.Interface().(fmt.Stringer); ok {
+		return s.String()
+	}
+
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		v = v.Elem()
+	}
+
+	switch v.Kind() { //nolint:exhaustive // Other kinds are formatted.
+	case reflect.String:
+		return v.String()
+	case reflect.Bool:
+		return v.Bool()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return v.Int()
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return int64(v.Uint()) //nolint:gosec // Overflow is tolerable for attribute.
+	case reflect.Float32, reflect.Float64:
+		return v.Float()
+	default:
+		return fmt.Sprintf("%v", v.Interface())
+	}
+}
+
+// formatRedacted formats value with fields that have redact option replaced.
+func formatRedacted(v interface{}) string {
+	rv, ok := structValue(v)
+	if !ok {
+		return fmt.Sprintf("%v", v)
+	}
+
+	ta := structFields(rv.Type())
+	if !ta.redact {
+		return fmt.Sprintf("%v", v)
+	}
+
+	cp := reflect.New(rv.Type()).Elem()
+	cp.Set(rv)
+
+	for _, f := range ta.fields {
+		if !f.redact {
+			continue
+		}
+
+		fv := cp.FieldByIndex(f.index)
+		if fv.Kind() == reflect.String {
+			fv.SetString(redacted)
+		} else {
+			fv.Set(reflect.Zero(fv.Type()))
+		}
+	}
+
+	return fmt.Sprintf("%v", cp.Interface())
+}
+
+// RecordError marks span as failed and adds event with details of validation errors.
+func RecordError(span Span, err error) {
+	var ve rest.ValidationErrors
+
+	if errors.As(err, &ve) {
+		fields := make([]string, 0, len(ve))
+
+		for f := range ve {
+			fields = append(fields, f)
+		}
+
+		sort.Strings(fields)
+
+		attrs := make([]Attribute, 0, len(fields))
+
+		for _, f := range fields {
+			attrs = append(attrs, String("validation."+f, strings.Join(ve[f], "; ")))
+		}
+
+		span.AddEvent("validation failed", attrs...)
+	}
+
+	span.SetError(err)
+}
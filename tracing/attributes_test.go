@@ -0,0 +1,191 @@
+package tracing_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/bool64/brick/tracing"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/swaggest/openapi-go/openapi3"
+	"github.com/swaggest/rest/nethttp"
+	"github.com/swaggest/rest/web"
+	"github.com/swaggest/usecase"
+	"go.opencensus.io/trace"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+type Tenant struct {
+	TenantID string `query:"tenant" trace:"tenant.id"`
+}
+
+type createUserReq struct {
+	Tenant
+	Name     string  `json:"name" trace:"user.name"`
+	Email    string  `json:"email" trace:"user.email,redact"`
+	Password string  `json:"password" trace:",redact"`
+	Age      uint    `json:"age" minimum:"18" trace:"user.age"`
+	Ref      *string `json:"ref" trace:"ref"`
+	internal string  `trace:"internal"`
+}
+
+type createUserResp struct {
+	ID int `json:"id" trace:"user.id"`
+}
+
+func TestStructAttributes(t *testing.T) {
+	ref := "promo"
+
+	assert.Nil(t, tracing.StructAttributes(nil))
+	assert.Nil(t, tracing.StructAttributes(1))
+	assert.Equal(t, []tracing.Attribute{
+		tracing.String("tenant.id", "acme"),
+		tracing.String("user.name", "John"),
+		tracing.String("user.email", "[redacted]"),
+		tracing.Int64("user.age", 42),
+		tracing.String("ref", "promo"),
+	}, tracing.StructAttributes(&createUserReq{
+		Tenant:   Tenant{TenantID: "acme"},
+		Name:     "John",
+		Email:    "john@example.com",
+		Password: "secret",
+		Age:      42,
+		Ref:      &ref,
+		internal: "foo",
+	}))
+}
+
+func TestUseCaseMiddleware_attributes(t *testing.T) {
+	tr, exp := newOpenTelemetry()
+
+	u := usecase.NewInteractor(func(_ context.Context, in createUserReq, out *createUserResp) error {
+		if in.Name == "" {
+			return errors.New("name is required")
+		}
+
+		out.ID = 123
+
+		return nil
+	})
+	u.SetName("createUser")
+
+	s := web.NewService(openapi3.NewReflector())
+	s.Wrap(nethttp.UseCaseMiddlewares(tracing.UseCaseMiddleware{Tracer: tr, WithInput: true}))
+	s.Post("/users", u)
+
+	for _, body := range []string{
+		`{"name":"John","email":"john@example.com","password":"secret","age":42}`,
+		`{"name":"John","age":10}`,
+		`{"age":42}`,
+	} {
+		req := httptest.NewRequest(http.MethodPost, "/users?tenant=acme", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+
+		s.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	spans := exp.GetSpans()
+	require.Len(t, spans, 3)
+
+	ok, invalid, failed := spans[0], spans[1], spans[2]
+
+	assert.Contains(t, ok.Attributes, attribute.String("tenant.id", "acme"))
+	assert.Contains(t, ok.Attributes, attribute.String("user.email", "[redacted]"))
+	assert.Contains(t, ok.Attributes, attribute.Int64("user.id", 123))
+	assert.Contains(t, ok.Attributes, attribute.String("input", "{{acme} John [redacted] [redacted] 42 <nil> }"))
+	assert.Empty(t, ok.Events)
+
+	require.Len(t, invalid.Events, 2)
+	assert.Equal(t, "validation failed", invalid.Events[0].Name)
+	assert.Equal(t, []attribute.KeyValue{
+		attribute.String("validation.body", "#/age: must be >= 18/1 but found 10"),
+	}, invalid.Events[0].Attributes)
+	assert.Equal(t, "exception", invalid.Events[1].Name)
+	assert.Contains(t, invalid.Attributes, attribute.String("status", "INVALID_ARGUMENT"))
+
+	require.Len(t, failed.Events, 1)
+	assert.Equal(t, "exception", failed.Events[0].Name)
+	assert.NotContains(t, failed.Attributes, attribute.Int64("user.id", 0))
+}
+
+func TestHTTPErrorMiddleware(t *testing.T) {
+	tr, exp := newOpenTelemetry()
+
+	u := usecase.NewInteractor(func(_ context.Context, _ createUserReq, _ *createUserResp) error {
+		return nil
+	})
+	u.SetName("createUser")
+
+	plain := web.NewService(openapi3.NewReflector())
+	plain.Wrap(tr.HTTPMiddleware, nethttp.UseCaseMiddlewares(tracing.HTTPErrorMiddleware{}))
+	plain.Post("/users", u)
+
+	traced := web.NewService(openapi3.NewReflector())
+	traced.Wrap(tr.HTTPMiddleware, nethttp.UseCaseMiddlewares(tracing.HTTPErrorMiddleware{}, tracing.UseCaseMiddleware{Tracer: tr}))
+	traced.Post("/users", u)
+
+	for _, s := range []*web.Service{plain, traced} {
+		req := httptest.NewRequest(http.MethodPost, "/users", strings.NewReader(`{"age":10}`))
+		req.Header.Set("Content-Type", "application/json")
+
+		rw := httptest.NewRecorder()
+		s.ServeHTTP(rw, req)
+		assert.Equal(t, http.StatusBadRequest, rw.Code)
+	}
+
+	spans := exp.GetSpans()
+	require.Len(t, spans, 3)
+
+	// Use case is not invoked, error is recorded in server span.
+	srv := spans[0]
+	assert.Equal(t, "POST /users", srv.Name)
+	require.Len(t, srv.Events, 2)
+	assert.Equal(t, "validation failed", srv.Events[0].Name)
+	assert.Contains(t, srv.Attributes, attribute.String("status", "INVALID_ARGUMENT"))
+
+	// Decoding error is passed through use case middleware and is not recorded again.
+	uc, srv := spans[1], spans[2]
+	assert.Equal(t, "createUser", uc.Name)
+	require.Len(t, uc.Events, 2)
+	assert.Equal(t, "validation failed", uc.Events[0].Name)
+	assert.Empty(t, srv.Events)
+}
+
+func TestAddSpan_openCensus(t *testing.T) {
+	var exp spanRecorder
+
+	trace.RegisterExporter(&exp)
+	defer trace.UnregisterExporter(&exp)
+
+	ctx, root := trace.StartSpan(context.Background(), "root", trace.WithSampler(trace.AlwaysSample()))
+
+	func() {
+		err := errors.New("failed")
+
+		_, finish := tracing.AddSpan(ctx, tracing.StructAttributes(createUserReq{Email: "john@example.com"})...)
+		finish(&err)
+	}()
+
+	root.End()
+
+	require.Len(t, exp.spans, 2)
+
+	sd := exp.spans[0]
+	assert.Equal(t, "[redacted]", sd.Attributes["user.email"])
+	require.Len(t, sd.Annotations, 1)
+	assert.Equal(t, "exception", sd.Annotations[0].Message)
+	assert.Equal(t, "failed", sd.Annotations[0].Attributes["exception.message"])
+	assert.Equal(t, "*errors.errorString", sd.Annotations[0].Attributes["exception.type"])
+}
+
+type spanRecorder struct {
+	spans []*trace.SpanData
+}
+
+func (r *spanRecorder) ExportSpan(sd *trace.SpanData) {
+	r.spans = append(r.spans, sd)
+}
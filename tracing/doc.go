@@ -0,0 +1,2 @@
+// Package tracing provides backend agnostic tracing with OpenCensus and OpenTelemetry implementations.
+package tracing
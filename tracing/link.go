@@ -0,0 +1,66 @@
+package tracing
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel/propagation"
+)
+
+// TraceLink is a serializable span context to continue a trace in async work.
+//
+// It can be embedded in a message or job payload by producer and used with StartLinked by consumer.
+//
+//	job := Job{Trace: tracing.LinkFromContext(ctx), ...}
+//
+//	ctx, span := tracing.StartLinked(ctx, "processJob", job.Trace)
+//	defer span.End()
+type TraceLink struct {
+	// Traceparent is W3C traceparent value, for example "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01".
+	Traceparent string `json:"traceparent,omitempty"`
+
+	// Tracestate is W3C tracestate value.
+	Tracestate string `json:"tracestate,omitempty"`
+}
+
+// LinkFromContext returns TraceLink of current span, or empty TraceLink if there is no span.
+func LinkFromContext(ctx context.Context) TraceLink {
+	span := FromContext(ctx)
+	if span == nil {
+		return TraceLink{}
+	}
+
+	sc := span.SpanContext()
+	if !sc.IsValid() {
+		return TraceLink{}
+	}
+
+	carrier := propagation.MapCarrier{}
+	inject(PropagationW3C, sc, carrier)
+
+	return TraceLink{
+		Traceparent: carrier[headerTraceparent],
+		Tracestate:  carrier[headerTracestate],
+	}
+}
+
+// SpanContext returns linked span context, false is returned if link is empty or malformed.
+func (l TraceLink) SpanContext() (SpanContext, bool) {
+	if l.Traceparent == "" {
+		return SpanContext{}, false
+	}
+
+	return extractW3C(propagation.MapCarrier{
+		headerTraceparent: l.Traceparent,
+		headerTracestate:  l.Tracestate,
+	})
+}
+
+// StartLinked starts a new trace with default tracer, root span is linked to span of TraceLink.
+//
+// New trace is started even if context has current span, so that async work of a request does not
+// extend request trace. Linked span is sampled if span of TraceLink is sampled.
+func StartLinked(ctx context.Context, name string, link TraceLink, attributes ...Attribute) (context.Context, Span) {
+	sc, _ := link.SpanContext()
+
+	return Default().StartLinked(ctx, name, sc, attributes...)
+}
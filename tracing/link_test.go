@@ -0,0 +1,100 @@
+package tracing_test
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/bool64/brick/tracing"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	octrace "go.opencensus.io/trace"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+type job struct {
+	Trace tracing.TraceLink `json:"trace"`
+}
+
+func TestStartLinked_openTelemetry(t *testing.T) {
+	assert.Equal(t, tracing.TraceLink{}, tracing.LinkFromContext(context.Background()))
+
+	s, err := tracing.NewSampler(tracing.Sampling{
+		Routes: map[string]float64{"POST /jobs": 1},
+	})
+	require.NoError(t, err)
+
+	exp := tracetest.NewInMemoryExporter()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exp), sdktrace.WithSampler(s.OpenTelemetry()))
+	tr := tracing.NewOpenTelemetry(tp)
+
+	tracing.SetDefault(tr)
+	defer tracing.SetDefault(tracing.OpenCensus{})
+
+	ctx, producer := tr.Start(context.Background(), "POST /jobs")
+	payload, err := json.Marshal(job{Trace: tracing.LinkFromContext(ctx)})
+	require.NoError(t, err)
+	producer.End()
+
+	var j job
+
+	require.NoError(t, json.Unmarshal(payload, &j))
+
+	sc, ok := j.Trace.SpanContext()
+	require.True(t, ok)
+	assert.Equal(t, producer.SpanContext(), sc)
+
+	// Consumer is started in the context of another span, but a new trace is linked to producer.
+	ctx, other := tr.Start(context.Background(), "poll")
+	_, consumer := tracing.StartLinked(ctx, "processJob", j.Trace)
+	consumer.End()
+	other.End()
+
+	spans := exp.GetSpans()
+	require.Len(t, spans, 2, "poll is not sampled by rules")
+
+	sd := spans[1]
+	assert.Equal(t, "processJob", sd.Name)
+	assert.False(t, sd.Parent.IsValid())
+	assert.NotEqual(t, sc.TraceIDString(), sd.SpanContext.TraceID().String())
+	require.Len(t, sd.Links, 1)
+	assert.Equal(t, sc.SpanIDString(), sd.Links[0].SpanContext.SpanID().String())
+
+	_, unlinked := tracing.StartLinked(context.Background(), "processJob", tracing.TraceLink{Traceparent: "invalid"})
+	unlinked.End()
+	assert.Len(t, exp.GetSpans(), 2)
+}
+
+func TestStartLinked_openCensus(t *testing.T) {
+	var exp spanRecorder
+
+	octrace.RegisterExporter(&exp)
+	defer octrace.UnregisterExporter(&exp)
+
+	ctx, producer := octrace.StartSpan(context.Background(), "POST /jobs", octrace.WithSampler(octrace.AlwaysSample()))
+	link := tracing.LinkFromContext(ctx)
+	producer.End()
+
+	_, consumer := tracing.OpenCensus{}.StartLinked(ctx, "processJob", mustSpanContext(t, link))
+	consumer.End()
+
+	require.Len(t, exp.spans, 2)
+
+	sd := exp.spans[1]
+	assert.Equal(t, "processJob", sd.Name)
+	assert.NotEqual(t, producer.SpanContext().TraceID, sd.TraceID)
+	assert.Equal(t, octrace.SpanID{}, sd.ParentSpanID)
+	require.Len(t, sd.Links, 1)
+	assert.Equal(t, producer.SpanContext().TraceID, sd.Links[0].TraceID)
+	assert.Equal(t, producer.SpanContext().SpanID, sd.Links[0].SpanID)
+}
+
+func mustSpanContext(t *testing.T, l tracing.TraceLink) tracing.SpanContext {
+	t.Helper()
+
+	sc, ok := l.SpanContext()
+	require.True(t, ok)
+
+	return sc
+}
@@ -0,0 +1,148 @@
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/bool64/brick/opencensus"
+	"go.opencensus.io/trace"
+	"go.opencensus.io/trace/propagation"
+)
+
+// OpenCensus is a tracer with go.opencensus.io backend.
+type OpenCensus struct {
+	// Propagation extracts remote span context of incoming HTTP requests, default B3.
+	// Use (*Propagator).OpenCensus to select formats.
+	Propagation propagation.HTTPFormat
+}
+
+// Start implements Tracer.
+func (OpenCensus) Start(ctx context.Context, name string, attributes ...Attribute) (context.Context, Span) {
+	ctx, span := trace.StartSpan(ctx, name) //nolint:spancheck // Span is ended by caller.
+
+	s := ocSpan{span}
+	s.SetAttributes(attributes...)
+
+	return ctx, s //nolint:spancheck // Span is ended by caller.
+}
+
+// StartClient implements ClientTracer.
+func (OpenCensus) StartClient(ctx context.Context, name string, attributes ...Attribute) (context.Context, Span) {
+	ctx, span := trace.StartSpan(ctx, name, trace.WithSpanKind(trace.SpanKindClient)) //nolint:spancheck // Span is ended by caller.
+
+	s := ocSpan{span}
+	s.SetAttributes(attributes...)
+
+	return ctx, s //nolint:spancheck // Span is ended by caller.
+}
+
+// StartLinked implements Tracer.
+func (OpenCensus) StartLinked(
+	ctx context.Context,
+	name string,
+	link SpanContext,
+	attributes ...Attribute,
+) (context.Context, Span) {
+	var opts []trace.StartOption
+
+	if link.IsValid() && link.Sampled {
+		opts = append(opts, trace.WithSampler(trace.AlwaysSample()))
+	}
+
+	// Empty remote parent starts a new trace.
+	ctx, span := trace.StartSpanWithRemoteParent(ctx, name, trace.SpanContext{}, opts...) //nolint:spancheck // Span is ended by caller.
+
+	if link.IsValid() {
+		span.AddLink(trace.Link{
+			TraceID: link.TraceID,
+			SpanID:  link.SpanID,
+			Type:    trace.LinkTypeParent,
+		})
+	}
+
+	s := ocSpan{span}
+	s.SetAttributes(attributes...)
+
+	return ctx, s //nolint:spancheck // Span is ended by caller.
+}
+
+// HTTPMiddleware implements Tracer.
+func (o OpenCensus) HTTPMiddleware(handler http.Handler) http.Handler {
+	return opencensus.PropagationMiddleware(o.Propagation)(handler)
+}
+
+type ocSpan struct {
+	s *trace.Span
+}
+
+func (s ocSpan) SpanContext() SpanContext {
+	sc := s.s.SpanContext()
+
+	return SpanContext{
+		TraceID:    sc.TraceID,
+		SpanID:     sc.SpanID,
+		Sampled:    sc.IsSampled(),
+		TraceState: ocTracestateString(sc.Tracestate),
+	}
+}
+
+func (s ocSpan) SetAttributes(attributes ...Attribute) {
+	if len(attributes) == 0 || !s.s.IsRecordingEvents() {
+		return
+	}
+
+	s.s.AddAttributes(ocAttributes(attributes)...)
+}
+
+func (s ocSpan) AddEvent(name string, attributes ...Attribute) {
+	if !s.s.IsRecordingEvents() {
+		return
+	}
+
+	s.s.Annotate(ocAttributes(attributes), name)
+}
+
+func (s ocSpan) SetError(err error) {
+	// Event mirrors exception event of OpenTelemetry.
+	s.AddEvent("exception",
+		String("exception.type", fmt.Sprintf("%T", err)),
+		String("exception.message", err.Error()),
+	)
+
+	s.s.SetStatus(trace.Status{
+		Code:    int32(errorStatus(err)), //nolint:gosec // Status codes are small.
+		Message: err.Error(),
+	})
+}
+
+func (s ocSpan) End() {
+	s.s.End()
+}
+
+func ocAttributes(attributes []Attribute) []trace.Attribute {
+	if len(attributes) == 0 {
+		return nil
+	}
+
+	attrs := make([]trace.Attribute, 0, len(attributes))
+
+	for _, a := range attributes {
+		switch v := a.Value.(type) {
+		case string:
+			attrs = append(attrs, trace.StringAttribute(a.Key, v))
+		case int64:
+			attrs = append(attrs, trace.Int64Attribute(a.Key, v))
+		case int:
+			attrs = append(attrs, trace.Int64Attribute(a.Key, int64(v)))
+		case float64:
+			attrs = append(attrs, trace.Float64Attribute(a.Key, v))
+		case bool:
+			attrs = append(attrs, trace.BoolAttribute(a.Key, v))
+		default:
+			attrs = append(attrs, trace.StringAttribute(a.Key, fmt.Sprintf("%v", v)))
+		}
+	}
+
+	return attrs
+}
@@ -0,0 +1,204 @@
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/swaggest/rest"
+	"github.com/swaggest/rest/nethttp"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// InstrumentationName identifies spans created by this package.
+const InstrumentationName = "github.com/bool64/brick"
+
+// OpenTelemetry is a tracer with OpenTelemetry backend.
+//
+// Please use NewOpenTelemetry to create an instance.
+type OpenTelemetry struct {
+	// Propagator extracts remote span context of incoming HTTP requests, default W3C Trace Context.
+	// Use *Propagator to select formats.
+	Propagator propagation.TextMapPropagator
+
+	tracer trace.Tracer
+}
+
+// NewOpenTelemetry creates tracer with provider, for example *go.opentelemetry.io/otel/sdk/trace.TracerProvider.
+func NewOpenTelemetry(tp trace.TracerProvider) *OpenTelemetry {
+	return &OpenTelemetry{
+		Propagator: propagation.TraceContext{},
+		tracer:     tp.Tracer(InstrumentationName),
+	}
+}
+
+// Start implements Tracer.
+func (o *OpenTelemetry) Start(ctx context.Context, name string, attributes ...Attribute) (context.Context, Span) {
+	ctx, span := o.tracer.Start(ctx, name, trace.WithAttributes(otelAttributes(attributes)...)) //nolint:spancheck // Span is ended by caller.
+
+	return ctx, otelSpan{span} //nolint:spancheck // Span is ended by caller.
+}
+
+// StartClient implements ClientTracer.
+func (o *OpenTelemetry) StartClient(ctx context.Context, name string, attributes ...Attribute) (context.Context, Span) {
+	ctx, span := o.tracer.Start(ctx, name, //nolint:spancheck // Span is ended by caller.
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(otelAttributes(attributes)...),
+	)
+
+	return ctx, otelSpan{span} //nolint:spancheck // Span is ended by caller.
+}
+
+// StartLinked implements Tracer.
+//
+// Sampler of tracer provider decides if linked span is sampled, Sampler follows decision of linked span.
+func (o *OpenTelemetry) StartLinked(
+	ctx context.Context,
+	name string,
+	link SpanContext,
+	attributes ...Attribute,
+) (context.Context, Span) {
+	opts := []trace.SpanStartOption{trace.WithNewRoot(), trace.WithAttributes(otelAttributes(attributes)...)}
+
+	if link.IsValid() {
+		sc := trace.SpanContextConfig{
+			TraceID: link.TraceID,
+			SpanID:  link.SpanID,
+			Remote:  true,
+		}
+
+		if link.Sampled {
+			sc.TraceFlags = trace.FlagsSampled
+		}
+
+		opts = append(opts, trace.WithLinks(trace.Link{SpanContext: trace.NewSpanContext(sc)}))
+	}
+
+	ctx, span := o.tracer.Start(ctx, name, opts...) //nolint:spancheck // Span is ended by caller.
+
+	return ctx, otelSpan{span} //nolint:spancheck // Span is ended by caller.
+}
+
+// HTTPMiddleware implements Tracer.
+func (o *OpenTelemetry) HTTPMiddleware(handler http.Handler) http.Handler {
+	var (
+		withRoute     rest.HandlerWithRoute
+		method, route string
+	)
+
+	if nethttp.HandlerAs(handler, &withRoute) {
+		method = withRoute.RouteMethod()
+		route = withRoute.RoutePattern()
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := o.Propagator.Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+
+		name := method + " " + route
+		if route == "" {
+			name = r.Method + " " + r.URL.Path
+		}
+
+		attrs := []attribute.KeyValue{
+			attribute.String("http.request.method", r.Method),
+			attribute.String("url.path", r.URL.Path),
+		}
+
+		if route != "" {
+			attrs = append(attrs, attribute.String("http.route", route))
+		}
+
+		ctx, span := o.tracer.Start(ctx, name,
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(attrs...),
+		)
+		defer span.End()
+
+		ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+
+		handler.ServeHTTP(ww, r.WithContext(ctx))
+
+		st := ww.Status()
+		if st == 0 {
+			st = http.StatusOK
+		}
+
+		span.SetAttributes(attribute.Int("http.response.status_code", st))
+
+		if st >= http.StatusInternalServerError {
+			span.SetStatus(codes.Error, http.StatusText(st))
+		}
+	})
+}
+
+type otelSpan struct {
+	s trace.Span
+}
+
+func (s otelSpan) SpanContext() SpanContext {
+	sc := s.s.SpanContext()
+
+	return SpanContext{
+		TraceID:    sc.TraceID(),
+		SpanID:     sc.SpanID(),
+		Sampled:    sc.IsSampled(),
+		TraceState: sc.TraceState().String(),
+	}
+}
+
+func (s otelSpan) SetAttributes(attributes ...Attribute) {
+	if len(attributes) == 0 || !s.s.IsRecording() {
+		return
+	}
+
+	s.s.SetAttributes(otelAttributes(attributes)...)
+}
+
+func (s otelSpan) AddEvent(name string, attributes ...Attribute) {
+	if !s.s.IsRecording() {
+		return
+	}
+
+	s.s.AddEvent(name, trace.WithAttributes(otelAttributes(attributes)...))
+}
+
+func (s otelSpan) SetError(err error) {
+	s.s.RecordError(err)
+	s.s.SetAttributes(attribute.String("status", errorStatus(err).String()))
+	s.s.SetStatus(codes.Error, err.Error())
+}
+
+func (s otelSpan) End() {
+	s.s.End()
+}
+
+func otelAttributes(attributes []Attribute) []attribute.KeyValue {
+	if len(attributes) == 0 {
+		return nil
+	}
+
+	res := make([]attribute.KeyValue, 0, len(attributes))
+
+	for _, a := range attributes {
+		switch v := a.Value.(type) {
+		case string:
+			res = append(res, attribute.String(a.Key, v))
+		case int64:
+			res = append(res, attribute.Int64(a.Key, v))
+		case int:
+			res = append(res, attribute.Int(a.Key, v))
+		case float64:
+			res = append(res, attribute.Float64(a.Key, v))
+		case bool:
+			res = append(res, attribute.Bool(a.Key, v))
+		default:
+			res = append(res, attribute.String(a.Key, fmt.Sprintf("%v", v)))
+		}
+	}
+
+	return res
+}
@@ -0,0 +1,422 @@
+package tracing
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	octrace "go.opencensus.io/trace"
+	ocpropagation "go.opencensus.io/trace/propagation"
+	"go.opencensus.io/trace/tracestate"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Propagation formats.
+const (
+	// PropagationW3C is W3C Trace Context with traceparent and tracestate headers.
+	PropagationW3C = "w3c"
+
+	// PropagationB3 is Zipkin B3 single b3 header.
+	PropagationB3 = "b3"
+
+	// PropagationB3Multi is Zipkin B3 with multiple X-B3-* headers.
+	PropagationB3Multi = "b3multi"
+
+	// PropagationJaeger is Jaeger uber-trace-id header.
+	PropagationJaeger = "jaeger"
+)
+
+// Propagation headers.
+const (
+	headerTraceparent = "traceparent"
+	headerTracestate  = "tracestate"
+	headerB3          = "b3"
+	headerB3TraceID   = "X-B3-TraceId"
+	headerB3SpanID    = "X-B3-SpanId"
+	headerB3Sampled   = "X-B3-Sampled"
+	headerB3Flags     = "X-B3-Flags"
+	headerJaeger      = "uber-trace-id"
+)
+
+var errUnknownPropagation = errors.New("unknown trace propagation format, expected w3c, b3, b3multi or jaeger")
+
+// Propagation selects formats of span context in HTTP headers.
+type Propagation struct {
+	// Inbound is a fallback chain of formats to extract remote span context of incoming requests,
+	// first format that has a valid span context wins.
+	Inbound []string `default:"w3c,b3,b3multi,jaeger"`
+
+	// Outbound is a list of formats to inject span context into outgoing requests, all of them are injected.
+	Outbound []string `default:"w3c"`
+}
+
+// Validate checks configuration.
+func (p Propagation) Validate() error {
+	for _, f := range append(append([]string{}, p.Inbound...), p.Outbound...) {
+		switch normalizeFormat(f) {
+		case PropagationW3C, PropagationB3, PropagationB3Multi, PropagationJaeger:
+		default:
+			return fmt.Errorf("%w: %s", errUnknownPropagation, f)
+		}
+	}
+
+	return nil
+}
+
+func normalizeFormat(f string) string {
+	return strings.ToLower(strings.TrimSpace(f))
+}
+
+// Propagator extracts and injects span context in HTTP headers with configured formats.
+//
+// It implements go.opentelemetry.io/otel/propagation.TextMapPropagator,
+// OpenCensus format is available with OpenCensus method.
+//
+// Please use NewPropagator to create an instance.
+type Propagator struct {
+	inbound  []string
+	outbound []string
+}
+
+var (
+	_ propagation.TextMapPropagator = &Propagator{}
+
+	defaultPropagator = &Propagator{
+		inbound:  []string{PropagationW3C, PropagationB3, PropagationB3Multi, PropagationJaeger},
+		outbound: []string{PropagationW3C},
+	}
+)
+
+// NewPropagator creates propagator.
+//
+// Empty inbound chain defaults to all formats, starting with W3C Trace Context,
+// empty outbound list defaults to W3C Trace Context.
+func NewPropagator(cfg Propagation) (*Propagator, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+
+	p := &Propagator{}
+
+	for _, f := range cfg.Inbound {
+		p.inbound = append(p.inbound, normalizeFormat(f))
+	}
+
+	for _, f := range cfg.Outbound {
+		p.outbound = append(p.outbound, normalizeFormat(f))
+	}
+
+	if len(p.inbound) == 0 {
+		p.inbound = defaultPropagator.inbound
+	}
+
+	if len(p.outbound) == 0 {
+		p.outbound = defaultPropagator.outbound
+	}
+
+	return p, nil
+}
+
+// Extract implements propagation.TextMapPropagator.
+func (p *Propagator) Extract(ctx context.Context, carrier propagation.TextMapCarrier) context.Context {
+	sc, ok := p.extract(carrier)
+	if !ok {
+		return ctx
+	}
+
+	cfg := trace.SpanContextConfig{
+		TraceID: sc.TraceID,
+		SpanID:  sc.SpanID,
+		Remote:  true,
+	}
+
+	if sc.Sampled {
+		cfg.TraceFlags = trace.FlagsSampled
+	}
+
+	if sc.TraceState != "" {
+		if ts, err := trace.ParseTraceState(sc.TraceState); err == nil {
+			cfg.TraceState = ts
+		}
+	}
+
+	return trace.ContextWithRemoteSpanContext(ctx, trace.NewSpanContext(cfg))
+}
+
+// Inject implements propagation.TextMapPropagator.
+//
+// Span of any backend is injected from context.
+func (p *Propagator) Inject(ctx context.Context, carrier propagation.TextMapCarrier) {
+	s := FromContext(ctx)
+	if s == nil {
+		return
+	}
+
+	sc := s.SpanContext()
+	if !sc.IsValid() {
+		return
+	}
+
+	for _, f := range p.outbound {
+		inject(f, sc, carrier)
+	}
+}
+
+// Fields implements propagation.TextMapPropagator.
+func (p *Propagator) Fields() []string {
+	var res []string
+
+	for _, f := range p.outbound {
+		switch f {
+		case PropagationW3C:
+			res = append(res, headerTraceparent, headerTracestate)
+		case PropagationB3:
+			res = append(res, headerB3)
+		case PropagationB3Multi:
+			res = append(res, headerB3TraceID, headerB3SpanID, headerB3Sampled, headerB3Flags)
+		case PropagationJaeger:
+			res = append(res, headerJaeger)
+		}
+	}
+
+	return res
+}
+
+// OpenCensus returns HTTP format for go.opencensus.io/plugin/ochttp.
+func (p *Propagator) OpenCensus() ocpropagation.HTTPFormat {
+	return ocFormat{p: p}
+}
+
+func (p *Propagator) extract(carrier propagation.TextMapCarrier) (SpanContext, bool) {
+	for _, f := range p.inbound {
+		var (
+			sc SpanContext
+			ok bool
+		)
+
+		switch f {
+		case PropagationW3C:
+			sc, ok = extractW3C(carrier)
+		case PropagationB3:
+			sc, ok = extractB3(carrier)
+		case PropagationB3Multi:
+			sc, ok = extractB3Multi(carrier)
+		case PropagationJaeger:
+			sc, ok = extractJaeger(carrier)
+		}
+
+		if ok && sc.IsValid() {
+			return sc, true
+		}
+	}
+
+	return SpanContext{}, false
+}
+
+func inject(format string, sc SpanContext, carrier propagation.TextMapCarrier) {
+	sampled := "0"
+	if sc.Sampled {
+		sampled = "1"
+	}
+
+	switch format {
+	case PropagationW3C:
+		carrier.Set(headerTraceparent, "00-"+sc.TraceIDString()+"-"+sc.SpanIDString()+"-0"+sampled)
+
+		if sc.TraceState != "" {
+			carrier.Set(headerTracestate, sc.TraceState)
+		}
+	case PropagationB3:
+		carrier.Set(headerB3, sc.TraceIDString()+"-"+sc.SpanIDString()+"-"+sampled)
+	case PropagationB3Multi:
+		carrier.Set(headerB3TraceID, sc.TraceIDString())
+		carrier.Set(headerB3SpanID, sc.SpanIDString())
+		carrier.Set(headerB3Sampled, sampled)
+	case PropagationJaeger:
+		carrier.Set(headerJaeger, sc.TraceIDString()+":"+sc.SpanIDString()+":0:"+sampled)
+	}
+}
+
+// extractW3C parses traceparent header, for example "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01".
+func extractW3C(carrier propagation.TextMapCarrier) (SpanContext, bool) {
+	parts := strings.Split(strings.TrimSpace(carrier.Get(headerTraceparent)), "-")
+
+	// Future versions may append fields, version ff is forbidden.
+	if len(parts) < 4 || len(parts[0]) != 2 || parts[0] == "ff" || (parts[0] == "00" && len(parts) != 4) {
+		return SpanContext{}, false
+	}
+
+	var sc SpanContext
+
+	flags, err := hex.DecodeString(parts[3])
+	if err != nil || len(flags) != 1 ||
+		!decodeID(sc.TraceID[:], parts[1], false) || !decodeID(sc.SpanID[:], parts[2], false) {
+		return SpanContext{}, false
+	}
+
+	sc.Sampled = flags[0]&1 == 1
+	sc.TraceState = carrier.Get(headerTracestate)
+
+	return sc, true
+}
+
+// extractB3 parses b3 header, for example "80f198ee56343ba864fe8b2a57d3eff7-e457b5a2e4d86bd1-1-05e3ac9a4f6e3b90".
+func extractB3(carrier propagation.TextMapCarrier) (SpanContext, bool) {
+	parts := strings.Split(strings.TrimSpace(carrier.Get(headerB3)), "-")
+
+	// Header may only carry sampling decision, that is not enough to continue trace.
+	if len(parts) < 2 || len(parts) > 4 {
+		return SpanContext{}, false
+	}
+
+	var sc SpanContext
+
+	if !decodeID(sc.TraceID[:], parts[0], true) || !decodeID(sc.SpanID[:], parts[1], true) {
+		return SpanContext{}, false
+	}
+
+	if len(parts) > 2 {
+		sc.Sampled = parts[2] == "1" || parts[2] == "d"
+	}
+
+	return sc, true
+}
+
+// extractB3Multi parses X-B3-* headers.
+func extractB3Multi(carrier propagation.TextMapCarrier) (SpanContext, bool) {
+	var sc SpanContext
+
+	if !decodeID(sc.TraceID[:], strings.TrimSpace(carrier.Get(headerB3TraceID)), true) ||
+		!decodeID(sc.SpanID[:], strings.TrimSpace(carrier.Get(headerB3SpanID)), true) {
+		return SpanContext{}, false
+	}
+
+	s := strings.TrimSpace(carrier.Get(headerB3Sampled))
+	sc.Sampled = s == "1" || strings.EqualFold(s, "true") || strings.TrimSpace(carrier.Get(headerB3Flags)) == "1"
+
+	return sc, true
+}
+
+// extractJaeger parses uber-trace-id header, for example "4bf92f3577b34da6a3ce929d0e0e4736:00f067aa0ba902b7:0:1".
+func extractJaeger(carrier propagation.TextMapCarrier) (SpanContext, bool) {
+	h := strings.TrimSpace(carrier.Get(headerJaeger))
+
+	if strings.Contains(h, "%") {
+		if u, err := url.QueryUnescape(h); err == nil {
+			h = u
+		}
+	}
+
+	parts := strings.Split(h, ":")
+	if len(parts) != 4 {
+		return SpanContext{}, false
+	}
+
+	var sc SpanContext
+
+	flags, err := strconv.ParseUint(parts[3], 16, 8)
+	if err != nil || !decodeID(sc.TraceID[:], parts[0], true) || !decodeID(sc.SpanID[:], parts[1], true) {
+		return SpanContext{}, false
+	}
+
+	sc.Sampled = flags&1 == 1
+
+	return sc, true
+}
+
+// decodeID decodes hex ID into dst, shorter values are left-padded with zeros if padding is allowed.
+func decodeID(dst []byte, s string, pad bool) bool {
+	if s == "" || len(s) > 2*len(dst) || (!pad && len(s) != 2*len(dst)) {
+		return false
+	}
+
+	if len(s) < 2*len(dst) {
+		s = strings.Repeat("0", 2*len(dst)-len(s)) + s
+	}
+
+	_, err := hex.Decode(dst, []byte(s))
+
+	return err == nil
+}
+
+// ocFormat implements OpenCensus propagation.HTTPFormat.
+type ocFormat struct {
+	p *Propagator
+}
+
+func (f ocFormat) SpanContextFromRequest(req *http.Request) (octrace.SpanContext, bool) {
+	sc, ok := f.p.extract(propagation.HeaderCarrier(req.Header))
+	if !ok {
+		return octrace.SpanContext{}, false
+	}
+
+	res := octrace.SpanContext{
+		TraceID:    sc.TraceID,
+		SpanID:     sc.SpanID,
+		Tracestate: ocTracestate(sc.TraceState),
+	}
+
+	if sc.Sampled {
+		res.TraceOptions = 1
+	}
+
+	return res, true
+}
+
+func (f ocFormat) SpanContextToRequest(sc octrace.SpanContext, req *http.Request) {
+	s := SpanContext{
+		TraceID:    sc.TraceID,
+		SpanID:     sc.SpanID,
+		Sampled:    sc.IsSampled(),
+		TraceState: ocTracestateString(sc.Tracestate),
+	}
+
+	for _, format := range f.p.outbound {
+		inject(format, s, propagation.HeaderCarrier(req.Header))
+	}
+}
+
+func ocTracestate(s string) *tracestate.Tracestate {
+	if s == "" {
+		return nil
+	}
+
+	var entries []tracestate.Entry
+
+	for _, kv := range strings.Split(s, ",") {
+		k, v, ok := strings.Cut(strings.TrimSpace(kv), "=")
+		if !ok {
+			continue
+		}
+
+		entries = append(entries, tracestate.Entry{Key: k, Value: v})
+	}
+
+	ts, err := tracestate.New(nil, entries...)
+	if err != nil {
+		return nil
+	}
+
+	return ts
+}
+
+func ocTracestateString(ts *tracestate.Tracestate) string {
+	if ts == nil {
+		return ""
+	}
+
+	entries := ts.Entries()
+	kv := make([]string, 0, len(entries))
+
+	for _, e := range entries {
+		kv = append(kv, e.Key+"="+e.Value)
+	}
+
+	return strings.Join(kv, ",")
+}
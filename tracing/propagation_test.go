@@ -0,0 +1,174 @@
+package tracing_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/bool64/brick/tracing"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestPropagator_Extract(t *testing.T) {
+	p, err := tracing.NewPropagator(tracing.Propagation{})
+	require.NoError(t, err)
+
+	for name, tc := range map[string]struct {
+		headers map[string]string
+		traceID string
+		spanID  string
+		sampled bool
+		state   string
+	}{
+		"w3c": {
+			headers: map[string]string{
+				"Traceparent": "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+				"Tracestate":  "congo=t61rcWkgMzE",
+			},
+			traceID: "4bf92f3577b34da6a3ce929d0e0e4736",
+			spanID:  "00f067aa0ba902b7",
+			sampled: true,
+			state:   "congo=t61rcWkgMzE",
+		},
+		"b3": {
+			headers: map[string]string{"B3": "64fe8b2a57d3eff7-e457b5a2e4d86bd1-d"},
+			traceID: "000000000000000064fe8b2a57d3eff7",
+			spanID:  "e457b5a2e4d86bd1",
+			sampled: true,
+		},
+		"b3multi": {
+			headers: map[string]string{
+				"X-B3-TraceId": "80f198ee56343ba864fe8b2a57d3eff7",
+				"X-B3-SpanId":  "e457b5a2e4d86bd1",
+				"X-B3-Sampled": "0",
+			},
+			traceID: "80f198ee56343ba864fe8b2a57d3eff7",
+			spanID:  "e457b5a2e4d86bd1",
+		},
+		"jaeger": {
+			headers: map[string]string{"Uber-Trace-Id": "4bf92f3577b34da6a3ce929d0e0e4736%3A0f067aa0ba902b7%3A0%3A3"},
+			traceID: "4bf92f3577b34da6a3ce929d0e0e4736",
+			spanID:  "00f067aa0ba902b7",
+			sampled: true,
+		},
+		"fallback": {
+			headers: map[string]string{
+				"Traceparent": "00-00000000000000000000000000000000-00f067aa0ba902b7-01",
+				"B3":          "80f198ee56343ba864fe8b2a57d3eff7-e457b5a2e4d86bd1-1",
+			},
+			traceID: "80f198ee56343ba864fe8b2a57d3eff7",
+			spanID:  "e457b5a2e4d86bd1",
+			sampled: true,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			h := http.Header{}
+			for k, v := range tc.headers {
+				h.Set(k, v)
+			}
+
+			sc := trace.SpanContextFromContext(p.Extract(context.Background(), propagation.HeaderCarrier(h)))
+
+			assert.True(t, sc.IsRemote())
+			assert.Equal(t, tc.traceID, sc.TraceID().String())
+			assert.Equal(t, tc.spanID, sc.SpanID().String())
+			assert.Equal(t, tc.sampled, sc.IsSampled())
+			assert.Equal(t, tc.state, sc.TraceState().String())
+		})
+	}
+
+	h := http.Header{}
+	h.Set("B3", "1")
+	h.Set("Traceparent", "ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	assert.False(t, trace.SpanContextFromContext(p.Extract(context.Background(), propagation.HeaderCarrier(h))).IsValid())
+}
+
+func TestPropagator_inboundChain(t *testing.T) {
+	p, err := tracing.NewPropagator(tracing.Propagation{Inbound: []string{"jaeger", "B3"}})
+	require.NoError(t, err)
+
+	h := http.Header{}
+	h.Set("Traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	h.Set("B3", "80f198ee56343ba864fe8b2a57d3eff7-e457b5a2e4d86bd1-1")
+
+	sc := trace.SpanContextFromContext(p.Extract(context.Background(), propagation.HeaderCarrier(h)))
+	assert.Equal(t, "80f198ee56343ba864fe8b2a57d3eff7", sc.TraceID().String())
+
+	_, err = tracing.NewPropagator(tracing.Propagation{Outbound: []string{"xray"}})
+	assert.EqualError(t, err, "unknown trace propagation format, expected w3c, b3, b3multi or jaeger: xray")
+}
+
+func TestTransport(t *testing.T) {
+	tr, exp := newOpenTelemetry()
+
+	p, err := tracing.NewPropagator(tracing.Propagation{Outbound: []string{"w3c", "b3", "b3multi", "jaeger"}})
+	require.NoError(t, err)
+
+	var headers http.Header
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		headers = r.Header
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer srv.Close()
+
+	ctx, parent := tr.Start(context.Background(), "parent")
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, srv.URL+"/items", nil)
+	require.NoError(t, err)
+
+	c := http.Client{Transport: &tracing.Transport{Tracer: tr, Propagator: p}}
+
+	resp, err := c.Do(req)
+	require.NoError(t, err)
+	require.NoError(t, resp.Body.Close())
+	parent.End()
+
+	assert.Empty(t, req.Header, "original request must not be modified")
+
+	spans := exp.GetSpans()
+	require.Len(t, spans, 2)
+
+	client := spans[0]
+	traceID := client.SpanContext.TraceID().String()
+	spanID := client.SpanContext.SpanID().String()
+
+	assert.Equal(t, "GET "+req.URL.Host, client.Name)
+	assert.Equal(t, trace.SpanKindClient, client.SpanKind)
+	assert.Equal(t, parent.SpanContext().SpanIDString(), client.Parent.SpanID().String())
+	assert.Equal(t, "server error: 502 Bad Gateway", client.Status.Description)
+
+	assert.Equal(t, "00-"+traceID+"-"+spanID+"-01", headers.Get("Traceparent"))
+	assert.Equal(t, traceID+"-"+spanID+"-1", headers.Get("B3"))
+	assert.Equal(t, traceID, headers.Get("X-B3-TraceId"))
+	assert.Equal(t, spanID, headers.Get("X-B3-SpanId"))
+	assert.Equal(t, "1", headers.Get("X-B3-Sampled"))
+	assert.Equal(t, traceID+":"+spanID+":0:1", headers.Get("Uber-Trace-Id"))
+}
+
+func TestOpenCensus_HTTPMiddleware_propagation(t *testing.T) {
+	p, err := tracing.NewPropagator(tracing.Propagation{Inbound: []string{"jaeger"}})
+	require.NoError(t, err)
+
+	var sc tracing.SpanContext
+
+	h := tracing.OpenCensus{Propagation: p.OpenCensus()}.HTTPMiddleware(
+		http.HandlerFunc(func(_ http.ResponseWriter, r *http.Request) {
+			sc = tracing.FromContext(r.Context()).SpanContext()
+		}),
+	)
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Uber-Trace-Id", "4bf92f3577b34da6a3ce929d0e0e4736:00f067aa0ba902b7:0:1")
+	req.Header.Set("B3", "80f198ee56343ba864fe8b2a57d3eff7-e457b5a2e4d86bd1-1")
+
+	h.ServeHTTP(httptest.NewRecorder(), req)
+
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", sc.TraceIDString())
+	assert.NotEqual(t, "00f067aa0ba902b7", sc.SpanIDString())
+	assert.True(t, sc.Sampled)
+}
@@ -0,0 +1,491 @@
+package tracing
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html/template"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bool64/brick/opencensus"
+	"github.com/bool64/ctxd"
+	octrace "go.opencensus.io/trace"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var (
+	errSamplingProbability = errors.New("sampling probability must be between 0 and 1")
+	errSamplingNegative    = errors.New("slow threshold and rate limit must not be negative")
+	errSamplingRule        = errors.New("sampling rule must be in \"name: probability\" format")
+)
+
+// Sampling defines rule-based trace sampling.
+//
+// Use case rules, Errors and Slow need complete local trace to make a decision, so they are only
+// effective with OpenCensus tail sampling (see opencensus.EnablePolicySampling).
+// Route rules and rate limit are also applied by head samplers to local root spans.
+type Sampling struct {
+	// Probability is the default sampling probability, it is set from debug.Config.
+	Probability float64 `json:"probability" ignored:"true"`
+
+	// Routes maps route patterns to sampling probabilities, for example "GET /items/{id}:0.5,/admin/*:0".
+	// Pattern is matched with name of local root span with or without HTTP method,
+	// trailing "*" matches any suffix, the longest matching pattern wins.
+	Routes map[string]float64 `json:"routes,omitempty"`
+
+	// UseCases maps use case names to sampling probabilities, for example "findItem:1".
+	// If multiple use cases of a trace match, the highest probability wins, use case rules
+	// take precedence over route rules.
+	UseCases map[string]float64 `split_words:"true" json:"useCases,omitempty"`
+
+	// Errors enables sampling of all traces that have failed spans.
+	Errors bool `json:"errors"`
+
+	// Slow enables sampling of all traces with local root span not faster than this threshold.
+	Slow time.Duration `json:"slow"`
+
+	// RateLimit is the max number of traces per second that are sampled by probability, 0 for no limit.
+	// Traces sampled for errors or slowness are not limited.
+	RateLimit float64 `split_words:"true" json:"rateLimit"`
+
+	// TailMaxTraces limits the number of local traces buffered by tail sampling,
+	// new traces are sampled by head rules while the buffer is full.
+	TailMaxTraces int `split_words:"true" default:"10000" json:"-"`
+
+	// TailMaxSpans limits the number of buffered spans of a local trace, extra spans are dropped.
+	TailMaxSpans int `split_words:"true" default:"1000" json:"-"`
+}
+
+// TailOptions applies buffer limits to OpenCensus tail sampling options.
+func (c Sampling) TailOptions(o *opencensus.TailOptions) {
+	o.MaxTraces = c.TailMaxTraces
+	o.MaxSpans = c.TailMaxSpans
+}
+
+// NeedsTail returns true if rules depend on complete local trace.
+func (c Sampling) NeedsTail() bool {
+	return len(c.UseCases) > 0 || c.Errors || c.Slow > 0
+}
+
+// Validate checks configuration.
+func (c Sampling) Validate() error {
+	if c.Probability < 0 || c.Probability > 1 {
+		return fmt.Errorf("%w: %v", errSamplingProbability, c.Probability)
+	}
+
+	for _, rules := range []map[string]float64{c.Routes, c.UseCases} {
+		for name, p := range rules {
+			if p < 0 || p > 1 {
+				return fmt.Errorf("%w: %s: %v", errSamplingProbability, name, p)
+			}
+		}
+	}
+
+	if c.Slow < 0 || c.RateLimit < 0 {
+		return errSamplingNegative
+	}
+
+	return nil
+}
+
+func (c Sampling) probability(root string, names []string) float64 {
+	if len(c.UseCases) > 0 {
+		p, found := 0.0, false
+
+		for _, name := range names {
+			if up, ok := c.UseCases[name]; ok && (!found || up > p) {
+				p, found = up, true
+			}
+		}
+
+		if found {
+			return p
+		}
+	}
+
+	p, matched := c.Probability, -1
+
+	for pattern, rp := range c.Routes {
+		if len(pattern) > matched && matchRoute(pattern, root) {
+			p, matched = rp, len(pattern)
+		}
+	}
+
+	return p
+}
+
+// matchRoute checks pattern against span name, for example "GET /items/{id}".
+func matchRoute(pattern, name string) bool {
+	route := name
+	if _, r, ok := strings.Cut(name, " "); ok {
+		route = r
+	}
+
+	if prefix, ok := strings.CutSuffix(pattern, "*"); ok {
+		return strings.HasPrefix(name, prefix) || strings.HasPrefix(route, prefix)
+	}
+
+	return pattern == name || pattern == route
+}
+
+// Sampler makes sampling decisions with rules that can be changed at runtime.
+//
+// Please use NewSampler to create an instance.
+type Sampler struct {
+	// Logger receives audit messages of rule changes.
+	Logger ctxd.Logger
+
+	base Sampling
+
+	mu      sync.Mutex
+	cfg     Sampling
+	changed string
+	tokens  float64
+	last    time.Time
+}
+
+// NewSampler creates sampler with configured rules.
+func NewSampler(cfg Sampling) (*Sampler, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+
+	return &Sampler{
+		Logger: ctxd.NoOpLogger{},
+		base:   cfg,
+		cfg:    cfg,
+	}, nil
+}
+
+// Config returns current rules.
+func (s *Sampler) Config() Sampling {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.cfg
+}
+
+// Set changes rules.
+func (s *Sampler) Set(ctx context.Context, cfg Sampling, changedBy string) error {
+	if err := cfg.Validate(); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.cfg = cfg
+	s.changed = changedBy
+
+	s.Logger.Important(ctx, "trace sampling changed", "sampling", cfg, "changed_by", changedBy)
+
+	return nil
+}
+
+// Reset restores configured rules.
+func (s *Sampler) Reset(ctx context.Context, changedBy string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.cfg = s.base
+	s.changed = ""
+
+	s.Logger.Important(ctx, "trace sampling reset", "changed_by", changedBy)
+}
+
+func (s *Sampler) sample(traceID [16]byte, root string, names []string, failed bool, duration time.Duration) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	cfg := s.cfg
+
+	if (cfg.Errors && failed) || (cfg.Slow > 0 && duration >= cfg.Slow) {
+		return true
+	}
+
+	if !sampledByID(traceID, cfg.probability(root, names)) {
+		return false
+	}
+
+	return s.allow(cfg.RateLimit)
+}
+
+// allow applies token bucket rate limit, it must be called with locked mutex.
+func (s *Sampler) allow(rate float64) bool {
+	if rate <= 0 {
+		return true
+	}
+
+	burst := math.Max(rate, 1)
+	now := time.Now()
+
+	if s.last.IsZero() {
+		s.tokens = burst
+	} else {
+		s.tokens = math.Min(burst, s.tokens+now.Sub(s.last).Seconds()*rate)
+	}
+
+	s.last = now
+
+	if s.tokens < 1 {
+		return false
+	}
+
+	s.tokens--
+
+	return true
+}
+
+// sampledByID makes a deterministic decision, compatible with OpenTelemetry TraceIDRatioBased sampler.
+func sampledByID(traceID [16]byte, probability float64) bool {
+	if probability >= 1 {
+		return true
+	}
+
+	if probability <= 0 {
+		return false
+	}
+
+	return binary.BigEndian.Uint64(traceID[8:16])>>1 < uint64(probability*(1<<63))
+}
+
+// SampleTrace implements opencensus.TailPolicy.
+func (s *Sampler) SampleTrace(t opencensus.TailTrace) bool {
+	var (
+		names  = make([]string, 0, len(t.Spans))
+		failed bool
+	)
+
+	for _, sd := range t.Spans {
+		names = append(names, sd.Name)
+
+		if sd.Code != 0 {
+			failed = true
+		}
+	}
+
+	return s.sample(t.Root.TraceID, t.Root.Name, names, failed, t.Root.EndTime.Sub(t.Root.StartTime))
+}
+
+// OpenCensus returns head sampler for local root spans, spans with sampled remote parent are sampled.
+func (s *Sampler) OpenCensus() octrace.Sampler {
+	return func(p octrace.SamplingParameters) octrace.SamplingDecision {
+		if p.ParentContext.IsSampled() {
+			return octrace.SamplingDecision{Sample: true}
+		}
+
+		return octrace.SamplingDecision{Sample: s.sample(p.TraceID, p.Name, []string{p.Name}, false, 0)}
+	}
+}
+
+// OpenTelemetry returns head sampler for local root spans, child spans follow parent decision and
+// linked spans of async work are sampled if linked span is sampled.
+func (s *Sampler) OpenTelemetry() sdktrace.Sampler {
+	return sdktrace.ParentBased(otelSampler{s: s})
+}
+
+type otelSampler struct {
+	s *Sampler
+}
+
+func (o otelSampler) ShouldSample(p sdktrace.SamplingParameters) sdktrace.SamplingResult {
+	res := sdktrace.SamplingResult{
+		Decision:   sdktrace.Drop,
+		Tracestate: trace.SpanContextFromContext(p.ParentContext).TraceState(),
+	}
+
+	// Async work follows sampling decision of linked trace.
+	for _, l := range p.Links {
+		if l.SpanContext.IsSampled() {
+			res.Decision = sdktrace.RecordAndSample
+
+			return res
+		}
+	}
+
+	if o.s.sample(p.TraceID, p.Name, []string{p.Name}, false, 0) {
+		res.Decision = sdktrace.RecordAndSample
+	}
+
+	return res
+}
+
+func (o otelSampler) Description() string {
+	return "RuleSampler"
+}
+
+var samplingPage = template.Must(template.New("sampling").Parse(`<!DOCTYPE html>
+<html><head><title>Trace Sampling</title></head><body>
+<h2>Trace Sampling</h2>
+{{if .Changed}}<p>Rules were changed by {{.Changed}}.</p>
+<form method="post"><input type="hidden" name="reset" value="1"/><button>Reset to configured rules</button></form>{{end}}
+<form method="post">
+<p><label>Default probability <input name="probability" value="{{.Config.Probability}}" size="5"/></label></p>
+<p><label>Routes, "pattern: probability" per line<br/>
+<textarea name="routes" rows="5" cols="60">{{range .Routes}}{{.}}
+{{end}}</textarea></label></p>
+<p><label>Use cases, "name: probability" per line<br/>
+<textarea name="use_cases" rows="5" cols="60">{{range .UseCases}}{{.}}
+{{end}}</textarea></label></p>
+<p><label><input type="checkbox" name="errors" value="1"{{if .Config.Errors}} checked{{end}}/> Sample traces with errors</label></p>
+<p><label>Sample traces slower than <input name="slow" value="{{.Config.Slow}}" size="5"/></label></p>
+<p><label>Rate limit, traces per second <input name="rate_limit" value="{{.Config.RateLimit}}" size="5"/></label></p>
+{{if not .Tail}}<p>Use case, error and slowness rules are not effective without tail sampling.</p>{{end}}
+<button>Apply</button>
+</form>
+</body></html>`))
+
+// ServeHTTP shows and changes sampling rules.
+//
+// GET request renders current rules as HTML or as JSON if "application/json" is accepted.
+// POST request with JSON body or form changes rules, form with "reset" parameter restores configured rules.
+func (s *Sampler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodPost {
+		if err := s.change(r); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+
+			return
+		}
+
+		if !strings.Contains(r.Header.Get("Accept"), "application/json") {
+			http.Redirect(w, r, r.URL.Path, http.StatusSeeOther)
+
+			return
+		}
+	}
+
+	s.mu.Lock()
+	cfg, changed := s.cfg, s.changed
+	tail := s.base.NeedsTail()
+	s.mu.Unlock()
+
+	if strings.Contains(r.Header.Get("Accept"), "application/json") {
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+
+		if err := json.NewEncoder(w).Encode(cfg); err != nil {
+			s.Logger.Error(r.Context(), "failed to write trace sampling rules", "error", err)
+		}
+
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+
+	if err := samplingPage.Execute(w, map[string]interface{}{
+		"Config":   cfg,
+		"Changed":  changed,
+		"Tail":     tail,
+		"Routes":   formatRules(cfg.Routes),
+		"UseCases": formatRules(cfg.UseCases),
+	}); err != nil {
+		s.Logger.Error(r.Context(), "failed to render trace sampling page", "error", err)
+	}
+}
+
+func (s *Sampler) change(r *http.Request) error {
+	changedBy := r.RemoteAddr
+	if user, _, ok := r.BasicAuth(); ok {
+		changedBy = user + "@" + changedBy
+	}
+
+	var cfg Sampling
+
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "application/json") {
+		if err := json.NewDecoder(r.Body).Decode(&cfg); err != nil {
+			return err
+		}
+
+		return s.Set(r.Context(), cfg, changedBy)
+	}
+
+	if err := r.ParseForm(); err != nil {
+		return err
+	}
+
+	if r.Form.Get("reset") != "" {
+		s.Reset(r.Context(), changedBy)
+
+		return nil
+	}
+
+	var err error
+
+	if cfg.Probability, err = strconv.ParseFloat(r.Form.Get("probability"), 64); err != nil {
+		return err
+	}
+
+	if cfg.Routes, err = parseRules(r.Form.Get("routes")); err != nil {
+		return err
+	}
+
+	if cfg.UseCases, err = parseRules(r.Form.Get("use_cases")); err != nil {
+		return err
+	}
+
+	cfg.Errors = r.Form.Get("errors") != ""
+
+	if v := r.Form.Get("slow"); v != "" {
+		if cfg.Slow, err = time.ParseDuration(v); err != nil {
+			return err
+		}
+	}
+
+	if v := r.Form.Get("rate_limit"); v != "" {
+		if cfg.RateLimit, err = strconv.ParseFloat(v, 64); err != nil {
+			return err
+		}
+	}
+
+	return s.Set(r.Context(), cfg, changedBy)
+}
+
+func formatRules(rules map[string]float64) []string {
+	res := make([]string, 0, len(rules))
+
+	for name, p := range rules {
+		res = append(res, name+": "+strconv.FormatFloat(p, 'g', -1, 64))
+	}
+
+	sort.Strings(res)
+
+	return res
+}
+
+// parseRules reads "name: probability" lines, name may contain colons.
+func parseRules(s string) (map[string]float64, error) {
+	var res map[string]float64
+
+	for _, line := range strings.Split(s, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+
+		i := strings.LastIndex(line, ":")
+		if i <= 0 {
+			return nil, fmt.Errorf("%w: %q", errSamplingRule, line)
+		}
+
+		p, err := strconv.ParseFloat(strings.TrimSpace(line[i+1:]), 64)
+		if err != nil {
+			return nil, err
+		}
+
+		if res == nil {
+			res = make(map[string]float64)
+		}
+
+		res[strings.TrimSpace(line[:i])] = p
+	}
+
+	return res, nil
+}
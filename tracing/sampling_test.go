@@ -0,0 +1,165 @@
+package tracing_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bool64/brick/opencensus"
+	"github.com/bool64/brick/tracing"
+	"github.com/bool64/ctxd"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	octrace "go.opencensus.io/trace"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestSampler_OpenTelemetry(t *testing.T) {
+	s, err := tracing.NewSampler(tracing.Sampling{
+		Routes: map[string]float64{
+			"/items/*":        1,
+			"GET /items/{id}": 0,
+		},
+	})
+	require.NoError(t, err)
+
+	exp := tracetest.NewInMemoryExporter()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exp), sdktrace.WithSampler(s.OpenTelemetry()))
+	tr := tracing.NewOpenTelemetry(tp)
+
+	for _, name := range []string{"GET /items/{id}", "POST /items/{id}", "GET /users", "DELETE /items/all"} {
+		ctx, span := tr.Start(context.Background(), name)
+		_, child := tr.Start(ctx, name+" child")
+
+		child.End()
+		span.End()
+	}
+
+	var names []string
+	for _, sp := range exp.GetSpans() {
+		names = append(names, sp.Name)
+	}
+
+	assert.Equal(t, []string{"POST /items/{id} child", "POST /items/{id}", "DELETE /items/all child", "DELETE /items/all"}, names)
+}
+
+func TestSampler_rateLimit(t *testing.T) {
+	s, err := tracing.NewSampler(tracing.Sampling{Probability: 1, RateLimit: 3})
+	require.NoError(t, err)
+
+	sample := s.OpenCensus()
+	sampled := 0
+
+	for i := 0; i < 10; i++ {
+		p := octrace.SamplingParameters{Name: "GET /items"}
+		p.TraceID[15] = byte(i)
+
+		if sample(p).Sample {
+			sampled++
+		}
+	}
+
+	assert.Equal(t, 3, sampled)
+}
+
+func TestSampler_SampleTrace(t *testing.T) {
+	s, err := tracing.NewSampler(tracing.Sampling{
+		UseCases: map[string]float64{"findItem": 1, "listItems": 0},
+		Errors:   true,
+		Slow:     time.Second,
+	})
+	require.NoError(t, err)
+
+	start := time.Now()
+
+	tt := func(d time.Duration, code int32, names ...string) opencensus.TailTrace {
+		root := &octrace.SpanData{Name: names[0], StartTime: start, EndTime: start.Add(d)}
+		root.TraceID[15] = 1
+
+		res := opencensus.TailTrace{Root: root}
+
+		for _, n := range names[1:] {
+			res.Spans = append(res.Spans, &octrace.SpanData{Name: n, Status: octrace.Status{Code: code}})
+		}
+
+		res.Spans = append(res.Spans, root)
+
+		return res
+	}
+
+	assert.True(t, s.SampleTrace(tt(time.Millisecond, 0, "GET /items/{id}", "findItem")))
+	assert.False(t, s.SampleTrace(tt(time.Millisecond, 0, "GET /items", "listItems")))
+	assert.False(t, s.SampleTrace(tt(time.Millisecond, 0, "GET /users")))
+	assert.True(t, s.SampleTrace(tt(time.Millisecond, 5, "GET /items", "listItems")))
+	assert.True(t, s.SampleTrace(tt(2*time.Second, 0, "GET /items", "listItems")))
+}
+
+func TestSampler_ServeHTTP(t *testing.T) {
+	s, err := tracing.NewSampler(tracing.Sampling{Probability: 0.1})
+	require.NoError(t, err)
+
+	audit := &ctxd.LoggerMock{}
+	s.Logger = audit
+
+	form := url.Values{
+		"probability": {"0.5"},
+		"routes":      {"GET /items/{id}: 1\n/admin/*:0\n"},
+		"use_cases":   {"findItem: 0.2"},
+		"errors":      {"1"},
+		"slow":        {"2s"},
+		"rate_limit":  {"10"},
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/sampling", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.SetBasicAuth("dev", "secret")
+
+	rw := httptest.NewRecorder()
+	s.ServeHTTP(rw, req)
+	assert.Equal(t, http.StatusSeeOther, rw.Code)
+
+	assert.Equal(t, tracing.Sampling{
+		Probability: 0.5,
+		Routes:      map[string]float64{"GET /items/{id}": 1, "/admin/*": 0},
+		UseCases:    map[string]float64{"findItem": 0.2},
+		Errors:      true,
+		Slow:        2 * time.Second,
+		RateLimit:   10,
+	}, s.Config())
+	assert.Contains(t, audit.String(), `important: trace sampling changed`)
+	assert.Contains(t, audit.String(), `"changed_by":"dev@192.0.2.1:1234"`)
+
+	req = httptest.NewRequest(http.MethodGet, "/sampling", nil)
+	rw = httptest.NewRecorder()
+	s.ServeHTTP(rw, req)
+	assert.Contains(t, rw.Body.String(), "/admin/*: 0\nGET /items/{id}: 1\n")
+	assert.Contains(t, rw.Body.String(), "Use case, error and slowness rules are not effective")
+
+	req = httptest.NewRequest(http.MethodPost, "/sampling", strings.NewReader(`{"probability":2}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+
+	rw = httptest.NewRecorder()
+	s.ServeHTTP(rw, req)
+	assert.Equal(t, http.StatusBadRequest, rw.Code)
+	assert.Equal(t, "sampling probability must be between 0 and 1: 2\n", rw.Body.String())
+
+	req = httptest.NewRequest(http.MethodPost, "/sampling", strings.NewReader(`reset=1`))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+
+	rw = httptest.NewRecorder()
+	s.ServeHTTP(rw, req)
+	assert.Equal(t, http.StatusOK, rw.Code)
+	assert.JSONEq(t, `{"probability":0.1,"errors":false,"slow":0,"rateLimit":0}`, rw.Body.String())
+}
+
+func TestNewSampler(t *testing.T) {
+	_, err := tracing.NewSampler(tracing.Sampling{UseCases: map[string]float64{"findItem": -1}})
+	assert.EqualError(t, err, "sampling probability must be between 0 and 1: findItem: -1")
+}
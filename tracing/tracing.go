@@ -0,0 +1,188 @@
+package tracing
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+
+	"github.com/bool64/brick/opencensus"
+	"github.com/swaggest/usecase/status"
+	"go.opencensus.io/trace"
+	oteltrace "go.opentelemetry.io/otel/trace"
+)
+
+// Backends.
+const (
+	OpenCensusBackend    = "opencensus"
+	OpenTelemetryBackend = "opentelemetry"
+)
+
+var errUnknownBackend = errors.New("unknown tracing backend, expected opencensus or opentelemetry")
+
+// Config controls tracing backend.
+type Config struct {
+	// Backend selects implementation, "opencensus" or "opentelemetry".
+	Backend string `default:"opencensus"`
+
+	// Propagation selects formats of span context in HTTP headers.
+	Propagation Propagation
+}
+
+// Validate checks configuration.
+func (c Config) Validate() error {
+	switch c.Backend {
+	case "", OpenCensusBackend, OpenTelemetryBackend:
+	default:
+		return fmt.Errorf("%w: %s", errUnknownBackend, c.Backend)
+	}
+
+	return c.Propagation.Validate()
+}
+
+// Attribute is a key-value pair of span metadata.
+type Attribute struct {
+	Key   string
+	Value interface{}
+}
+
+// String creates string attribute.
+func String(key, value string) Attribute {
+	return Attribute{Key: key, Value: value}
+}
+
+// Int64 creates integer attribute.
+func Int64(key string, value int64) Attribute {
+	return Attribute{Key: key, Value: value}
+}
+
+// Float64 creates float attribute.
+func Float64(key string, value float64) Attribute {
+	return Attribute{Key: key, Value: value}
+}
+
+// Bool creates boolean attribute.
+func Bool(key string, value bool) Attribute {
+	return Attribute{Key: key, Value: value}
+}
+
+// SpanContext identifies span.
+type SpanContext struct {
+	TraceID [16]byte
+	SpanID  [8]byte
+	Sampled bool
+
+	// TraceState is a W3C tracestate value with vendor-specific data, for example "congo=t61rcWkgMzE".
+	TraceState string
+}
+
+// IsValid returns true if span context has trace and span IDs.
+func (sc SpanContext) IsValid() bool {
+	return sc.TraceID != [16]byte{} && sc.SpanID != [8]byte{}
+}
+
+// TraceIDString returns hex encoded trace ID.
+func (sc SpanContext) TraceIDString() string {
+	return hex.EncodeToString(sc.TraceID[:])
+}
+
+// SpanIDString returns hex encoded span ID.
+func (sc SpanContext) SpanIDString() string {
+	return hex.EncodeToString(sc.SpanID[:])
+}
+
+// Span is an operation in a trace.
+type Span interface {
+	SpanContext() SpanContext
+	SetAttributes(attributes ...Attribute)
+
+	// AddEvent adds timed annotation to span.
+	AddEvent(name string, attributes ...Attribute)
+
+	// SetError marks span as failed and records error as event, status code is taken from error if available.
+	SetError(err error)
+
+	End()
+}
+
+// Tracer creates spans with a backend.
+type Tracer interface {
+	// Start starts a child span of current span in context.
+	Start(ctx context.Context, name string, attributes ...Attribute) (context.Context, Span)
+
+	// StartLinked starts a root span of a new trace that is linked to a span, invalid link is ignored.
+	StartLinked(ctx context.Context, name string, link SpanContext, attributes ...Attribute) (context.Context, Span)
+
+	// HTTPMiddleware starts server spans for incoming requests.
+	HTTPMiddleware(handler http.Handler) http.Handler
+}
+
+// ClientTracer is an optional interface of Tracer to start client spans of outgoing requests.
+type ClientTracer interface {
+	StartClient(ctx context.Context, name string, attributes ...Attribute) (context.Context, Span)
+}
+
+var (
+	defaultMu     sync.RWMutex
+	defaultTracer Tracer = OpenCensus{}
+)
+
+// SetDefault sets tracer used by package level functions, OpenCensus is used by default.
+func SetDefault(t Tracer) {
+	defaultMu.Lock()
+	defer defaultMu.Unlock()
+
+	defaultTracer = t
+}
+
+// Default returns default tracer.
+func Default() Tracer {
+	defaultMu.RLock()
+	defer defaultMu.RUnlock()
+
+	return defaultTracer
+}
+
+// Start starts a span with default tracer.
+func Start(ctx context.Context, name string, attributes ...Attribute) (context.Context, Span) {
+	return Default().Start(ctx, name, attributes...)
+}
+
+// FromContext returns current span of any backend, or nil if there is no span.
+func FromContext(ctx context.Context) Span {
+	if s := oteltrace.SpanFromContext(ctx); s.SpanContext().IsValid() {
+		return otelSpan{s}
+	}
+
+	if s := trace.FromContext(ctx); s != nil {
+		return ocSpan{s}
+	}
+
+	return nil
+}
+
+// IsSampled returns true if trace of current span of any backend is sampled.
+//
+// For OpenCensus with tail sampling it reports head sampling decision, see opencensus.IsSampled.
+func IsSampled(ctx context.Context) bool {
+	if s := oteltrace.SpanFromContext(ctx); s.SpanContext().IsValid() {
+		return s.SpanContext().IsSampled()
+	}
+
+	return opencensus.IsSampled(ctx)
+}
+
+type errorWithStatus interface {
+	Status() status.Code
+}
+
+func errorStatus(err error) status.Code {
+	var ws errorWithStatus
+	if errors.As(err, &ws) {
+		return ws.Status()
+	}
+
+	return status.Unknown
+}
@@ -0,0 +1,147 @@
+package tracing_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/bool64/brick/tracing"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/swaggest/openapi-go/openapi3"
+	"github.com/swaggest/rest/nethttp"
+	"github.com/swaggest/rest/web"
+	"github.com/swaggest/usecase"
+	"github.com/swaggest/usecase/status"
+	"go.opencensus.io/trace"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func newOpenTelemetry() (*tracing.OpenTelemetry, *tracetest.InMemoryExporter) {
+	exp := tracetest.NewInMemoryExporter()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exp))
+
+	return tracing.NewOpenTelemetry(tp), exp
+}
+
+func TestOpenTelemetry_HTTPMiddleware(t *testing.T) {
+	tr, exp := newOpenTelemetry()
+
+	var traceID string
+
+	type itemReq struct {
+		ID int `path:"id"`
+	}
+
+	u := usecase.NewInteractor(func(ctx context.Context, _ itemReq, _ *struct{}) error {
+		traceID = tracing.FromContext(ctx).SpanContext().TraceIDString()
+
+		return status.Wrap(errors.New("failed"), status.NotFound)
+	})
+	u.SetName("findItem")
+
+	s := web.NewService(openapi3.NewReflector())
+	s.Wrap(tr.HTTPMiddleware, nethttp.UseCaseMiddlewares(tracing.UseCaseMiddleware{Tracer: tr}))
+	s.Get("/items/{id}", u)
+
+	req := httptest.NewRequest(http.MethodGet, "/items/1", nil)
+	req.Header.Set("Traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+
+	rw := httptest.NewRecorder()
+	s.ServeHTTP(rw, req)
+
+	assert.Equal(t, http.StatusNotFound, rw.Code)
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", traceID)
+
+	spans := exp.GetSpans()
+	require.Len(t, spans, 2)
+
+	uc, srv := spans[0], spans[1]
+
+	assert.Equal(t, "findItem", uc.Name)
+	assert.Equal(t, codes.Error, uc.Status.Code)
+	assert.Equal(t, "not found: failed", uc.Status.Description)
+	assert.Contains(t, uc.Attributes, attribute.String("status", "NOT_FOUND"))
+	assert.Equal(t, srv.SpanContext.SpanID(), uc.Parent.SpanID())
+
+	assert.Equal(t, "GET /items/{id}", srv.Name)
+	assert.Equal(t, "00f067aa0ba902b7", srv.Parent.SpanID().String())
+	assert.Equal(t, codes.Unset, srv.Status.Code)
+	assert.Contains(t, srv.Attributes, attribute.String("http.route", "/items/{id}"))
+	assert.Contains(t, srv.Attributes, attribute.Int("http.response.status_code", http.StatusNotFound))
+}
+
+func TestAddSpan(t *testing.T) {
+	tr, exp := newOpenTelemetry()
+
+	tracing.SetDefault(tr)
+	defer tracing.SetDefault(tracing.OpenCensus{})
+
+	func() {
+		err := errors.New("failed")
+
+		_, finish := tracing.AddSpan(context.Background(), tracing.String("key", "value"), tracing.Int64("n", 1))
+		finish(&err)
+	}()
+
+	spans := exp.GetSpans()
+	require.Len(t, spans, 1)
+
+	assert.Equal(t, "brick/tracing_test.TestAddSpan.func1", spans[0].Name)
+	assert.Equal(t, codes.Error, spans[0].Status.Code)
+	assert.Equal(t, []attribute.KeyValue{
+		attribute.String("key", "value"),
+		attribute.Int64("n", 1),
+		attribute.String("status", "UNKNOWN"),
+	}, spans[0].Attributes)
+}
+
+func TestFromContext(t *testing.T) {
+	assert.Nil(t, tracing.FromContext(context.Background()))
+
+	ctx, span := trace.StartSpan(context.Background(), "oc", trace.WithSampler(trace.AlwaysSample()))
+	defer span.End()
+
+	s := tracing.FromContext(ctx)
+	require.NotNil(t, s)
+	assert.Equal(t, span.SpanContext().TraceID.String(), s.SpanContext().TraceIDString())
+	assert.Equal(t, span.SpanContext().SpanID.String(), s.SpanContext().SpanIDString())
+	assert.True(t, s.SpanContext().Sampled)
+
+	_, s = tracing.OpenCensus{}.Start(ctx, "child", tracing.Bool("ok", true))
+	defer s.End()
+
+	assert.Equal(t, span.SpanContext().TraceID.String(), s.SpanContext().TraceIDString())
+}
+
+func TestIsSampled(t *testing.T) {
+	assert.False(t, tracing.IsSampled(context.Background()))
+
+	ctx, span := trace.StartSpan(context.Background(), "oc", trace.WithSampler(trace.AlwaysSample()))
+	defer span.End()
+
+	assert.True(t, tracing.IsSampled(ctx))
+
+	tr := tracing.NewOpenTelemetry(sdktrace.NewTracerProvider(sdktrace.WithSampler(sdktrace.NeverSample())))
+	ctx, s := tr.Start(ctx, "otel")
+	defer s.End()
+
+	assert.False(t, tracing.IsSampled(ctx))
+
+	tr = tracing.NewOpenTelemetry(sdktrace.NewTracerProvider())
+	ctx, s = tr.Start(context.Background(), "otel")
+	defer s.End()
+
+	assert.True(t, tracing.IsSampled(ctx))
+}
+
+func TestConfig_Validate(t *testing.T) {
+	assert.NoError(t, tracing.Config{Backend: tracing.OpenTelemetryBackend}.Validate())
+	assert.EqualError(t, tracing.Config{Backend: "zipkin"}.Validate(),
+		"unknown tracing backend, expected opencensus or opentelemetry: zipkin")
+}
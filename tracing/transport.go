@@ -0,0 +1,76 @@
+package tracing
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"go.opentelemetry.io/otel/propagation"
+)
+
+var errServerStatus = errors.New("server error")
+
+// Transport is an instrumented HTTP client transport.
+//
+// It starts client spans (with ClientTracer if implemented by Tracer) and injects span context into outgoing requests.
+//
+//	client := &http.Client{Transport: &tracing.Transport{Propagator: p}}
+type Transport struct {
+	// Base performs requests, http.DefaultTransport is used if nil.
+	Base http.RoundTripper
+
+	// Tracer starts client spans, Default is used if nil.
+	Tracer Tracer
+
+	// Propagator injects span context into request headers, W3C Trace Context is used if nil.
+	Propagator *Propagator
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *Transport) RoundTrip(r *http.Request) (*http.Response, error) {
+	base := t.Base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+
+	tr := t.Tracer
+	if tr == nil {
+		tr = Default()
+	}
+
+	p := t.Propagator
+	if p == nil {
+		p = defaultPropagator
+	}
+
+	start := tr.Start
+	if ct, ok := tr.(ClientTracer); ok {
+		start = ct.StartClient
+	}
+
+	ctx, span := start(r.Context(), r.Method+" "+r.URL.Host,
+		String("http.request.method", r.Method),
+		String("server.address", r.URL.Host),
+		String("url.path", r.URL.Path),
+	)
+	defer span.End()
+
+	// Round tripper must not modify original request.
+	r = r.Clone(ctx)
+	p.Inject(ctx, propagation.HeaderCarrier(r.Header))
+
+	resp, err := base.RoundTrip(r)
+	if err != nil {
+		span.SetError(err)
+
+		return nil, err
+	}
+
+	span.SetAttributes(Int64("http.response.status_code", int64(resp.StatusCode)))
+
+	if resp.StatusCode >= http.StatusInternalServerError {
+		span.SetError(fmt.Errorf("%w: %s", errServerStatus, resp.Status))
+	}
+
+	return resp, nil
+}
@@ -0,0 +1,125 @@
+package tracing
+
+import (
+	"context"
+
+	"github.com/bool64/brick/runtime"
+	"github.com/swaggest/usecase"
+)
+
+// UseCaseMiddleware is a tracing usecase middleware.
+type UseCaseMiddleware struct {
+	// Tracer is used to start spans, Default is used if nil.
+	Tracer Tracer
+
+	// WithInput records formatted input as span attribute, fields with redact option of TagName are redacted.
+	WithInput bool
+}
+
+// Wrap makes an instrumented use case interactor.
+//
+// Fields of input and output that are tagged with `trace` become span attributes, see TagName.
+// Output attributes are recorded if use case succeeds. Errors and validation failures are recorded as span events.
+func (mw UseCaseMiddleware) Wrap(u usecase.Interactor) usecase.Interactor {
+	var (
+		withName  usecase.HasName
+		withTitle usecase.HasTitle
+		spanName  string
+	)
+
+	if usecase.As(u, &withName) && withName.Name() != "" {
+		spanName = withName.Name()
+	} else if usecase.As(u, &withTitle) && withTitle.Title() != "" {
+		spanName = withTitle.Title()
+	}
+
+	if spanName == "" {
+		spanName = "useCaseUnknown"
+	}
+
+	return usecase.Interact(func(ctx context.Context, input, output interface{}) error {
+		t := mw.Tracer
+		if t == nil {
+			t = Default()
+		}
+
+		ctx, span := t.Start(ctx, spanName, StructAttributes(input)...)
+		if mw.WithInput {
+			span.SetAttributes(String("input", formatRedacted(input)))
+		}
+
+		defer span.End()
+
+		err := u.Interact(ctx, input, output)
+		if err != nil {
+			RecordError(span, err)
+
+			if r, ok := ctx.Value(errorRecordedCtxKey{}).(*errorRecorded); ok {
+				r.recorded = true
+			}
+		} else {
+			span.SetAttributes(StructAttributes(output)...)
+		}
+
+		return err
+	})
+}
+
+type errorRecordedCtxKey struct{}
+
+type errorRecorded struct {
+	recorded bool
+}
+
+// HTTPErrorMiddleware is a usecase middleware that records errors of use case HTTP handlers in current span,
+// for example request validation failures that are rejected before use case is invoked.
+//
+// Errors that are already recorded by UseCaseMiddleware are not recorded again.
+// Middleware should be the first one of nethttp.UseCaseMiddlewares, request decoding errors are passed
+// to use case middlewares of the last nethttp.UseCaseMiddlewares wrapper.
+type HTTPErrorMiddleware struct{}
+
+// Wrap makes use case interactor that records errors.
+func (HTTPErrorMiddleware) Wrap(u usecase.Interactor) usecase.Interactor {
+	return usecase.Interact(func(ctx context.Context, input, output interface{}) error {
+		rec, ok := ctx.Value(errorRecordedCtxKey{}).(*errorRecorded)
+		if !ok {
+			rec = &errorRecorded{}
+			ctx = context.WithValue(ctx, errorRecordedCtxKey{}, rec)
+		}
+
+		err := u.Interact(ctx, input, output)
+		if err != nil && !rec.recorded {
+			if span := FromContext(ctx); span != nil {
+				RecordError(span, err)
+			}
+
+			rec.recorded = true
+		}
+
+		return err
+	})
+}
+
+// AddSpan starts span with default tracer and returns updated context with callback to finish span.
+//
+// Span is named by the parent function.
+// Typically, span should be finished with deferred statement.
+// Error is recorded in the same way as with UseCaseMiddleware, use StructAttributes to collect tagged fields.
+//
+//	var err error
+//	ctx, finish := tracing.AddSpan(ctx,
+//		tracing.String("key", "value"),
+//	)
+//	defer finish(&err)
+func AddSpan(ctx context.Context, attributes ...Attribute) (context.Context, func(*error)) {
+	ctx, span := Start(ctx, runtime.CallerFunc(2), attributes...) //nolint:spancheck
+
+	return ctx, func(err *error) { //nolint:spancheck
+		if err != nil && *err != nil {
+			RecordError(span, *err)
+		}
+
+		span.End()
+	}
+}
@@ -4,17 +4,60 @@ import (
 	"context"
 	"errors"
 	"strconv"
+	"sync/atomic"
+	"time"
 
+	"github.com/bool64/brick/tracing"
 	"github.com/bool64/stats"
+	"github.com/prometheus/client_golang/prometheus"
 	"github.com/swaggest/rest"
 	"github.com/swaggest/usecase"
 	"github.com/swaggest/usecase/status"
 )
 
-// StatsMiddleware counts use case interactions.
-func StatsMiddleware(tracker stats.Tracker) usecase.Middleware {
+// StatsOptions configures StatsMiddleware.
+type StatsOptions struct {
+	// Buckets are upper bounds of use case duration histogram in seconds, prometheus.DefBuckets by default.
+	Buckets []float64
+}
+
+// StatsMiddleware counts use case interactions, errors by status, in-flight interactions and measures duration.
+//
+// If tracker provides prometheus registry (for example github.com/bool64/prom-stats.Tracker), duration
+// histogram and in-flight gauge are registered in it directly, duration observations of sampled traces
+// carry trace ID as exemplar (see tracing.IsSampled). Otherwise, duration and in-flight values are collected with tracker.
+func StatsMiddleware(tracker stats.Tracker, options ...func(o *StatsOptions)) usecase.Middleware {
 	unknownIndex := 0
 
+	o := StatsOptions{}
+	for _, opt := range options {
+		opt(&o)
+	}
+
+	if len(o.Buckets) == 0 {
+		o.Buckets = prometheus.DefBuckets
+	}
+
+	var (
+		duration *prometheus.HistogramVec
+		inFlight *prometheus.GaugeVec
+	)
+
+	if rp, ok := tracker.(interface{ PrometheusRegistry() *prometheus.Registry }); ok && rp.PrometheusRegistry() != nil {
+		reg := rp.PrometheusRegistry()
+
+		duration = register(reg, prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "use_case_duration_seconds",
+			Help:    "Duration of use case interactions.",
+			Buckets: o.Buckets,
+		}, []string{"name"}))
+
+		inFlight = register(reg, prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "use_case_in_flight",
+			Help: "Number of use case interactions in progress.",
+		}, []string{"name"}))
+	}
+
 	return usecase.MiddlewareFunc(func(u usecase.Interactor) usecase.Interactor {
 		var (
 			withName  usecase.HasName
@@ -36,8 +79,37 @@ func StatsMiddleware(tracker stats.Tracker) usecase.Middleware {
 			name = "unnamed" + strconv.Itoa(unknownIndex)
 		}
 
+		var (
+			inFlightCount atomic.Int64
+			observer      prometheus.Observer
+			gauge         prometheus.Gauge
+		)
+
+		if duration != nil {
+			observer = duration.WithLabelValues(name)
+			gauge = inFlight.WithLabelValues(name)
+		}
+
 		return usecase.Interact(func(ctx context.Context, input, output interface{}) error {
+			start := time.Now()
+
+			if gauge != nil {
+				gauge.Inc()
+			} else {
+				tracker.Set(ctx, "use_case_in_flight", float64(inFlightCount.Add(1)), "name", name)
+			}
+
 			err := u.Interact(ctx, input, output)
+			elapsed := time.Since(start).Seconds()
+
+			if gauge != nil {
+				gauge.Dec()
+				observe(ctx, observer, elapsed)
+			} else {
+				tracker.Set(ctx, "use_case_in_flight", float64(inFlightCount.Add(-1)), "name", name)
+				tracker.Add(ctx, "use_case_duration_seconds", elapsed, "name", name)
+			}
+
 			st := status.OK
 
 			if err != nil {
@@ -47,6 +119,11 @@ func StatsMiddleware(tracker stats.Tracker) usecase.Middleware {
 				if errors.As(err, &withStatus) {
 					st = withStatus.Status()
 				}
+
+				tracker.Add(ctx, "use_case_errors_count", 1,
+					"name", name,
+					"status", st.String(),
+				)
 			}
 
 			tracker.Add(ctx, "use_case_interactions_count", 1,
@@ -61,3 +138,32 @@ func StatsMiddleware(tracker stats.Tracker) usecase.Middleware {
 		})
 	})
 }
+
+// observe adds trace ID exemplar to observation of a sampled trace.
+//
+// With OpenCensus tail sampling, only traces sampled by head sampler are known to be exported.
+func observe(ctx context.Context, o prometheus.Observer, value float64) {
+	if span := tracing.FromContext(ctx); span != nil && tracing.IsSampled(ctx) {
+		if eo, ok := o.(prometheus.ExemplarObserver); ok {
+			eo.ObserveWithExemplar(value, prometheus.Labels{"trace_id": span.SpanContext().TraceIDString()})
+
+			return
+		}
+	}
+
+	o.Observe(value)
+}
+
+// register registers collector or returns already registered one, for example by another middleware instance.
+func register[T prometheus.Collector](reg prometheus.Registerer, c T) T {
+	if err := reg.Register(c); err != nil {
+		var are prometheus.AlreadyRegisteredError
+		if errors.As(err, &are) {
+			if existing, ok := are.ExistingCollector.(T); ok {
+				return existing
+			}
+		}
+	}
+
+	return c
+}
@@ -0,0 +1,131 @@
+package usecase_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/bool64/brick/opencensus"
+	"github.com/bool64/brick/tracing"
+	ucase "github.com/bool64/brick/usecase"
+	prom "github.com/bool64/prom-stats"
+	"github.com/bool64/stats"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/swaggest/usecase"
+	"github.com/swaggest/usecase/status"
+	"go.opencensus.io/trace"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+func newInteractor(t *testing.T, inFlight func() float64) usecase.Interactor {
+	t.Helper()
+
+	u := usecase.NewInteractor(func(_ context.Context, in string, _ *struct{}) error {
+		assert.Equal(t, 1.0, inFlight())
+
+		if in == "missing" {
+			return status.Wrap(errors.New("missing"), status.NotFound)
+		}
+
+		return nil
+	})
+	u.SetName("findItem")
+
+	return u
+}
+
+func TestStatsMiddleware_prometheus(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	tracker, err := prom.NewStatsTracker(reg)
+	require.NoError(t, err)
+
+	gauge := func() float64 {
+		mf, err := reg.Gather()
+		require.NoError(t, err)
+
+		for _, m := range mf {
+			if m.GetName() == "use_case_in_flight" {
+				return m.GetMetric()[0].GetGauge().GetValue()
+			}
+		}
+
+		return 0
+	}
+
+	mw := ucase.StatsMiddleware(tracker, func(o *ucase.StatsOptions) {
+		o.Buckets = []float64{0.1, 1}
+	})
+	u := usecase.Wrap(newInteractor(t, gauge), mw)
+
+	// Second instance reuses registered collectors.
+	_ = ucase.StatsMiddleware(tracker)
+
+	tr := tracing.NewOpenTelemetry(sdktrace.NewTracerProvider())
+	ctx, span := tr.Start(context.Background(), "request")
+
+	require.NoError(t, u.Interact(ctx, "found", &struct{}{}))
+	require.Error(t, u.Interact(context.Background(), "missing", &struct{}{}))
+	span.End()
+
+	assert.Equal(t, 0.0, gauge())
+
+	rw := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+	req.Header.Set("Accept", "application/openmetrics-text; version=1.0.0")
+	promhttp.HandlerFor(reg, promhttp.HandlerOpts{EnableOpenMetrics: true}).ServeHTTP(rw, req)
+
+	metrics := rw.Body.String()
+
+	assert.Contains(t, metrics, `use_case_duration_seconds_bucket{name="findItem",le="0.1"} 2 # {trace_id="`+
+		span.SpanContext().TraceIDString()+`"}`)
+	assert.Contains(t, metrics, `use_case_duration_seconds_bucket{name="findItem",le="1.0"} 2`)
+	assert.Contains(t, metrics, `use_case_errors_count{name="findItem",status="NOT_FOUND"} 1`)
+	assert.Contains(t, metrics, `use_case_interactions_count{name="findItem",status="OK"} 1`)
+	assert.Equal(t, 1, strings.Count(metrics, "# TYPE use_case_in_flight gauge"))
+}
+
+func TestStatsMiddleware_tailSampling(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	tracker, err := prom.NewStatsTracker(reg)
+	require.NoError(t, err)
+
+	u := usecase.Wrap(newInteractor(t, func() float64 { return 1 }), ucase.StatsMiddleware(tracker))
+
+	disable := opencensus.EnableTailSampling(trace.NeverSample())
+	defer disable()
+
+	// Tail sampling records all spans as sampled, but trace is not exported unless forced.
+	ctx, span := trace.StartSpan(context.Background(), "request")
+	require.True(t, span.SpanContext().IsSampled())
+	require.NoError(t, u.Interact(ctx, "found", &struct{}{}))
+	span.End()
+
+	rw := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+	req.Header.Set("Accept", "application/openmetrics-text; version=1.0.0")
+	promhttp.HandlerFor(reg, promhttp.HandlerOpts{EnableOpenMetrics: true}).ServeHTTP(rw, req)
+
+	assert.Contains(t, rw.Body.String(), `use_case_duration_seconds_count{name="findItem"} 1`)
+	assert.NotContains(t, rw.Body.String(), "trace_id")
+}
+
+func TestStatsMiddleware_tracker(t *testing.T) {
+	tracker := &stats.TrackerMock{}
+	u := usecase.Wrap(newInteractor(t, func() float64 {
+		return tracker.Value("use_case_in_flight", "name", "findItem")
+	}), ucase.StatsMiddleware(tracker))
+
+	require.NoError(t, u.Interact(context.Background(), "found", &struct{}{}))
+	require.Error(t, u.Interact(context.Background(), "missing", &struct{}{}))
+
+	assert.Equal(t, 0.0, tracker.Value("use_case_in_flight", "name", "findItem"))
+	assert.Greater(t, tracker.Value("use_case_duration_seconds", "name", "findItem"), 0.0)
+	assert.Equal(t, 1, tracker.Int("use_case_errors_count", "name", "findItem", "status", "NOT_FOUND"))
+	assert.Equal(t, 2, tracker.Int("use_case_interactions_total", "name", "findItem"))
+}